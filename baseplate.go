@@ -323,6 +323,14 @@ func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error)
 	bp.closers.Add(batchcloser.WrapCancel(cancel))
 
 	log.InitFromConfig(cfg.Log)
+	if err := log.InitRedaction(cfg.Log.Redaction); err != nil {
+		bp.Close()
+		return nil, nil, fmt.Errorf(
+			"baseplate.New: failed to init log redaction: %w (config: %#v)",
+			err,
+			cfg.Log.Redaction,
+		)
+	}
 
 	closer, err := log.InitSentry(cfg.Sentry)
 	if err != nil {
@@ -1,8 +1,11 @@
 package clientpool
 
 import (
+	"container/list"
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -15,10 +18,33 @@ type channelPool struct {
 	opener     ClientOpener
 	numActive  atomic.Int32
 	maxClients int
+
+	// waitersLock guards waiters and the closed state of the pool channel.
+	//
+	// Each element in waiters is a *waiter, in the order they started waiting.
+	waitersLock sync.Mutex
+	waiters     list.List
+	numWaiters  atomic.Int32
+	closed      bool
 }
 
-// Make sure channelPool implements Pool interface.
-var _ Pool = (*channelPool)(nil)
+// waiter is a caller blocked in GetContext.
+type waiter struct {
+	// ch has buffer size 1.
+	//
+	// A nil Client sent to ch means that there's capacity freed up in the pool,
+	// and the waiter should try the opener by itself.
+	ch chan Client
+
+	// served is set when the waiter is removed from the queue by the pool,
+	// guarded by waitersLock.
+	served bool
+}
+
+var errGetAfterClose = errors.New("clientpool: Get called after Close")
+
+// Make sure channelPool implements ContextPool interface.
+var _ ContextPool = (*channelPool)(nil)
 
 // NewChannelPool creates a new client pool implemented via channel.
 func NewChannelPool(ctx context.Context, requiredInitialClients, bestEffortInitialClients, maxClients int, opener ClientOpener) (_ Pool, err error) {
@@ -100,7 +126,7 @@ func (cp *channelPool) Get() (client Client, err error) {
 	select {
 	case c, ok := <-cp.pool:
 		if !ok {
-			return nil, errors.New("clientpool: Get called after Close")
+			return nil, errGetAfterClose
 		}
 		if c.IsOpen() {
 			return c, nil
@@ -123,20 +149,182 @@ func (cp *channelPool) Get() (client Client, err error) {
 	return cp.opener()
 }
 
+// GetContext returns a client from the pool.
+//
+// Unlike Get, when the pool is exhausted GetContext waits for a client to be
+// released back to the pool until ctx is done.
+//
+// Waiters are woken up in the order they started waiting, but the order is
+// not strict: a concurrent Get call can still take a released client ahead of
+// the waiters, and a woken up waiter failing to get a usable client (e.g. the
+// client was closed, or the opener failed) waits again at the end of the
+// queue.
+//
+// When ctx is done before a client becomes available,
+// the error returned wraps both ErrExhausted and ctx.Err().
+func (cp *channelPool) GetContext(ctx context.Context) (Client, error) {
+	for {
+		c, err := cp.Get()
+		if !errors.Is(err, ErrExhausted) {
+			return c, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrExhausted, err)
+		}
+
+		c, err = cp.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			// Some capacity was freed up without a client returned to the pool,
+			// try again.
+			continue
+		}
+		if !c.IsOpen() {
+			// See the comment in Get regarding why we still need to call Close here.
+			c.Close()
+			continue
+		}
+		cp.numActive.Add(1)
+		return c, nil
+	}
+}
+
+// wait adds a waiter to the end of the queue and blocks until it's served or
+// ctx is done.
+func (cp *channelPool) wait(ctx context.Context) (Client, error) {
+	w := &waiter{
+		ch: make(chan Client, 1),
+	}
+
+	cp.waitersLock.Lock()
+	if cp.closed {
+		cp.waitersLock.Unlock()
+		return nil, errGetAfterClose
+	}
+	elem := cp.waiters.PushBack(w)
+	cp.numWaiters.Add(1)
+	cp.waitersLock.Unlock()
+
+	// In case a client was released between our Get call and us joining the
+	// queue.
+	cp.dispatch()
+
+	select {
+	case c, ok := <-w.ch:
+		if !ok {
+			return nil, errGetAfterClose
+		}
+		return c, nil
+	case <-ctx.Done():
+	}
+
+	cp.waitersLock.Lock()
+	if !w.served {
+		cp.waiters.Remove(elem)
+		cp.numWaiters.Add(-1)
+	}
+	cp.waitersLock.Unlock()
+
+	// We could have been served right before we removed ourselves from the
+	// queue, in which case we need to pass it along.
+	select {
+	case c, ok := <-w.ch:
+		if ok {
+			cp.giveBack(c)
+		}
+	default:
+	}
+	return nil, fmt.Errorf("%w: %w", ErrExhausted, ctx.Err())
+}
+
+// dispatch hands idle clients in the pool to the waiters, in the order they
+// started waiting.
+func (cp *channelPool) dispatch() {
+	if cp.numWaiters.Load() == 0 {
+		return
+	}
+
+	cp.waitersLock.Lock()
+	defer cp.waitersLock.Unlock()
+
+	for !cp.closed && cp.waiters.Len() > 0 {
+		select {
+		case c := <-cp.pool:
+			cp.popWaiterLocked() <- c
+		default:
+			return
+		}
+	}
+}
+
+// notify wakes up the first waiter (if any) without a client, so that it would
+// try to open a new client by itself.
+//
+// It's used when a slot was freed up in the pool but we failed to put a client
+// back to the pool.
+func (cp *channelPool) notify() {
+	if cp.numWaiters.Load() == 0 {
+		return
+	}
+
+	cp.waitersLock.Lock()
+	defer cp.waitersLock.Unlock()
+
+	if !cp.closed && cp.waiters.Len() > 0 {
+		cp.popWaiterLocked() <- nil
+	}
+}
+
+// popWaiterLocked removes the first waiter from the queue and returns its
+// channel.
+//
+// It must be called with waitersLock held and a non-empty queue.
+func (cp *channelPool) popWaiterLocked() chan Client {
+	w := cp.waiters.Remove(cp.waiters.Front()).(*waiter)
+	w.served = true
+	cp.numWaiters.Add(-1)
+	return w.ch
+}
+
+// giveBack puts a client a waiter received but no longer needs back to the
+// pool.
+func (cp *channelPool) giveBack(c Client) {
+	if c == nil {
+		cp.notify()
+		return
+	}
+
+	cp.waitersLock.Lock()
+	if cp.closed {
+		cp.waitersLock.Unlock()
+		c.Close()
+		return
+	}
+	select {
+	case cp.pool <- c:
+	default:
+		c.Close()
+	}
+	cp.waitersLock.Unlock()
+
+	cp.dispatch()
+}
+
 // Release releases a client back to the pool.
 //
 // If the pool is full, the client will be closed instead.
 //
+// If there are callers blocked in GetContext, the client will be handed to the
+// longest waiting one.
+//
 // Calling Release after Close will cause panic.
 func (cp *channelPool) Release(c Client) error {
 	if c == nil {
 		return nil
 	}
 
-	// As long as c is not nil, we always need to decrease numActive by 1,
-	// even if we encounter errors here, either due to close or opener.
-	defer cp.numActive.Add(-1)
-
 	if !c.IsOpen() {
 		// Even when c.IsOpen reported false, still call Close explicitly to avoid
 		// connection leaks. At worst case scenario it just returns an already
@@ -145,13 +333,22 @@ func (cp *channelPool) Release(c Client) error {
 
 		newC, err := cp.opener()
 		if err != nil {
+			// As long as c is not nil, we always need to decrease numActive by 1,
+			// even if we encounter errors here.
+			// We are still freeing up a slot, so let a waiter (if any) use it.
+			cp.numActive.Add(-1)
+			cp.notify()
 			return err
 		}
 		c = newC
 	}
 
+	// This must happen before we hand c to a waiter, as the waiter would
+	// increase numActive by 1.
+	cp.numActive.Add(-1)
 	select {
 	case cp.pool <- c:
+		cp.dispatch()
 		return nil
 	default:
 		// Pool is full, just close it instead.
@@ -160,9 +357,18 @@ func (cp *channelPool) Release(c Client) error {
 }
 
 // Close closes the pool, and all allocated clients.
+//
+// All callers blocked in GetContext will get an error.
 func (cp *channelPool) Close() error {
-	var lastErr error
+	cp.waitersLock.Lock()
+	cp.closed = true
+	for cp.waiters.Len() > 0 {
+		close(cp.popWaiterLocked())
+	}
 	close(cp.pool)
+	cp.waitersLock.Unlock()
+
+	var lastErr error
 	for c := range cp.pool {
 		if err := c.Close(); err != nil {
 			lastErr = err
@@ -181,6 +387,11 @@ func (cp *channelPool) NumAllocated() int32 {
 	return int32(len(cp.pool))
 }
 
+// NumWaiters returns the number of callers currently blocked in GetContext.
+func (cp *channelPool) NumWaiters() int32 {
+	return cp.numWaiters.Load()
+}
+
 // IsExhausted returns true when NumActiveClients >= max capacity.
 func (cp *channelPool) IsExhausted() bool {
 	return cp.NumActiveClients() >= int32(cp.maxClients)
@@ -3,8 +3,10 @@ package clientpool_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/reddit/baseplate.go/clientpool"
 )
@@ -113,3 +115,132 @@ func TestChannelPoolWithOpenerFailure(t *testing.T) {
 		},
 	)
 }
+
+func TestChannelPoolGetContext(t *testing.T) {
+	opener := func() (clientpool.Client, error) {
+		return &testClient{}, nil
+	}
+
+	const min, init, max = 0, 1, 1
+	p, err := clientpool.NewChannelPool(context.Background(), min, init, max, opener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, ok := p.(clientpool.ContextPool)
+	if !ok {
+		t.Fatalf("NewChannelPool returned %T, which does not implement clientpool.ContextPool", p)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	first, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("pool.GetContext returned error: %v", err)
+	}
+
+	t.Run(
+		"timeout",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+			defer cancel()
+
+			_, err := pool.GetContext(ctx)
+			if !errors.Is(err, clientpool.ErrExhausted) {
+				t.Errorf("Expected error to wrap ErrExhausted, got %v", err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected error to wrap context.DeadlineExceeded, got %v", err)
+			}
+			if n := pool.NumWaiters(); n != 0 {
+				t.Errorf("pool.NumWaiters() expected 0, got %d", n)
+			}
+		},
+	)
+
+	t.Run(
+		"fifo",
+		func(t *testing.T) {
+			const n = 3
+			order := make(chan int, n)
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					c, err := pool.GetContext(context.Background())
+					if err != nil {
+						t.Errorf("pool.GetContext on #%d failed with: %v", i, err)
+						return
+					}
+					order <- i
+					if err := pool.Release(c); err != nil {
+						t.Errorf("pool.Release on #%d failed with: %v", i, err)
+					}
+				}(i)
+				// Make sure the waiters join the queue in order.
+				waitForWaiters(t, pool, int32(i+1))
+			}
+
+			if err := pool.Release(first); err != nil {
+				t.Fatalf("pool.Release returned error: %v", err)
+			}
+			wg.Wait()
+			close(order)
+
+			var i int
+			for got := range order {
+				if got != i {
+					t.Errorf("Expected waiter #%d to be served, got #%d", i, got)
+				}
+				i++
+			}
+			checkActiveAndAllocated(t, pool, 0, 1)
+		},
+	)
+}
+
+func TestChannelPoolGetContextClose(t *testing.T) {
+	opener := func() (clientpool.Client, error) {
+		return &testClient{}, nil
+	}
+
+	const min, init, max = 0, 0, 1
+	p, err := clientpool.NewChannelPool(context.Background(), min, init, max, opener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, ok := p.(clientpool.ContextPool)
+	if !ok {
+		t.Fatalf("NewChannelPool returned %T, which does not implement clientpool.ContextPool", p)
+	}
+	if _, err := pool.Get(); err != nil {
+		t.Fatalf("pool.Get returned error: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := pool.GetContext(context.Background())
+		errCh <- err
+	}()
+	waitForWaiters(t, pool, 1)
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("pool.Close returned error: %v", err)
+	}
+	if err := <-errCh; err == nil {
+		t.Error("Expected pool.GetContext to return error after Close, got nil")
+	}
+}
+
+func waitForWaiters(t *testing.T, pool clientpool.ContextPool, n int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for pool.NumWaiters() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d waiters, got %d", n, pool.NumWaiters())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -11,6 +11,10 @@
 //	PASS
 //	ok  	github.com/reddit/baseplate.go/clientpool	2.495s
 //
+// When the pool is exhausted, Get returns ErrExhausted immediately,
+// while GetContext (see ContextPool) waits for a client to be released back to
+// the pool until the context is done.
+//
 // This package is considered low level and should not be used directly in most
 // cases.
 // A thrift-specific wrapping is available in thriftbp package.
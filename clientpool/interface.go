@@ -1,6 +1,7 @@
 package clientpool
 
 import (
+	"context"
 	"io"
 )
 
@@ -22,9 +23,28 @@ type ClientOpener func() (Client, error)
 type Pool interface {
 	io.Closer
 
+	// Get returns a client from the pool,
+	// or ErrExhausted immediately when the pool is exhausted.
 	Get() (Client, error)
+
 	Release(c Client) error
 	NumActiveClients() int32
 	NumAllocated() int32
 	IsExhausted() bool
 }
+
+// ContextPool is a Pool that can also wait for a client when it's exhausted.
+//
+// The Pool returned by NewChannelPool implements it,
+// use a type assertion to check whether a Pool implements it.
+type ContextPool interface {
+	Pool
+
+	// GetContext is similar to Get, but when the pool is exhausted it waits for
+	// a client to be released back to the pool until ctx is done,
+	// instead of returning ErrExhausted immediately.
+	GetContext(ctx context.Context) (Client, error)
+
+	// NumWaiters returns the number of callers currently blocked in GetContext.
+	NumWaiters() int32
+}
@@ -11,7 +11,11 @@ import (
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/reddit/baseplate.go/grpcbp"
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/thriftbp"
@@ -46,6 +50,7 @@ var checkers = map[string]interface{}{
 	"thrift": checker(checkThrift),
 	"wsgi":   checker(checkHTTP),
 	"http":   checker(checkHTTP),
+	"grpc":   checker(checkGRPC),
 }
 
 // Actual value type: baseplate.IsHealthyProbe
@@ -192,3 +197,32 @@ func checkHTTP(addr string, probe baseplate.IsHealthyProbe, timeout time.Duratio
 	}
 	return nil
 }
+
+func checkGRPC(addr string, probe baseplate.IsHealthyProbe, timeout time.Duration) error {
+	// grpc.Dial doesn't block on connecting, the connection is established by
+	// the health check request below, so its deadline bounds the whole check.
+	//
+	// TODO: Use grpc.NewClient once we upgrade to grpc v1.63+.
+	conn, err := grpc.Dial(
+		addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create grpc client: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client := healthpb.NewHealthClient(conn)
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{
+		Service: grpcbp.HealthCheckServiceName(int64(probe)),
+	})
+	if err != nil {
+		return fmt.Errorf("grpc health check request failed: %w", err)
+	}
+	if status := resp.GetStatus(); status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpc health check returned %v", status)
+	}
+	return nil
+}
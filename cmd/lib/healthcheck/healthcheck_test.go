@@ -11,7 +11,11 @@ import (
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"google.golang.org/grpc"
 
+	bp "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/grpcbp"
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/thriftbp"
@@ -137,6 +141,62 @@ func httpService(healthy healthyMap) *service {
 	return s
 }
 
+type grpcHealthChecker struct {
+	healthy healthyMap
+}
+
+func (gh grpcHealthChecker) IsHealthy(ctx context.Context) bool {
+	return gh.healthy[baseplate.IsHealthyProbe(grpcbp.GetHealthCheckProbe(ctx))]
+}
+
+func grpcService(healthy healthyMap) *service {
+	var wg sync.WaitGroup
+	var server bp.Server
+
+	s := new(service)
+	s.up = func(t *testing.T) {
+		t.Helper()
+
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to create listener: %v", err)
+		}
+		s.addr = listener.Addr().String()
+		t.Logf("Listening on %v...", s.addr)
+		server, err = grpcbp.NewBaseplateServer(grpcbp.ServerArgs{
+			Baseplate: bp.NewTestBaseplate(bp.NewTestBaseplateArgs{
+				EdgeContextImpl: ecinterface.Mock(),
+			}),
+			RegisterServices: func(*grpc.Server) {},
+			HealthChecker: grpcHealthChecker{
+				healthy: healthy,
+			},
+			Listener: listener,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Serve(); err != nil {
+				t.Errorf("server.Serve returned error: %v", err)
+			}
+		}()
+	}
+	s.down = func(t *testing.T) {
+		t.Helper()
+
+		if server != nil {
+			if err := server.Close(); err != nil {
+				t.Errorf("Failed to stop service: %v", err)
+			}
+		}
+		wg.Wait()
+	}
+	return s
+}
+
 func TestRunArgs(t *testing.T) {
 	const timeout = time.Millisecond * 100
 	for _, c := range []struct {
@@ -204,6 +264,43 @@ func TestRunArgs(t *testing.T) {
 				baseplate.IsHealthyProbe_STARTUP:   false,
 			}),
 		},
+		{
+			label:   "grpc",
+			args:    []string{"--type", "grpc"},
+			service: grpcService(allHealthy),
+		},
+		{
+			label:   "all-unhealthy-grpc",
+			args:    []string{"--type", "grpc"},
+			err:     true,
+			service: grpcService(allUnhealthy),
+		},
+		{
+			label: "liveness-unhealthy-grpc-1",
+			args:  []string{"--type", "grpc", "--probe", "liveness"},
+			err:   true,
+			service: grpcService(healthyMap{
+				baseplate.IsHealthyProbe_READINESS: true,
+				baseplate.IsHealthyProbe_LIVENESS:  false,
+				baseplate.IsHealthyProbe_STARTUP:   true,
+			}),
+		},
+		{
+			label: "liveness-unhealthy-grpc-2",
+			args:  []string{"--type", "grpc"},
+			err:   false, // This one checks readiness probe so it should report healthy
+			service: grpcService(healthyMap{
+				baseplate.IsHealthyProbe_READINESS: true,
+				baseplate.IsHealthyProbe_LIVENESS:  false,
+				baseplate.IsHealthyProbe_STARTUP:   false,
+			}),
+		},
+		{
+			label:   "short-timeout-grpc",
+			args:    []string{"--type", "grpc", "--timeout", "1ns"},
+			err:     true,
+			service: grpcService(allHealthy),
+		},
 		{
 			label: "help",
 			args:  []string{"-h"},
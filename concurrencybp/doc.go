@@ -0,0 +1,10 @@
+// Package concurrencybp provides an adaptive concurrency limiter to be used by
+// servers to shed load.
+//
+// Unlike a fixed limit, the limit of a Limiter is adjusted automatically based
+// on the observed latency of the requests, using either the AIMD (additive
+// increase, multiplicative decrease) or the gradient algorithm.
+//
+// Please use httpbp.ConcurrencyLimit and thriftbp.ConcurrencyLimit to apply a
+// Limiter to your servers.
+package concurrencybp
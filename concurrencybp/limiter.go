@@ -0,0 +1,357 @@
+package concurrencybp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+const (
+	nameLabel = "limiter"
+)
+
+var (
+	limiterLabels = []string{
+		nameLabel,
+	}
+
+	limitGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "concurrencybp_limit",
+		Help: "The current concurrency limit of the limiter",
+	}, limiterLabels)
+
+	inflightGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "concurrencybp_inflight_requests",
+		Help: "The number of requests currently in-flight through the limiter",
+	}, limiterLabels)
+
+	rejectedCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "concurrencybp_rejected_requests_total",
+		Help: "The number of requests rejected by the limiter",
+	}, limiterLabels)
+)
+
+// ErrLimitExceeded is the error used when a request is rejected by the
+// Limiter.
+var ErrLimitExceeded = errors.New("concurrencybp: concurrency limit exceeded")
+
+// Algorithm is the algorithm used by a Limiter to adjust its limit.
+type Algorithm string
+
+// Algorithm values.
+const (
+	// AIMD grows the limit by 1 for every successful request when the limiter is
+	// at least half utilized, and multiplies the limit by BackoffRatio whenever a
+	// request is dropped or its latency is above LatencyThreshold.
+	AIMD Algorithm = "aimd"
+
+	// Gradient adjusts the limit by the ratio between the long term average
+	// latency and the latency of the current request, allowing a queue of
+	// sqrt(limit) requests.
+	Gradient Algorithm = "gradient"
+)
+
+// Default values used by Config.
+const (
+	DefaultInitialLimit     = 20
+	DefaultMinLimit         = 1
+	DefaultMaxLimit         = 1000
+	DefaultLatencyThreshold = 5 * time.Second
+	DefaultBackoffRatio     = 0.9
+	DefaultTolerance        = 1.5
+	DefaultSmoothing        = 0.2
+	DefaultRetryAfter       = time.Second
+)
+
+// longWindow is the number of samples used by the exponential moving average
+// of the long term latency in the gradient algorithm.
+const longWindow = 600
+
+// Config is the configuration of a Limiter.
+//
+// It can be parsed directly from YAML.
+// All the fields except Name are optional and have sensible defaults.
+type Config struct {
+	// Name of the limiter, used as the label value in the Prometheus metrics.
+	Name string `yaml:"name"`
+
+	// Algorithm used to adjust the limit, default to AIMD.
+	Algorithm Algorithm `yaml:"algorithm"`
+
+	// InitialLimit, MinLimit and MaxLimit define the initial value and the
+	// boundaries of the concurrency limit.
+	InitialLimit int `yaml:"initialLimit"`
+	MinLimit     int `yaml:"minLimit"`
+	MaxLimit     int `yaml:"maxLimit"`
+
+	// LatencyThreshold is used by the AIMD algorithm, requests slower than it
+	// are considered as dropped.
+	LatencyThreshold time.Duration `yaml:"latencyThreshold"`
+
+	// BackoffRatio is used by the AIMD algorithm, the limit is multiplied by it
+	// on dropped requests. It must be in range (0, 1).
+	BackoffRatio float64 `yaml:"backoffRatio"`
+
+	// Tolerance is used by the gradient algorithm, it's the ratio between the
+	// current latency and the long term average latency that's tolerated before
+	// reducing the limit. It must be >= 1.
+	Tolerance float64 `yaml:"tolerance"`
+
+	// Smoothing is used by the gradient algorithm, it's the weight of the new
+	// limit when updating the limit. It must be in range (0, 1].
+	Smoothing float64 `yaml:"smoothing"`
+
+	// RetryAfter is the duration suggested to the clients to retry after when
+	// a request is rejected.
+	RetryAfter time.Duration `yaml:"retryAfter"`
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AIMD
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = DefaultInitialLimit
+	}
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = DefaultMinLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultMaxLimit
+	}
+	if cfg.LatencyThreshold <= 0 {
+		cfg.LatencyThreshold = DefaultLatencyThreshold
+	}
+	if cfg.BackoffRatio == 0 {
+		cfg.BackoffRatio = DefaultBackoffRatio
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = DefaultTolerance
+	}
+	if cfg.Smoothing == 0 {
+		cfg.Smoothing = DefaultSmoothing
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultRetryAfter
+	}
+	return cfg
+}
+
+// Validate checks the Config for errors after applying the defaults.
+func (cfg Config) Validate() error {
+	cfg = cfg.withDefaults()
+	var errs []error
+	if cfg.Algorithm != AIMD && cfg.Algorithm != Gradient {
+		errs = append(errs, fmt.Errorf("concurrencybp: unknown algorithm %q", cfg.Algorithm))
+	}
+	if cfg.MinLimit > cfg.MaxLimit {
+		errs = append(errs, fmt.Errorf("concurrencybp: minLimit %d is larger than maxLimit %d", cfg.MinLimit, cfg.MaxLimit))
+	}
+	if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+		errs = append(errs, fmt.Errorf("concurrencybp: backoffRatio %v must be in range (0, 1)", cfg.BackoffRatio))
+	}
+	if cfg.Tolerance < 1 {
+		errs = append(errs, fmt.Errorf("concurrencybp: tolerance %v must be >= 1", cfg.Tolerance))
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		errs = append(errs, fmt.Errorf("concurrencybp: smoothing %v must be in range (0, 1]", cfg.Smoothing))
+	}
+	return errors.Join(errs...)
+}
+
+// Limiter is an adaptive concurrency limiter.
+//
+// It's safe for concurrent use.
+type Limiter struct {
+	cfg    Config
+	labels prometheus.Labels
+
+	// For tests.
+	now func() time.Time
+
+	lock     sync.Mutex
+	limit    float64
+	inflight int
+	longRTT  float64 // in seconds, only used by the gradient algorithm
+	samples  int
+}
+
+// New creates a new Limiter with the given config.
+func New(cfg Config) (*Limiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	l := &Limiter{
+		cfg: cfg,
+		labels: prometheus.Labels{
+			nameLabel: cfg.Name,
+		},
+		now:   time.Now,
+		limit: float64(cfg.InitialLimit),
+	}
+	l.clampLocked()
+	limitGauge.With(l.labels).Set(l.limit)
+	return l, nil
+}
+
+// Outcome is the outcome of a request reported back to the Limiter.
+type Outcome int
+
+// Outcome values.
+const (
+	// Success means that the request is processed, and its latency should be
+	// used to adjust the limit.
+	Success Outcome = iota
+
+	// Dropped means that the request failed in a way indicating overload,
+	// for example the deadline was exceeded. The limit will be reduced.
+	Dropped
+
+	// Ignored means that the request failed in a way unrelated to the load,
+	// and its latency should not be used to adjust the limit.
+	Ignored
+)
+
+// OutcomeFromError returns the Outcome for a request finished with err:
+//
+// - context.DeadlineExceeded: Dropped
+//
+// - context.Canceled: Ignored
+//
+// - other errors, including nil: Success
+func OutcomeFromError(err error) Outcome {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Dropped
+	case errors.Is(err, context.Canceled):
+		return Ignored
+	default:
+		return Success
+	}
+}
+
+// Release is the function to be called once the request acquired from the
+// Limiter is finished.
+type Release func(outcome Outcome)
+
+// Acquire tries to acquire a slot for a request.
+//
+// If the limit is reached it returns false and nil Release.
+// Otherwise the returned Release must be called exactly once after the request
+// is finished.
+func (l *Limiter) Acquire() (Release, bool) {
+	l.lock.Lock()
+	if l.inflight >= int(l.limit) {
+		l.lock.Unlock()
+		rejectedCounter.With(l.labels).Inc()
+		return nil, false
+	}
+	l.inflight++
+	inflight := l.inflight
+	l.lock.Unlock()
+	inflightGauge.With(l.labels).Set(float64(inflight))
+
+	start := l.now()
+	var once sync.Once
+	return func(outcome Outcome) {
+		once.Do(func() {
+			l.release(outcome, l.now().Sub(start))
+		})
+	}, true
+}
+
+// Limit returns the current concurrency limit.
+func (l *Limiter) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int(l.limit)
+}
+
+// Inflight returns the number of requests currently in-flight.
+func (l *Limiter) Inflight() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.inflight
+}
+
+// RetryAfter returns the duration suggested to the clients to retry after when
+// a request is rejected.
+func (l *Limiter) RetryAfter() time.Duration {
+	return l.cfg.RetryAfter
+}
+
+func (l *Limiter) release(outcome Outcome, latency time.Duration) {
+	l.lock.Lock()
+	// inflight before this request is released, used to check utilization.
+	inflight := l.inflight
+	l.inflight--
+	switch outcome {
+	case Success, Dropped:
+		switch l.cfg.Algorithm {
+		case Gradient:
+			l.updateGradientLocked(outcome, latency)
+		default:
+			l.updateAIMDLocked(outcome, latency, inflight)
+		}
+		l.clampLocked()
+	}
+	limit := l.limit
+	remaining := l.inflight
+	l.lock.Unlock()
+
+	limitGauge.With(l.labels).Set(limit)
+	inflightGauge.With(l.labels).Set(float64(remaining))
+}
+
+func (l *Limiter) updateAIMDLocked(outcome Outcome, latency time.Duration, inflight int) {
+	if outcome == Dropped || latency > l.cfg.LatencyThreshold {
+		l.limit *= l.cfg.BackoffRatio
+		return
+	}
+	// Only grow the limit when it's actually being utilized.
+	if float64(inflight)*2 >= l.limit {
+		l.limit++
+	}
+}
+
+func (l *Limiter) updateGradientLocked(outcome Outcome, latency time.Duration) {
+	if outcome == Dropped {
+		l.limit *= l.cfg.BackoffRatio
+		return
+	}
+	rtt := latency.Seconds()
+	if rtt <= 0 {
+		return
+	}
+
+	l.samples++
+	if l.samples == 1 {
+		l.longRTT = rtt
+	} else {
+		window := math.Min(float64(l.samples), longWindow)
+		l.longRTT += (rtt - l.longRTT) / window
+	}
+	// When the long term latency is way above the current latency, it's likely
+	// recovering from an overload, decay it faster to adapt.
+	if l.longRTT/rtt > 2 {
+		l.longRTT *= 0.95
+	}
+
+	gradient := math.Max(0.5, math.Min(1, l.cfg.Tolerance*l.longRTT/rtt))
+	queueSize := math.Sqrt(l.limit)
+	newLimit := l.limit*gradient + queueSize
+	l.limit = l.limit*(1-l.cfg.Smoothing) + newLimit*l.cfg.Smoothing
+}
+
+func (l *Limiter) clampLocked() {
+	l.limit = math.Max(float64(l.cfg.MinLimit), math.Min(float64(l.cfg.MaxLimit), l.limit))
+}
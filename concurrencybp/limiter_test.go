@@ -0,0 +1,208 @@
+package concurrencybp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/concurrencybp"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		cfg   concurrencybp.Config
+		err   bool
+	}{
+		{
+			label: "default",
+		},
+		{
+			label: "gradient",
+			cfg: concurrencybp.Config{
+				Algorithm: concurrencybp.Gradient,
+			},
+		},
+		{
+			label: "unknown-algorithm",
+			cfg: concurrencybp.Config{
+				Algorithm: "foo",
+			},
+			err: true,
+		},
+		{
+			label: "min-larger-than-max",
+			cfg: concurrencybp.Config{
+				MinLimit: 10,
+				MaxLimit: 5,
+			},
+			err: true,
+		},
+		{
+			label: "backoff-ratio",
+			cfg: concurrencybp.Config{
+				BackoffRatio: 1.5,
+			},
+			err: true,
+		},
+		{
+			label: "tolerance",
+			cfg: concurrencybp.Config{
+				Tolerance: 0.5,
+			},
+			err: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := c.cfg.Validate()
+			if c.err && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !c.err && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLimiterAcquire(t *testing.T) {
+	l, err := concurrencybp.New(concurrencybp.Config{
+		Name:         "acquire",
+		InitialLimit: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r1, ok := l.Acquire()
+	if !ok {
+		t.Fatal("Expected first Acquire to succeed")
+	}
+	r2, ok := l.Acquire()
+	if !ok {
+		t.Fatal("Expected second Acquire to succeed")
+	}
+	if _, ok := l.Acquire(); ok {
+		t.Error("Expected third Acquire to fail")
+	}
+	if got := l.Inflight(); got != 2 {
+		t.Errorf("Expected 2 inflight requests, got %d", got)
+	}
+
+	r1(concurrencybp.Ignored)
+	// Calling Release multiple times should be a no-op.
+	r1(concurrencybp.Ignored)
+	if got := l.Inflight(); got != 1 {
+		t.Errorf("Expected 1 inflight request, got %d", got)
+	}
+	if _, ok := l.Acquire(); !ok {
+		t.Error("Expected Acquire to succeed after release")
+	}
+	r2(concurrencybp.Ignored)
+}
+
+func TestLimiterAIMD(t *testing.T) {
+	l, err := concurrencybp.New(concurrencybp.Config{
+		Name:         "aimd",
+		InitialLimit: 10,
+		MaxLimit:     11,
+		BackoffRatio: 0.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Not utilized enough to grow the limit.
+	release, _ := l.Acquire()
+	release(concurrencybp.Success)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Expected limit to stay at 10, got %d", got)
+	}
+
+	releases := make([]concurrencybp.Release, 0, 10)
+	for i := 0; i < 10; i++ {
+		release, ok := l.Acquire()
+		if !ok {
+			t.Fatalf("Acquire #%d failed", i)
+		}
+		releases = append(releases, release)
+	}
+	releases[0](concurrencybp.Success)
+	releases[1](concurrencybp.Success)
+	if got := l.Limit(); got != 11 {
+		t.Errorf("Expected limit to grow to the max 11, got %d", got)
+	}
+
+	releases[2](concurrencybp.Dropped)
+	if got := l.Limit(); got != 5 {
+		t.Errorf("Expected limit to back off to 5, got %d", got)
+	}
+	for _, release := range releases[3:] {
+		release(concurrencybp.Dropped)
+	}
+	if got := l.Limit(); got != concurrencybp.DefaultMinLimit {
+		t.Errorf("Expected limit to back off to the min %d, got %d", concurrencybp.DefaultMinLimit, got)
+	}
+}
+
+func TestLimiterGradient(t *testing.T) {
+	l, err := concurrencybp.New(concurrencybp.Config{
+		Name:         "gradient",
+		Algorithm:    concurrencybp.Gradient,
+		InitialLimit: 100,
+		Smoothing:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(n int, latency time.Duration) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			release, ok := l.Acquire()
+			if !ok {
+				t.Fatalf("Acquire failed with limit %d", l.Limit())
+			}
+			time.Sleep(latency)
+			release(concurrencybp.Success)
+		}
+	}
+
+	run(20, time.Millisecond)
+	steady := l.Limit()
+
+	run(5, 20*time.Millisecond)
+	if got := l.Limit(); got >= steady {
+		t.Errorf("Expected limit to decrease from %d with increased latency, got %d", steady, got)
+	}
+}
+
+func TestOutcomeFromError(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want concurrencybp.Outcome
+	}{
+		{
+			err:  nil,
+			want: concurrencybp.Success,
+		},
+		{
+			err:  errors.New("foo"),
+			want: concurrencybp.Success,
+		},
+		{
+			err:  fmt.Errorf("foo: %w", context.DeadlineExceeded),
+			want: concurrencybp.Dropped,
+		},
+		{
+			err:  context.Canceled,
+			want: concurrencybp.Ignored,
+		},
+	} {
+		if got := concurrencybp.OutcomeFromError(c.err); got != c.want {
+			t.Errorf("OutcomeFromError(%v) got %v, want %v", c.err, got, c.want)
+		}
+	}
+}
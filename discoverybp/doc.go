@@ -0,0 +1,20 @@
+// Package discoverybp provides file-based service discovery.
+//
+// A FileResolver watches an endpoints file (in JSON or YAML format) written by
+// a discovery sidecar or config management, and resolves service names into
+// the list of their current endpoints.
+// The file is a map from service names to their endpoints, for example:
+//
+//	{
+//	  "my-thrift-service": [
+//	    {"host": "10.0.0.1", "port": 9090},
+//	    {"host": "10.0.0.2", "port": 9090}
+//	  ],
+//	  "my-http-service": [
+//	    {"host": "10.0.1.1", "port": 8080}
+//	  ]
+//	}
+//
+// The resolved endpoints can be used by thrift clients via
+// ThriftAddressGenerator, and by http clients via HTTPClientMiddleware.
+package discoverybp
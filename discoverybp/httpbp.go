@@ -0,0 +1,62 @@
+package discoverybp
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+// HTTPClientMiddleware returns an httpbp.ClientMiddleware that rewrites the
+// host of the requests to the endpoints of the service from the resolver,
+// picked in round-robin order.
+//
+// Only requests with the host of the URL being a service name known to the
+// resolver are rewritten, for example "http://my-http-service/path".
+// Other requests are passed through unchanged.
+// The Host header of the rewritten request is kept as the service name.
+//
+// If the service is known to the resolver but has no endpoints,
+// the request fails with an error wrapping ErrNoEndpoints.
+func HTTPClientMiddleware(r Resolver) httpbp.ClientMiddleware {
+	var pickers sync.Map // map[string]*roundRobin
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			service := req.URL.Hostname()
+			v, ok := pickers.Load(service)
+			if !ok {
+				// Only create pickers for known services.
+				if _, err := r.Resolve(service); errors.Is(err, ErrServiceNotFound) {
+					return next.RoundTrip(req)
+				}
+				v, _ = pickers.LoadOrStore(service, &roundRobin{
+					resolver: r,
+					service:  service,
+				})
+			}
+			endpoint, err := v.(*roundRobin).pick()
+			if errors.Is(err, ErrServiceNotFound) {
+				// The service was removed from the endpoints file.
+				return next.RoundTrip(req)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			if req.Host == "" {
+				req.Host = req.URL.Host
+			}
+			req.URL.Host = endpoint.Addr()
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// roundTripperFunc adapts closures and functions to implement http.RoundTripper.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,215 @@
+package discoverybp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/filewatcher/v2"
+)
+
+// Errors returned by Resolver.Resolve.
+var (
+	// ErrServiceNotFound is returned when the service is not in the endpoints
+	// file at all.
+	ErrServiceNotFound = errors.New("discoverybp: service not found")
+
+	// ErrNoEndpoints is returned when the service has an empty list of
+	// endpoints.
+	ErrNoEndpoints = errors.New("discoverybp: no endpoints available")
+)
+
+// Endpoint is a single endpoint of a service.
+type Endpoint struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+// Addr returns the "${host}:${port}" address of the endpoint.
+func (e Endpoint) Addr() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// Endpoints is the parsed content of an endpoints file,
+// which is a map from service names to their endpoints.
+type Endpoints map[string][]Endpoint
+
+// Resolver resolves service names into their endpoints.
+type Resolver interface {
+	// Resolve returns the current endpoints of the service.
+	//
+	// It returns ErrServiceNotFound if the service is unknown,
+	// or ErrNoEndpoints if the service has no endpoints.
+	//
+	// The returned slice is shared and must not be modified.
+	Resolve(service string) ([]Endpoint, error)
+
+	// Subscribe registers a callback to be called with the new endpoints
+	// whenever the endpoints of the service change.
+	//
+	// Callbacks are called synchronously from the goroutine detecting the
+	// change, so they should not block.
+	//
+	// The returned unsubscribe function removes the callback.
+	// It's safe to be called multiple times.
+	Subscribe(service string, callback func([]Endpoint)) (unsubscribe func())
+}
+
+// Config is the configuration for NewFileResolver.
+type Config struct {
+	// Path is the path to the endpoints file.
+	Path string `yaml:"path"`
+}
+
+// FileResolver is a Resolver backed by an endpoints file watched by
+// filewatcher.
+type FileResolver struct {
+	watcher io.Closer
+
+	current atomic.Pointer[Endpoints]
+
+	lock          sync.Mutex
+	nextID        uint64
+	subscriptions map[string]map[uint64]func([]Endpoint)
+}
+
+var _ Resolver = (*FileResolver)(nil)
+
+// NewFileResolver creates a FileResolver watching the endpoints file
+// configured in cfg.
+//
+// Context should come with a timeout otherwise this might block forever, i.e.
+// if the path never becomes available.
+//
+// Additional filewatcher options can be passed in via opts.
+func NewFileResolver(ctx context.Context, cfg Config, opts ...filewatcher.Option) (*FileResolver, error) {
+	r := &FileResolver{
+		subscriptions: make(map[string]map[uint64]func([]Endpoint)),
+	}
+	fw, err := filewatcher.New(ctx, cfg.Path, r.parse, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("discoverybp: error watching endpoints file %q: %w", cfg.Path, err)
+	}
+	r.watcher = fw
+	return r, nil
+}
+
+// parse is the filewatcher.Parser used by FileResolver.
+//
+// Besides parsing the file, it also stores the result and notifies the
+// subscribers.
+func (r *FileResolver) parse(f io.Reader) (*Endpoints, error) {
+	var endpoints Endpoints
+	if err := yaml.NewDecoder(f).Decode(&endpoints); err != nil {
+		return nil, fmt.Errorf("discoverybp: error parsing endpoints file: %w", err)
+	}
+	r.update(endpoints)
+	return &endpoints, nil
+}
+
+func (r *FileResolver) update(endpoints Endpoints) {
+	for _, notify := range r.swap(endpoints) {
+		notify()
+	}
+}
+
+// swap stores endpoints as the current endpoints, and returns the callbacks
+// of the services with changed endpoints to be called.
+//
+// The callbacks are called by update after the lock is released,
+// so that they can call Subscribe or unsubscribe without deadlocking.
+func (r *FileResolver) swap(endpoints Endpoints) []func() {
+	// Hold the lock while swapping, so that Subscribe calls will not miss any
+	// changes.
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	prev := r.current.Swap(&endpoints)
+	if prev == nil {
+		return nil
+	}
+	var callbacks []func()
+	for service, subs := range r.subscriptions {
+		if slices.Equal((*prev)[service], endpoints[service]) {
+			continue
+		}
+		for _, callback := range subs {
+			callbacks = append(callbacks, func() {
+				callback(endpoints[service])
+			})
+		}
+	}
+	return callbacks
+}
+
+// Resolve implements Resolver.
+func (r *FileResolver) Resolve(service string) ([]Endpoint, error) {
+	current := r.current.Load()
+	if current == nil {
+		return nil, fmt.Errorf("%w: %q", ErrServiceNotFound, service)
+	}
+	endpoints, ok := (*current)[service]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrServiceNotFound, service)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoEndpoints, service)
+	}
+	return endpoints, nil
+}
+
+// Subscribe implements Resolver.
+func (r *FileResolver) Subscribe(service string, callback func([]Endpoint)) (unsubscribe func()) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	id := r.nextID
+	r.nextID++
+	subs := r.subscriptions[service]
+	if subs == nil {
+		subs = make(map[uint64]func([]Endpoint))
+		r.subscriptions[service] = subs
+	}
+	subs[id] = callback
+
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		delete(r.subscriptions[service], id)
+		if len(r.subscriptions[service]) == 0 {
+			delete(r.subscriptions, service)
+		}
+	}
+}
+
+// Close stops watching the endpoints file.
+//
+// After Close is called the resolver keeps returning the last endpoints.
+func (r *FileResolver) Close() error {
+	return r.watcher.Close()
+}
+
+// roundRobin picks endpoints of a service in turn.
+type roundRobin struct {
+	resolver Resolver
+	service  string
+
+	next atomic.Uint64
+}
+
+func (rr *roundRobin) pick() (Endpoint, error) {
+	endpoints, err := rr.resolver.Resolve(rr.service)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	return endpoints[(rr.next.Add(1)-1)%uint64(len(endpoints))], nil
+}
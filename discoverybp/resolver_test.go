@@ -0,0 +1,213 @@
+package discoverybp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/discoverybp"
+	"github.com/reddit/baseplate.go/filewatcher/v2"
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	// Write to a temp file then rename, so the filewatcher never sees a
+	// partially written file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newResolver(t *testing.T, content string) (*discoverybp.FileResolver, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "endpoints.json")
+	writeFile(t, path, content)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	r, err := discoverybp.NewFileResolver(
+		ctx,
+		discoverybp.Config{Path: path},
+		filewatcher.WithFSEventsDelay(time.Millisecond),
+		filewatcher.WithPollingInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+	})
+	return r, path
+}
+
+func TestFileResolverResolve(t *testing.T) {
+	r, _ := newResolver(t, `
+foo:
+  - host: 10.0.0.1
+    port: 9090
+  - host: 10.0.0.2
+    port: 9090
+bar: []
+`)
+
+	got, err := r.Resolve("foo")
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	want := []discoverybp.Endpoint{
+		{Host: "10.0.0.1", Port: 9090},
+		{Host: "10.0.0.2", Port: 9090},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Resolve got %+v, want %+v", got, want)
+	}
+	if addr := got[0].Addr(); addr != "10.0.0.1:9090" {
+		t.Errorf("Addr got %q, want %q", addr, "10.0.0.1:9090")
+	}
+
+	if _, err := r.Resolve("bar"); !errors.Is(err, discoverybp.ErrNoEndpoints) {
+		t.Errorf("Expected ErrNoEndpoints, got %v", err)
+	}
+	if _, err := r.Resolve("fizz"); !errors.Is(err, discoverybp.ErrServiceNotFound) {
+		t.Errorf("Expected ErrServiceNotFound, got %v", err)
+	}
+}
+
+func TestFileResolverSubscribe(t *testing.T) {
+	r, path := newResolver(t, `{"foo": [{"host": "10.0.0.1", "port": 9090}], "bar": []}`)
+
+	fooCh := make(chan []discoverybp.Endpoint, 10)
+	r.Subscribe("foo", func(endpoints []discoverybp.Endpoint) {
+		fooCh <- endpoints
+	})
+	barCh := make(chan []discoverybp.Endpoint, 10)
+	unsubscribe := r.Subscribe("bar", func(endpoints []discoverybp.Endpoint) {
+		barCh <- endpoints
+	})
+	unsubscribe()
+
+	writeFile(t, path, `{"foo": [{"host": "10.0.0.2", "port": 9090}], "bar": [{"host": "10.0.0.3", "port": 8080}]}`)
+
+	select {
+	case got := <-fooCh:
+		want := []discoverybp.Endpoint{{Host: "10.0.0.2", Port: 9090}}
+		if !slices.Equal(got, want) {
+			t.Errorf("Subscription got %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscription not called after endpoints change")
+	}
+
+	select {
+	case got := <-barCh:
+		t.Errorf("Unsubscribed callback called with %+v", got)
+	default:
+	}
+}
+
+func TestFileResolverSubscribeFromCallback(t *testing.T) {
+	r, path := newResolver(t, `{"foo": [{"host": "10.0.0.1", "port": 9090}]}`)
+
+	called := make(chan struct{})
+	var unsubscribe func()
+	unsubscribe = r.Subscribe("foo", func([]discoverybp.Endpoint) {
+		// Neither of them should deadlock.
+		unsubscribe()
+		r.Subscribe("foo", func([]discoverybp.Endpoint) {})
+		close(called)
+	})
+
+	writeFile(t, path, `{"foo": [{"host": "10.0.0.2", "port": 9090}]}`)
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("Subscription not called after endpoints change")
+	}
+}
+
+func TestFileResolverResolveBeforeLoad(t *testing.T) {
+	var r discoverybp.FileResolver
+	if _, err := r.Resolve("foo"); !errors.Is(err, discoverybp.ErrServiceNotFound) {
+		t.Errorf("Expected ErrServiceNotFound, got %v", err)
+	}
+}
+
+func TestThriftAddressGenerator(t *testing.T) {
+	r, _ := newResolver(t, `{"foo": [{"host": "10.0.0.1", "port": 9090}, {"host": "10.0.0.2", "port": 9090}]}`)
+
+	gen := discoverybp.ThriftAddressGenerator(r, "foo")
+	for i, want := range []string{"10.0.0.1:9090", "10.0.0.2:9090", "10.0.0.1:9090"} {
+		got, err := gen()
+		if err != nil {
+			t.Fatalf("#%d: AddressGenerator returned error: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("#%d: AddressGenerator got %q, want %q", i, got, want)
+		}
+	}
+
+	if _, err := discoverybp.ThriftAddressGenerator(r, "bar")(); !errors.Is(err, discoverybp.ErrServiceNotFound) {
+		t.Errorf("Expected ErrServiceNotFound, got %v", err)
+	}
+}
+
+func TestHTTPClientMiddleware(t *testing.T) {
+	var gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotHost = req.Host
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := newResolver(t, `{"foo": [{"host": "`+u.Hostname()+`", "port": `+u.Port()+`}], "bar": []}`)
+	client := &http.Client{
+		Transport: httpbp.WrapTransport(http.DefaultTransport, discoverybp.HTTPClientMiddleware(r)),
+	}
+
+	t.Run("rewrite", func(t *testing.T) {
+		resp, err := client.Get("http://foo/path")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if gotHost != "foo" {
+			t.Errorf("Expected Host header %q, got %q", "foo", gotHost)
+		}
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if gotHost != u.Host {
+			t.Errorf("Expected Host header %q, got %q", u.Host, gotHost)
+		}
+	})
+
+	t.Run("no-endpoints", func(t *testing.T) {
+		_, err := client.Get("http://bar/path")
+		if !errors.Is(err, discoverybp.ErrNoEndpoints) {
+			t.Errorf("Expected ErrNoEndpoints, got %v", err)
+		}
+	})
+}
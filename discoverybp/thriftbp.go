@@ -0,0 +1,23 @@
+package discoverybp
+
+import (
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+// ThriftAddressGenerator returns a thriftbp.AddressGenerator that picks the
+// endpoints of the service from the resolver in round-robin order.
+//
+// It can be used with thriftbp.NewCustomClientPool.
+func ThriftAddressGenerator(r Resolver, service string) thriftbp.AddressGenerator {
+	rr := &roundRobin{
+		resolver: r,
+		service:  service,
+	}
+	return func() (string, error) {
+		endpoint, err := rr.pick()
+		if err != nil {
+			return "", err
+		}
+		return endpoint.Addr(), nil
+	}
+}
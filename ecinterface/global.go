@@ -3,6 +3,7 @@ package ecinterface
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,6 +33,19 @@ var ErrGetBeforeSet = errors.New("ecinterface: Get called before Set is called")
 
 var global atomic.Pointer[Interface]
 
+func init() {
+	log.RegisterContextAttrs(func(ctx context.Context) []slog.Attr {
+		stored := global.Load()
+		if stored == nil {
+			return nil
+		}
+		if provider, ok := (*stored).(LogAttrsProvider); ok {
+			return provider.LogAttrs(ctx)
+		}
+		return nil
+	})
+}
+
 // Set sets the global edge context implementation.
 func Set(impl Interface) {
 	global.Store(&impl)
@@ -2,6 +2,7 @@ package ecinterface
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/reddit/baseplate.go/secrets"
 )
@@ -24,6 +25,16 @@ type Interface interface {
 	ContextToHeader(ctx context.Context) (header string, ok bool)
 }
 
+// LogAttrsProvider is an optional interface an Interface implementation can
+// implement to add edge context fields (e.g. the user ID) to the records
+// logged by log.SlogHandler.
+type LogAttrsProvider interface {
+	// LogAttrs returns the attributes of the edge context attached to ctx.
+	//
+	// It shall return nil when there's no edge context attached to ctx.
+	LogAttrs(ctx context.Context) []slog.Attr
+}
+
 // FactoryArgs defines the args used in Factory.
 type FactoryArgs struct {
 	Store *secrets.Store
@@ -3,16 +3,24 @@ package grpcbp
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
+	"github.com/avast/retry-go"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/internal/faults"
 	"github.com/reddit/baseplate.go/prometheusbp"
+	"github.com/reddit/baseplate.go/retrybp"
 	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
 )
 
 // MonitorInterceptorArgs are the arguments to be passed into the
@@ -192,3 +200,345 @@ func PrometheusStreamClientInterceptor(serverSlug string) grpc.StreamClientInter
 		return nil, errors.New("PrometheusStreamClientInterceptor: not implemented")
 	}
 }
+
+// MonitorInterceptorWrappedSlugSuffix is a suffix to be added to the service
+// slug arg of MonitorInterceptorUnary function, in order to distinguish from
+// the spans that are the raw client calls.
+//
+// The MonitorInterceptorUnary with this suffix will have span operation names
+// like:
+//
+//	service-with-retry.MethodName
+//
+// Which groups all retries of the same client call together,
+// while the MonitorInterceptorUnary without this suffix will have span
+// operation names like:
+//
+//	service.MethodName
+const MonitorInterceptorWrappedSlugSuffix = transport.WithRetrySlugSuffix
+
+// DefaultRetryableCodes are the gRPC status codes retried by
+// WithDefaultRetryFilters.
+//
+// codes.Unavailable is the only code that's always safe to retry according to
+// gRPC's status code documentation, as it indicates that the request was not
+// processed by the server.
+var DefaultRetryableCodes = []codes.Code{
+	codes.Unavailable,
+}
+
+// WithDefaultRetryFilters returns a list of retrybp.Filters by appending the
+// given filters to the "default" retry filters:
+//
+// 1. RetryableErrorFilter - handle errors already provided retryable
+// information.
+//
+// 2. ContextErrorFilter - do not retry on context cancellation/timeout.
+//
+// 3. StatusCodeRetryFilter(DefaultRetryableCodes...) - retry on gRPC status
+// codes that are always safe to retry.
+func WithDefaultRetryFilters(filters ...retrybp.Filter) []retrybp.Filter {
+	return append([]retrybp.Filter{
+		retrybp.RetryableErrorFilter,
+		retrybp.ContextErrorFilter,
+		StatusCodeRetryFilter(DefaultRetryableCodes...),
+	}, filters...)
+}
+
+// StatusCodeRetryFilter returns a retrybp.Filter that returns true if the error
+// is a gRPC status error with one of the given codes,
+// otherwise it calls the next filter in the chain.
+func StatusCodeRetryFilter(retryable ...codes.Code) retrybp.Filter {
+	return func(err error, next retry.RetryIfFunc) bool {
+		if s, ok := status.FromError(err); ok && err != nil {
+			for _, code := range retryable {
+				if s.Code() == code {
+					return true
+				}
+			}
+		}
+		return next(err)
+	}
+}
+
+// DefaultUnaryClientInterceptorArgs is the arg struct for
+// BaseplateDefaultUnaryClientInterceptors.
+type DefaultUnaryClientInterceptorArgs struct {
+	// ServiceSlug is a short identifier for the gRPC service you are creating
+	// clients for. The preferred convention is to take the service's name,
+	// remove the 'Service' prefix, if present, and convert from camel case to
+	// all lower case, hyphen separated.
+	//
+	// Examples:
+	//
+	//     AuthenticationService -> authentication
+	//     ImageUploadService -> image-upload
+	ServiceSlug string
+
+	// RetryOptions is the list of retry.Options to apply as the defaults for the
+	// retry interceptor.
+	//
+	// This is optional, if it is not set, we will use a single option,
+	// retry.Attempts(1).  This sets up the retry interceptor but does not
+	// automatically retry any requests.  You can set retry behavior per-call by
+	// using retrybp.WithOptions.
+	//
+	// When setting retry attempts, you usually also want to set
+	// retrybp.Filters(WithDefaultRetryFilters()...) to only retry on the
+	// errors that are safe to retry.
+	RetryOptions []retry.Option
+
+	// When BreakerConfig is non-nil,
+	// a breakerbp.FailureRatioBreaker will be created for the client,
+	// and its interceptor will be used.
+	BreakerConfig *breakerbp.Config
+
+	// The edge context implementation. Optional.
+	//
+	// If it's not set, the global one from ecinterface.Get will be used instead.
+	EdgeContextImpl ecinterface.Interface
+}
+
+// BaseplateDefaultUnaryClientInterceptors returns the default unary client
+// interceptors that should be used by a baseplate service.
+//
+// Currently they are (in order):
+//
+// 1. ForwardEdgeContextUnary
+//
+// 2. MonitorInterceptorUnary with MonitorInterceptorWrappedSlugSuffix - This
+// creates the spans from the view of the client that group all retries into a
+// single, wrapped span.
+//
+// 3. PrometheusUnaryClientInterceptor with MonitorInterceptorWrappedSlugSuffix -
+// This creates the prometheus client metrics from the view of the client that
+// group all retries into a single operation.
+//
+// 4. RetryUnaryClientInterceptor(retryOptions) - If retryOptions is empty/nil,
+// default to only retry.Attempts(1), this will not actually retry any calls but
+// your client is configured to set retry logic per-call using
+// retrybp.WithOptions.
+//
+// 5. CircuitBreakerUnaryClientInterceptor - Only if BreakerConfig is non-nil.
+//
+// 6. MonitorInterceptorUnary - This creates the spans of the raw client calls.
+//
+// 7. PrometheusUnaryClientInterceptor
+//
+// 8. SetDeadlineBudgetUnary
+//
+// 9. clientFaultInterceptor - This injects faults at the client side if the
+// request matches the X-Bp-Fault metadata.
+//
+// IMPORTANT: clientFaultInterceptor MUST be the last interceptor as it
+// simulates faults as if they originated from the upstream server.
+//
+// The returned interceptors can be used with grpc.WithChainUnaryInterceptor.
+func BaseplateDefaultUnaryClientInterceptors(args DefaultUnaryClientInterceptorArgs) []grpc.UnaryClientInterceptor {
+	if len(args.RetryOptions) == 0 {
+		args.RetryOptions = []retry.Option{retry.Attempts(1)}
+	}
+
+	interceptors := []grpc.UnaryClientInterceptor{
+		ForwardEdgeContextUnary(args.EdgeContextImpl),
+		MonitorInterceptorUnary(MonitorInterceptorArgs{
+			ServiceSlug: args.ServiceSlug + MonitorInterceptorWrappedSlugSuffix,
+		}),
+		PrometheusUnaryClientInterceptor(args.ServiceSlug + MonitorInterceptorWrappedSlugSuffix),
+		RetryUnaryClientInterceptor(args.RetryOptions...),
+	}
+	if args.BreakerConfig != nil {
+		interceptors = append(
+			interceptors,
+			CircuitBreakerUnaryClientInterceptor(breakerbp.NewFailureRatioBreaker(*args.BreakerConfig)),
+		)
+	}
+	return append(
+		interceptors,
+		MonitorInterceptorUnary(MonitorInterceptorArgs{
+			ServiceSlug: args.ServiceSlug,
+		}),
+		PrometheusUnaryClientInterceptor(args.ServiceSlug),
+		SetDeadlineBudgetUnary,
+		NewClientFaultInterceptor(args.ServiceSlug).UnaryInterceptor(), // clientFaultInterceptor MUST be last
+	)
+}
+
+// RetryUnaryClientInterceptor returns a grpc.UnaryClientInterceptor that can be
+// used to automatically retry gRPC requests.
+//
+// The retry options set on the context object via retrybp.WithOptions takes
+// precedence over the defaults.
+func RetryUnaryClientInterceptor(defaults ...retry.Option) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req interface{},
+		reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return retrybp.Do(
+			ctx,
+			func() error {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			},
+			defaults...,
+		)
+	}
+}
+
+// DefaultBreakerFailureCodes are the gRPC status codes counted as failures by
+// CircuitBreakerUnaryClientInterceptor.
+//
+// Other codes (for example codes.NotFound or codes.InvalidArgument) indicate
+// that the server is able to handle the request,
+// so they don't count towards tripping the breaker.
+var DefaultBreakerFailureCodes = []codes.Code{
+	codes.Unknown,
+	codes.DeadlineExceeded,
+	codes.ResourceExhausted,
+	codes.Internal,
+	codes.Unavailable,
+}
+
+// breakerPassthroughError is used to pass the errors not counted as failures
+// through the circuit breaker.
+type breakerPassthroughError struct {
+	err error
+}
+
+// CircuitBreakerUnaryClientInterceptor is a grpc.UnaryClientInterceptor that
+// handles circuit breaking.
+//
+// Only errors that are not gRPC status errors, or gRPC status errors with codes
+// in DefaultBreakerFailureCodes are counted as failures by the breaker.
+func CircuitBreakerUnaryClientInterceptor(cb breakerbp.CircuitBreaker) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req interface{},
+		reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		result, err := cb.Execute(func() (interface{}, error) {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err != nil && !isBreakerFailure(err) {
+				return breakerPassthroughError{err: err}, nil
+			}
+			return nil, err
+		})
+		if passthrough, ok := result.(breakerPassthroughError); ok {
+			return passthrough.err
+		}
+		return err
+	}
+}
+
+func isBreakerFailure(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	for _, code := range DefaultBreakerFailureCodes {
+		if s.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDeadlineBudgetUnary is a grpc.UnaryClientInterceptor that propagates the
+// remaining time of the deadline set on the context object to the server,
+// via the "Deadline-Budget" (transport.HeaderDeadlineBudget) metadata,
+// in milliseconds.
+//
+// If the deadline already passed, it returns the gRPC status error converted
+// from the context error without calling the server.
+func SetDeadlineBudgetUnary(
+	ctx context.Context,
+	method string,
+	req interface{},
+	reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if err := ctx.Err(); err != nil {
+		// Deadline already passed, no need to even try
+		return status.FromContextError(err).Err()
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		// Round up to the next millisecond.
+		// In the scenario that the caller set a 10ms timeout and send the
+		// request, by the time we get into this interceptor it's definitely gonna
+		// be less than 10ms.
+		// If we use round down then we are only gonna send 9 over the wire.
+		timeout := time.Until(deadline) + time.Millisecond - 1
+		ms := timeout.Milliseconds()
+		if ms < 1 {
+			// Make sure we give it at least 1ms.
+			ms = 1
+		}
+		ctx = metadata.AppendToOutgoingContext(
+			ctx,
+			transport.HeaderDeadlineBudget, strconv.FormatInt(ms, 10),
+		)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// UnaryInterceptor returns the unary client interceptor injecting faults.
+func (c clientFaultInterceptor) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req interface{},
+		reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		resume := func() (struct{}, error) {
+			return struct{}{}, invoker(ctx, method, req, reply, cc, opts...)
+		}
+		_, err := c.unaryInjector.Inject(ctx, faults.InjectParameters[struct{}]{
+			Address:     targetAddress(cc.Target()),
+			Method:      methodSlug(method),
+			MethodLabel: method,
+			Headers:     &grpcHeaders{},
+			Resume:      resume,
+		})
+		return err
+	}
+}
+
+// StreamInterceptor returns the streaming client interceptor injecting faults.
+//
+// Faults are only injected when the stream is created.
+func (c clientFaultInterceptor) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		resume := func() (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		return c.streamInjector.Inject(ctx, faults.InjectParameters[grpc.ClientStream]{
+			Address:     targetAddress(cc.Target()),
+			Method:      methodSlug(method),
+			MethodLabel: method,
+			Headers:     &grpcHeaders{},
+			Resume:      resume,
+		})
+	}
+}
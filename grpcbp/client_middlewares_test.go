@@ -3,18 +3,29 @@ package grpcbp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/avast/retry-go"
 	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
 	"github.com/opentracing/opentracing-go"
+	"github.com/sony/gobreaker"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 
+	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/internal/faults"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/retrybp"
 	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
 )
 
 func TestMonitorInterceptorUnary(t *testing.T) {
@@ -170,3 +181,261 @@ func drainRecorder(t *testing.T, recorder *mqsend.MockMessageQueue) []byte {
 	}
 	return msg
 }
+
+func TestRetryUnaryClientInterceptor(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{
+			name:      "success",
+			err:       nil,
+			wantCalls: 1,
+		},
+		{
+			name:      "unavailable",
+			err:       status.Error(codes.Unavailable, "unavailable"),
+			wantCalls: 3,
+		},
+		{
+			name:      "not-found",
+			err:       status.Error(codes.NotFound, "not found"),
+			wantCalls: 1,
+		},
+		{
+			name:      "deadline-exceeded",
+			err:       status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+			wantCalls: 1,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var calls int
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				calls++
+				return c.err
+			}
+			interceptor := RetryUnaryClientInterceptor(
+				retry.Attempts(3),
+				retry.Delay(0),
+				retrybp.Filters(WithDefaultRetryFilters()...),
+			)
+			err := interceptor(context.Background(), "/test.Service/Method", nil, nil, nil, invoker)
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+			if calls != c.wantCalls {
+				t.Errorf("Expected %d calls, got %d", c.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerUnaryClientInterceptor(t *testing.T) {
+	breaker := breakerbp.NewFailureRatioBreaker(breakerbp.Config{
+		Name:              "test",
+		MinRequestsToTrip: 2,
+		FailureThreshold:  0.5,
+		Timeout:           time.Minute,
+	})
+	interceptor := CircuitBreakerUnaryClientInterceptor(breaker)
+	invoke := func(err error) error {
+		return interceptor(
+			context.Background(),
+			"/test.Service/Method",
+			nil, // req
+			nil, // reply
+			nil, // cc
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return err
+			},
+		)
+	}
+
+	notFound := status.Error(codes.NotFound, "not found")
+	for i := 0; i < 5; i++ {
+		if err := invoke(notFound); !errors.Is(err, notFound) {
+			t.Fatalf("Expected error %v, got %v", notFound, err)
+		}
+	}
+	if state := breaker.State(); state != gobreaker.StateClosed {
+		t.Fatalf("Expected breaker to be closed after non-failure codes, got %v", state)
+	}
+
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	for i := 0; i < 5; i++ {
+		invoke(unavailable)
+	}
+	if state := breaker.State(); state != gobreaker.StateOpen {
+		t.Fatalf("Expected breaker to be open after failure codes, got %v", state)
+	}
+	if err := invoke(nil); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("Expected gobreaker.ErrOpenState, got %v", err)
+	}
+}
+
+func TestSetDeadlineBudgetUnary(t *testing.T) {
+	var got string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		got, _ = GetHeader(md, transport.HeaderDeadlineBudget)
+		return nil
+	}
+
+	t.Run("no-deadline", func(t *testing.T) {
+		got = ""
+		if err := SetDeadlineBudgetUnary(context.Background(), "/test.Service/Method", nil, nil, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+		if got != "" {
+			t.Errorf("Expected no deadline budget header, got %q", got)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		got = ""
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := SetDeadlineBudgetUnary(ctx, "/test.Service/Method", nil, nil, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+		if got != "100" {
+			t.Errorf("Expected deadline budget header %q, got %q", "100", got)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := SetDeadlineBudgetUnary(ctx, "/test.Service/Method", nil, nil, nil, invoker)
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("Expected code %v, got %v", codes.Canceled, code)
+		}
+	})
+}
+
+func TestBaseplateDefaultUnaryClientInterceptors(t *testing.T) {
+	l, _ := setupServer(t)
+	conn := setupClient(t, l, grpc.WithChainUnaryInterceptor(
+		BaseplateDefaultUnaryClientInterceptors(DefaultUnaryClientInterceptorArgs{
+			ServiceSlug:     "test",
+			EdgeContextImpl: ecinterface.Mock(),
+			BreakerConfig: &breakerbp.Config{
+				Name:              "test",
+				MinRequestsToTrip: 10,
+				FailureThreshold:  0.5,
+			},
+		})...,
+	))
+	client := pb.NewTestServiceClient(conn)
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{}); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func TestClientFaultInterceptor(t *testing.T) {
+	l, _ := setupServer(t)
+	interceptor := NewClientFaultInterceptor("test")
+	conn := setupClient(
+		t,
+		l,
+		grpc.WithUnaryInterceptor(interceptor.UnaryInterceptor()),
+		grpc.WithStreamInterceptor(interceptor.StreamInterceptor()),
+	)
+	client := pb.NewTestServiceClient(conn)
+
+	for _, c := range []struct {
+		name        string
+		faultHeader string
+		want        codes.Code
+	}{
+		{
+			name: "no fault specified",
+			want: codes.OK,
+		},
+		{
+			name:        "abort",
+			faultHeader: "a=bufnet;m=Ping;f=14;b=test fault",
+			want:        codes.Unavailable,
+		},
+		{
+			name:        "multiple header values abort",
+			faultHeader: "a=foo, a=bufnet;m=Ping;f=8;b=test fault",
+			want:        codes.ResourceExhausted,
+		},
+		{
+			name:        "address does not match",
+			faultHeader: "a=foo;m=Ping;f=14;b=test fault",
+			want:        codes.OK,
+		},
+		{
+			name:        "method does not match",
+			faultHeader: "a=bufnet;m=foo;f=14;b=test fault",
+			want:        codes.OK,
+		},
+		{
+			name:        "greater than max abort code",
+			faultHeader: "a=bufnet;m=Ping;f=17;b=test fault",
+			want:        codes.OK,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.faultHeader != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, faults.FaultHeader, c.faultHeader)
+			}
+			_, err := client.Ping(ctx, &pb.PingRequest{})
+			if got := status.Code(err); got != c.want {
+				t.Errorf("Expected code %v, got %v (%v)", c.want, got, err)
+			}
+			if c.want != codes.OK {
+				if msg := status.Convert(err).Message(); msg != "test fault" {
+					t.Errorf("Expected message %q, got %q", "test fault", msg)
+				}
+			}
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(
+			context.Background(),
+			faults.FaultHeader,
+			"a=bufnet;m=PingList;f=14;b=test fault",
+		)
+		_, err := client.PingList(ctx, &pb.PingRequest{})
+		if got := status.Code(err); got != codes.Unavailable {
+			t.Errorf("Expected code %v, got %v (%v)", codes.Unavailable, got, err)
+		}
+	})
+}
+
+func TestTargetAddress(t *testing.T) {
+	for _, c := range []struct {
+		target string
+		want   string
+	}{
+		{
+			target: "dns:///foo.bar.svc.cluster.local:9090",
+			want:   "foo.bar.svc.cluster.local:9090",
+		},
+		{
+			target: "passthrough:///foo:9090",
+			want:   "foo:9090",
+		},
+		{
+			target: "foo:9090",
+			want:   "foo:9090",
+		},
+		{
+			target: "unix:///tmp/grpc.sock",
+			want:   "unix:///tmp/grpc.sock",
+		},
+	} {
+		t.Run(c.target, func(t *testing.T) {
+			if got := targetAddress(c.target); got != c.want {
+				t.Errorf("targetAddress(%q) got %q, want %q", c.target, got, c.want)
+			}
+		})
+	}
+}
@@ -5,10 +5,16 @@
 // On the client side, this package provides middlewares to support tracing
 // propagation or initialization as well as forwarding EdgeRequestContext
 // according to the Baseplate specification.
+// BaseplateDefaultUnaryClientInterceptors bundles them together with retries,
+// circuit breaking and deadline budget propagation.
 //
 // # Servers
 //
 // On the server side, this package provides middleware implementations for
 // EdgeRequestContext handling and tracing propagation according to Baseplate
 // specification.
+// BaseplateDefaultServerInterceptors bundles them together with deadline
+// budget extraction, Prometheus metrics and panic recovery.
+// NewBaseplateServer creates a baseplate.Server with those interceptors and the
+// standard gRPC health service, to be used with baseplate.Serve.
 package grpcbp
@@ -0,0 +1,71 @@
+package grpcbp
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/internal/faults"
+)
+
+type clientFaultInterceptor struct {
+	unaryInjector  faults.Injector[struct{}]
+	streamInjector faults.Injector[grpc.ClientStream]
+}
+
+// NewClientFaultInterceptor creates and returns a new client-side fault
+// injection interceptor.
+//
+// The interceptors inject faults into the outgoing gRPC requests based on the
+// X-Bp-Fault values found in the outgoing metadata. Aborted requests return a
+// status error with the abort code as the codes.Code, which must be between
+// codes.Canceled (1) and codes.Unauthenticated (16).
+func NewClientFaultInterceptor(clientName string) clientFaultInterceptor {
+	return clientFaultInterceptor{
+		unaryInjector: *faults.NewInjector(
+			clientName,
+			"grpcbp.clientFaultInterceptor",
+			int(codes.Canceled),
+			int(codes.Unauthenticated),
+			faults.WithDefaultAbort(func(code int, message string) (struct{}, error) {
+				return struct{}{}, status.Error(codes.Code(code), message)
+			}),
+		),
+		streamInjector: *faults.NewInjector(
+			clientName,
+			"grpcbp.clientFaultInterceptor",
+			int(codes.Canceled),
+			int(codes.Unauthenticated),
+			faults.WithDefaultAbort(func(code int, message string) (grpc.ClientStream, error) {
+				return nil, status.Error(codes.Code(code), message)
+			}),
+		),
+	}
+}
+
+// targetAddress strips the resolver scheme from the gRPC dial target.
+func targetAddress(target string) string {
+	for _, scheme := range []string{"dns:///", "passthrough:///"} {
+		if strings.HasPrefix(target, scheme) {
+			return strings.TrimPrefix(target, scheme)
+		}
+	}
+	return target
+}
+
+type grpcHeaders struct{}
+
+var _ faults.Headers = &grpcHeaders{}
+
+// Lookup returns the values of the header from the outgoing metadata, if found.
+func (h *grpcHeaders) LookupValues(ctx context.Context, key string) ([]string, error) {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return md.Get(key), nil
+}
@@ -0,0 +1,73 @@
+package grpcbp
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+)
+
+type probeKeyType struct{}
+
+var probeKey probeKeyType
+
+// HealthCheckServiceName returns the service name used in the standard gRPC
+// health check request (grpc.health.v1.Health/Check) for the given probe.
+//
+// For example, the service name for the LIVENESS probe is "liveness".
+// An empty service name is treated as READINESS.
+func HealthCheckServiceName(probe int64) string {
+	return strings.ToLower(baseplatethrift.IsHealthyProbe(probe).String())
+}
+
+// GetHealthCheckProbe returns the health check probe being checked from the
+// context object passed into baseplate.HealthChecker.IsHealthy by the health
+// service registered by NewBaseplateServer.
+//
+// If the context is not from a health check request, it returns READINESS.
+//
+// Note that because of go's type system,
+// to make this function more useful the returned probe value is casted back to
+// int64 from the thrift defined enum type.
+func GetHealthCheckProbe(ctx context.Context) int64 {
+	if probe, ok := ctx.Value(probeKey).(int64); ok {
+		return probe
+	}
+	return int64(baseplatethrift.IsHealthyProbe_READINESS)
+}
+
+// healthServer implements the standard gRPC health service backed by a
+// baseplate.HealthChecker.
+//
+// Watch is not supported.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+
+	checker baseplate.HealthChecker
+}
+
+func (s healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	probe := baseplatethrift.IsHealthyProbe_READINESS
+	if service := req.GetService(); service != "" {
+		var err error
+		probe, err = baseplatethrift.IsHealthyProbeFromString(strings.ToUpper(service))
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "unknown service %q", service)
+		}
+	}
+
+	resp := &healthpb.HealthCheckResponse{
+		Status: healthpb.HealthCheckResponse_SERVING,
+	}
+	if s.checker != nil && !s.checker.IsHealthy(context.WithValue(ctx, probeKey, int64(probe))) {
+		resp.Status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return resp, nil
+}
+
+var _ healthpb.HealthServer = healthServer{}
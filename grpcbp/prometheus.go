@@ -23,6 +23,7 @@ const (
 	unary        = "unary"
 	clientStream = "client_stream"
 	serverStream = "server_stream"
+	bidiStream   = "bidi_stream"
 )
 
 var (
@@ -106,6 +107,43 @@ var (
 	}, clientActiveRequestsLabels)
 )
 
+const (
+	// Note that this is not used by the prometheus metrics defined in Baseplate
+	// spec.
+	promNamespace = "grpcbp"
+
+	subsystemServer = "server"
+)
+
+var (
+	serverPanicLabels = []string{
+		serviceLabel,
+		methodLabel,
+		typeLabel,
+	}
+
+	serverPanicRecoverCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemServer,
+		Name:      "recovered_panics_total",
+		Help:      "The number of panics recovered from gRPC server handlers",
+	}, serverPanicLabels)
+
+	deadlineBudgetLabels = []string{
+		serviceLabel,
+		methodLabel,
+		typeLabel,
+	}
+
+	deadlineBudgetHisto = promauto.With(prometheusbpint.GlobalRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemServer,
+		Name:      "extracted_deadline_budget_seconds",
+		Help:      "Baseplate deadline budget extracted from client set metadata",
+		Buckets:   prometheusbp.DefaultLatencyBuckets,
+	}, deadlineBudgetLabels)
+)
+
 // serviceAndMethodSlug splits the UnaryServerInfo.FullMethod and returns
 // the package.service part separate from the method part.
 // ref: https://pkg.go.dev/google.golang.org/grpc#UnaryServerInfo
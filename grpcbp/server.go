@@ -0,0 +1,205 @@
+package grpcbp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/internal/admin"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// DefaultStopTimeout is the timeout used by the server returned by
+// NewBaseplateServer to wait for GracefulStop when Config.StopTimeout is not
+// set, matching the default used by baseplate.Serve.
+const DefaultStopTimeout = 30 * time.Second
+
+// ServerArgs defines all of the arguments used to create a new gRPC
+// Baseplate server.
+type ServerArgs struct {
+	// Baseplate is a required argument to NewBaseplateServer and must
+	// be non-nil.
+	Baseplate baseplate.Baseplate
+
+	// RegisterServices is a required argument to NewBaseplateServer,
+	// it is called to register your gRPC services to the server.
+	//
+	// Example:
+	//
+	//     RegisterServices: func(s *grpc.Server) {
+	//         pb.RegisterMyServiceServer(s, &myService{})
+	//     },
+	RegisterServices func(*grpc.Server)
+
+	// HealthChecker is optional, it's used to drive the standard gRPC health
+	// service (grpc.health.v1.Health) registered to the server.
+	//
+	// The service name in the health check request is mapped to the probe,
+	// see HealthCheckServiceName and GetHealthCheckProbe for more details.
+	//
+	// If it's nil, the health service always reports SERVING.
+	HealthChecker baseplate.HealthChecker
+
+	// UnaryInterceptors and StreamInterceptors are optional, additional
+	// interceptors that will be chained after (inside of)
+	// BaseplateDefaultServerInterceptors.
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+
+	// ServerOptions is optional, additional grpc.ServerOptions used to create
+	// the grpc.Server.
+	ServerOptions []grpc.ServerOption
+
+	// ServeAdmin is optional, when set to true the admin server
+	// (see ServeAdmin) will also be started when the server is started.
+	ServeAdmin bool
+
+	// Listener is optional, when set it will be used instead of listening on
+	// the Addr from the Baseplate config.
+	//
+	// Most servers will not need to set this, it's mainly provided for testing.
+	Listener net.Listener
+}
+
+// ValidateAndSetDefaults checks the ServerArgs for any errors and sets any
+// default values.
+//
+// ValidateAndSetDefaults does not generally need to be called manually but can
+// be used for testing purposes.  It is called as a part of setting up a new
+// Baseplate server.
+func (args ServerArgs) ValidateAndSetDefaults() (ServerArgs, error) {
+	var errs []error
+	if args.Baseplate == nil {
+		errs = append(errs, errors.New("argument Baseplate must be non-nil"))
+	}
+	if args.RegisterServices == nil {
+		errs = append(errs, errors.New("argument RegisterServices must be non-nil"))
+	}
+	return args, errors.Join(errs...)
+}
+
+// NewBaseplateServer returns a new gRPC implementation of a Baseplate
+// server with the given ServerArgs.
+//
+// The server is created with BaseplateDefaultServerInterceptors followed by
+// any additional interceptors passed in, and the standard gRPC health service
+// backed by args.HealthChecker is registered alongside the services.
+//
+// Close stops the server gracefully, and falls back to stop it forcefully
+// when it fails to stop within Config.StopTimeout.
+func NewBaseplateServer(args ServerArgs) (baseplate.Server, error) {
+	args, err := args.ValidateAndSetDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := DefaultServerInterceptorsArgs{
+		EdgeContextImpl: args.Baseplate.EdgeContextImpl(),
+	}
+	opts := make([]grpc.ServerOption, 0, len(args.ServerOptions)+2)
+	opts = append(
+		opts,
+		grpc.ChainUnaryInterceptor(append(
+			BaseplateDefaultUnaryServerInterceptors(defaults),
+			args.UnaryInterceptors...,
+		)...),
+		grpc.ChainStreamInterceptor(append(
+			BaseplateDefaultStreamServerInterceptors(defaults),
+			args.StreamInterceptors...,
+		)...),
+	)
+	opts = append(opts, args.ServerOptions...)
+
+	srv := grpc.NewServer(opts...)
+	args.RegisterServices(srv)
+	healthpb.RegisterHealthServer(srv, healthServer{checker: args.HealthChecker})
+
+	return &server{
+		bp:         args.Baseplate,
+		srv:        srv,
+		listener:   args.Listener,
+		serveAdmin: args.ServeAdmin,
+	}, nil
+}
+
+type server struct {
+	bp         baseplate.Baseplate
+	srv        *grpc.Server
+	listener   net.Listener
+	serveAdmin bool
+}
+
+func (s *server) Baseplate() baseplate.Baseplate {
+	return s.bp
+}
+
+func (s *server) Serve() error {
+	if s.serveAdmin {
+		go ServeAdmin()
+	}
+
+	l := s.listener
+	if l == nil {
+		var err error
+		l, err = net.Listen("tcp", s.bp.GetConfig().Addr)
+		if err != nil {
+			return fmt.Errorf("grpcbp: failed to listen: %w", err)
+		}
+	}
+	// Serve returns nil after Stop or GracefulStop is called.
+	return s.srv.Serve(l)
+}
+
+func (s *server) Close() error {
+	timeout := s.bp.GetConfig().StopTimeout
+	if timeout == 0 {
+		timeout = DefaultStopTimeout
+	}
+	if timeout < 0 {
+		s.srv.GracefulStop()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.srv.GracefulStop()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		// Stop also unblocks the pending GracefulStop call.
+		s.srv.Stop()
+		return fmt.Errorf("grpcbp: graceful stop did not finish within %v, server stopped forcefully", timeout)
+	}
+}
+
+var (
+	_ baseplate.Server = (*server)(nil)
+)
+
+// ServeAdmin starts a blocking HTTP server for internal functions:
+//
+//	metrics       - serve /metrics for prometheus
+//	profiling     - serve /debug/pprof for profiling, ref: https://pkg.go.dev/net/http/pprof
+//
+// Default server address is admin.Addr.
+//
+// This function blocks, so it should be run as its own goroutine.
+func ServeAdmin() {
+	if err := admin.Serve(); errors.Is(err, http.ErrServerClosed) {
+		log.Info("grpcbp: admin server closed")
+	} else {
+		log.Panicw("grpcbp: admin serving failed", "err", err)
+	}
+}
@@ -3,9 +3,12 @@ package grpcbp
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/prometheusbp"
+	"github.com/reddit/baseplate.go/ratelimitbp"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -117,6 +121,10 @@ func InitializeEdgeContext(ctx context.Context, impl ecinterface.Interface) cont
 // correctly, this span (and all its child-spans) will never be sampled, unless
 // debug flag was set explicitly later.
 //
+// The W3C traceparent and tracestate headers are also supported,
+// see tracing.StartSpanFromHeaders for the precedence between them and the
+// Baseplate tracing headers.
+//
 // If any of the tracing related gRPC header is present but malformed, it will
 // be ignored. The error will also be logged if InitGlobalTracer was last
 // called with a non-nil logger. Absent tracing related headers are always
@@ -141,10 +149,22 @@ func StartSpanFromGRPCContext(ctx context.Context, name string) (context.Context
 	}
 
 	if value, ok := GetHeader(md, transport.HeaderTracingSampled); ok {
-		sampled = value == transport.HeaderTracingSampled
+		sampled = value == transport.HeaderTracingSampledTrue
 		headers.Sampled = &sampled
 	}
 
+	if value, ok := GetHeader(md, transport.HeaderTracingTraceParent); ok {
+		headers.TraceParent = value
+	}
+
+	if value, ok := GetHeader(md, transport.HeaderTracingTraceState); ok {
+		headers.TraceState = value
+	}
+
+	if value, ok := GetHeader(md, transport.HeaderTracingBaggage); ok {
+		headers.Baggage = value
+	}
+
 	return tracing.StartSpanFromHeaders(ctx, name, headers)
 }
 
@@ -216,3 +236,304 @@ func InjectPrometheusStreamServerInterceptor(serverSlug string) grpc.StreamServe
 		return errors.New("InjectPrometheusStreamServerInterceptor: not implemented")
 	}
 }
+
+// DefaultServerInterceptorsArgs are the args to be passed into
+// BaseplateDefaultServerInterceptors function to create default server
+// interceptors.
+type DefaultServerInterceptorsArgs struct {
+	// The edge context implementation. Optional.
+	//
+	// If it's not set, the global one from ecinterface.Get will be used instead.
+	EdgeContextImpl ecinterface.Interface
+}
+
+// BaseplateDefaultServerInterceptors returns the grpc.ServerOptions to chain
+// the default unary and streaming server interceptors that should be used by a
+// baseplate gRPC service.
+//
+// See BaseplateDefaultUnaryServerInterceptors and
+// BaseplateDefaultStreamServerInterceptors for the actual interceptors.
+func BaseplateDefaultServerInterceptors(args DefaultServerInterceptorsArgs) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(BaseplateDefaultUnaryServerInterceptors(args)...),
+		grpc.ChainStreamInterceptor(BaseplateDefaultStreamServerInterceptors(args)...),
+	}
+}
+
+// BaseplateDefaultUnaryServerInterceptors returns the default unary server
+// interceptors that should be used by a baseplate gRPC service.
+//
+// Currently they are (in order):
+//
+// 1. ExtractDeadlineBudgetInterceptorUnary
+//
+// 2. InjectServerSpanInterceptorUnary
+//
+// 3. InjectEdgeContextInterceptorUnary
+//
+// 4. InjectPrometheusUnaryServerInterceptor
+//
+// 5. AbandonCanceledRequestsInterceptorUnary
+//
+// 6. RecoverPanicInterceptorUnary - This is the last one to ensure that other
+// interceptors get the correct error if panic happened.
+func BaseplateDefaultUnaryServerInterceptors(args DefaultServerInterceptorsArgs) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		ExtractDeadlineBudgetInterceptorUnary(),
+		InjectServerSpanInterceptorUnary(),
+		InjectEdgeContextInterceptorUnary(args.EdgeContextImpl),
+		InjectPrometheusUnaryServerInterceptor(),
+		AbandonCanceledRequestsInterceptorUnary(),
+		RecoverPanicInterceptorUnary(),
+	}
+}
+
+// BaseplateDefaultStreamServerInterceptors returns the default streaming server
+// interceptors that should be used by a baseplate gRPC service.
+//
+// Currently they are (in order):
+//
+// 1. ExtractDeadlineBudgetInterceptorStreaming
+//
+// 2. AbandonCanceledRequestsInterceptorStreaming
+//
+// 3. RecoverPanicInterceptorStreaming
+//
+// The tracing, edge context and Prometheus interceptors are not included as
+// their streaming versions are not implemented yet.
+func BaseplateDefaultStreamServerInterceptors(args DefaultServerInterceptorsArgs) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		ExtractDeadlineBudgetInterceptorStreaming(),
+		AbandonCanceledRequestsInterceptorStreaming(),
+		RecoverPanicInterceptorStreaming(),
+	}
+}
+
+// wrappedServerStream is a grpc.ServerStream with overridden context object.
+type wrappedServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// extractDeadlineBudget sets the timeout on ctx from the "Deadline-Budget"
+// (transport.HeaderDeadlineBudget) metadata if it's at least 1ms.
+//
+// The returned cancel function is never nil.
+func extractDeadlineBudget(ctx context.Context, fullMethod, requestType string) (context.Context, context.CancelFunc) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s, ok := GetHeader(md, transport.HeaderDeadlineBudget)
+	if !ok {
+		return ctx, func() {}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 1 {
+		return ctx, func() {}
+	}
+
+	timeout := time.Duration(v) * time.Millisecond
+	serviceName, method := serviceAndMethodSlug(fullMethod)
+	deadlineBudgetHisto.With(prometheus.Labels{
+		serviceLabel: serviceName,
+		methodLabel:  method,
+		typeLabel:    requestType,
+	}).Observe(timeout.Seconds())
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ExtractDeadlineBudgetInterceptorUnary is the server middleware implementing
+// Phase 1 of Baseplate deadline propagation.
+//
+// It only sets the timeout if the passed in deadline is at least 1ms.
+func ExtractDeadlineBudgetInterceptorUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		ctx, cancel := extractDeadlineBudget(ctx, info.FullMethod, unary)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// ExtractDeadlineBudgetInterceptorStreaming is the server middleware
+// implementing Phase 1 of Baseplate deadline propagation.
+//
+// It only sets the timeout if the passed in deadline is at least 1ms.
+func ExtractDeadlineBudgetInterceptorStreaming() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := extractDeadlineBudget(stream.Context(), info.FullMethod, streamType(info))
+		defer cancel()
+		return handler(srv, wrappedServerStream{
+			ServerStream: stream,
+			ctx:          ctx,
+		})
+	}
+}
+
+// contextErrorToStatus converts context.Canceled and context.DeadlineExceeded
+// errors into the corresponding gRPC status errors.
+func contextErrorToStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		// Already a status error (or nil).
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return err
+}
+
+// AbandonCanceledRequestsInterceptorUnary transforms context.Canceled and
+// context.DeadlineExceeded errors returned by the handler into gRPC status
+// errors with codes.Canceled and codes.DeadlineExceeded.
+//
+// Without it, gRPC reports those errors as codes.Unknown to the client.
+func AbandonCanceledRequestsInterceptorUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, contextErrorToStatus(err)
+	}
+}
+
+// AbandonCanceledRequestsInterceptorStreaming transforms context.Canceled and
+// context.DeadlineExceeded errors returned by the handler into gRPC status
+// errors with codes.Canceled and codes.DeadlineExceeded.
+//
+// Without it, gRPC reports those errors as codes.Unknown to the client.
+func AbandonCanceledRequestsInterceptorStreaming() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return contextErrorToStatus(handler(srv, stream))
+	}
+}
+
+// recoverPanic converts the recovered panic value r into a gRPC status error
+// with codes.Internal, logs it, and records a metric.
+func recoverPanic(ctx context.Context, r interface{}, fullMethod, requestType string) error {
+	var rErr error
+	if asErr, ok := r.(error); ok {
+		rErr = asErr
+	} else {
+		rErr = fmt.Errorf("panic in %q: %+v", fullMethod, r)
+	}
+	log.C(ctx).Errorw(
+		"recovered from panic:",
+		"err", rErr,
+		"endpoint", fullMethod,
+	)
+	serviceName, method := serviceAndMethodSlug(fullMethod)
+	serverPanicRecoverCounter.With(prometheus.Labels{
+		serviceLabel: serviceName,
+		methodLabel:  method,
+		typeLabel:    requestType,
+	}).Inc()
+	return status.Error(codes.Internal, rErr.Error())
+}
+
+// RecoverPanicInterceptorUnary recovers from panics raised in the handler,
+// logs them, records a metric indicating that the endpoint recovered from a
+// panic, and returns a gRPC status error with codes.Internal instead.
+//
+// It emits grpcbp_server_recovered_panics_total counter with labels:
+//
+//   - grpc_service: the fully qualified name of the gRPC service
+//   - grpc_method: the name of the method called on the gRPC service
+//   - grpc_type: type of request, i.e unary
+func RecoverPanicInterceptorUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				resp = nil
+				err = recoverPanic(ctx, r, info.FullMethod, unary)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoverPanicInterceptorStreaming recovers from panics raised in the handler,
+// logs them, records a metric indicating that the endpoint recovered from a
+// panic, and returns a gRPC status error with codes.Internal instead.
+//
+// It emits the same counter as RecoverPanicInterceptorUnary.
+func RecoverPanicInterceptorStreaming() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(stream.Context(), r, info.FullMethod, streamType(info))
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+// streamType returns the grpc_type label value of a streaming request.
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return bidiStream
+	case info.IsClientStream:
+		return clientStream
+	default:
+		return serverStream
+	}
+}
+
+// rateLimit checks the request against the limiter, and returns a
+// codes.ResourceExhausted status error if it's rate limited.
+func rateLimit(ctx context.Context, limiter ratelimitbp.Limiter, key ratelimitbp.KeyFunc, fullMethod string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	headers := ratelimitbp.HeadersFunc(func(key string) string {
+		v, _ := GetHeader(md, key)
+		return v
+	})
+	result, err := limiter.Allow(ctx, key(ctx, headers))
+	if err != nil {
+		log.C(ctx).Warnw(
+			"grpcbp.RateLimit: limiter failed, allowing the request",
+			"err", err,
+			"endpoint", fullMethod,
+		)
+		return nil
+	}
+	if !result.Allowed {
+		return status.Errorf(
+			codes.ResourceExhausted,
+			"%v, retry after %v",
+			ratelimitbp.ErrRateLimited,
+			result.RetryAfter,
+		)
+	}
+	return nil
+}
+
+// RateLimitInterceptorUnary returns a server interceptor that rate limits the
+// requests using the given limiter, with the token bucket keys extracted by
+// key from the incoming metadata.
+//
+// When the request is rate limited, it's rejected with codes.ResourceExhausted
+// without calling the handler.
+// When the limiter returns an error, the error is logged and the request is
+// allowed.
+func RateLimitInterceptorUnary(limiter ratelimitbp.Limiter, key ratelimitbp.KeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := rateLimit(ctx, limiter, key, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitInterceptorStreaming is the streaming version of
+// RateLimitInterceptorUnary.
+//
+// The rate limit is only checked when the stream is created.
+func RateLimitInterceptorStreaming(limiter ratelimitbp.Limiter, key ratelimitbp.KeyFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rateLimit(stream.Context(), limiter, key, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -12,11 +13,13 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/internal/prometheusbpint/spectest"
 	"github.com/reddit/baseplate.go/mqsend"
 	"github.com/reddit/baseplate.go/prometheusbp/promtest"
+	"github.com/reddit/baseplate.go/ratelimitbp"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -309,3 +312,271 @@ func TestInjectPrometheusUnaryServerClientInterceptor(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractDeadlineBudgetInterceptorUnary(t *testing.T) {
+	const method = "Ping"
+	l, service := setupServer(t, grpc.UnaryInterceptor(ExtractDeadlineBudgetInterceptorUnary()))
+	client := pb.NewTestServiceClient(setupClient(t, l))
+
+	for _, c := range []struct {
+		label  string
+		budget string
+		want   bool
+	}{
+		{
+			label:  "valid",
+			budget: "50000",
+			want:   true,
+		},
+		{
+			label:  "zero",
+			budget: "0",
+		},
+		{
+			label:  "invalid",
+			budget: "foo",
+		},
+		{
+			label: "missing",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			labels := prometheus.Labels{
+				serviceLabel: "mwitkow.testproto.TestService",
+				methodLabel:  method,
+				typeLabel:    unary,
+			}
+			var delta int
+			if c.want {
+				delta = 1
+			}
+			defer promtest.NewPrometheusMetricTest(t, "deadline budget", deadlineBudgetHisto, labels).CheckSampleCountDelta(delta)
+
+			ctx := context.Background()
+			if c.budget != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, transport.HeaderDeadlineBudget, c.budget)
+			}
+			if _, err := client.Ping(ctx, &pb.PingRequest{}); err != nil {
+				t.Fatalf("Ping returned error: %v", err)
+			}
+
+			deadline, ok := service.ctx.Deadline()
+			if ok != c.want {
+				t.Fatalf("Expected deadline set to be %v, got %v", c.want, ok)
+			}
+			if ok {
+				if remaining := time.Until(deadline); remaining > 50*time.Second || remaining < 45*time.Second {
+					t.Errorf("Expected remaining deadline to be around 50s, got %v", remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractDeadlineBudgetInterceptorStreaming(t *testing.T) {
+	interceptor := ExtractDeadlineBudgetInterceptorStreaming()
+	ctx := metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs(transport.HeaderDeadlineBudget, "1000"),
+	)
+	info := &grpc.StreamServerInfo{
+		FullMethod:     "/mwitkow.testproto.TestService/PingStream",
+		IsClientStream: true,
+		IsServerStream: true,
+	}
+	err := interceptor(nil, wrappedServerStream{ctx: ctx}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		if _, ok := stream.Context().Deadline(); !ok {
+			t.Error("Expected deadline to be set on stream context")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestAbandonCanceledRequestsInterceptorUnary(t *testing.T) {
+	interceptor := AbandonCanceledRequestsInterceptorUnary()
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/mwitkow.testproto.TestService/Ping",
+	}
+	for _, c := range []struct {
+		label string
+		err   error
+		want  codes.Code
+	}{
+		{
+			label: "canceled",
+			err:   fmt.Errorf("wrapped: %w", context.Canceled),
+			want:  codes.Canceled,
+		},
+		{
+			label: "deadline-exceeded",
+			err:   context.DeadlineExceeded,
+			want:  codes.DeadlineExceeded,
+		},
+		{
+			label: "status",
+			err:   status.Error(codes.NotFound, "not found"),
+			want:  codes.NotFound,
+		},
+		{
+			label: "other",
+			err:   errors.New("error"),
+			want:  codes.Unknown,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, c.err
+			})
+			if got := status.Code(err); got != c.want {
+				t.Errorf("Expected code %v, got %v (%v)", c.want, got, err)
+			}
+		})
+	}
+}
+
+func TestRecoverPanicInterceptorUnary(t *testing.T) {
+	const method = "PingEmpty"
+	l, _ := setupServer(t, grpc.UnaryInterceptor(RecoverPanicInterceptorUnary()))
+	client := pb.NewTestServiceClient(setupClient(t, l))
+
+	labels := prometheus.Labels{
+		serviceLabel: "mwitkow.testproto.TestService",
+		methodLabel:  method,
+		typeLabel:    unary,
+	}
+	defer promtest.NewPrometheusMetricTest(t, "panic counter", serverPanicRecoverCounter, labels).CheckDelta(1)
+
+	_, err := client.PingEmpty(context.Background(), &pb.Empty{})
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("Expected code %v, got %v (%v)", codes.Internal, got, err)
+	}
+}
+
+func TestRecoverPanicInterceptorStreaming(t *testing.T) {
+	const method = "PingList"
+	l, _ := setupServer(t, grpc.StreamInterceptor(RecoverPanicInterceptorStreaming()))
+	client := pb.NewTestServiceClient(setupClient(t, l))
+
+	labels := prometheus.Labels{
+		serviceLabel: "mwitkow.testproto.TestService",
+		methodLabel:  method,
+		typeLabel:    serverStream,
+	}
+	defer promtest.NewPrometheusMetricTest(t, "panic counter", serverPanicRecoverCounter, labels).CheckDelta(1)
+
+	stream, err := client.PingList(context.Background(), &pb.PingRequest{})
+	if err != nil {
+		t.Fatalf("PingList returned error: %v", err)
+	}
+	_, err = stream.Recv()
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("Expected code %v, got %v (%v)", codes.Internal, got, err)
+	}
+}
+
+func TestStreamType(t *testing.T) {
+	for _, c := range []struct {
+		info grpc.StreamServerInfo
+		want string
+	}{
+		{
+			info: grpc.StreamServerInfo{IsClientStream: true},
+			want: clientStream,
+		},
+		{
+			info: grpc.StreamServerInfo{IsServerStream: true},
+			want: serverStream,
+		},
+		{
+			info: grpc.StreamServerInfo{IsClientStream: true, IsServerStream: true},
+			want: bidiStream,
+		},
+	} {
+		t.Run(c.want, func(t *testing.T) {
+			if got := streamType(&c.info); got != c.want {
+				t.Errorf("streamType(%+v) got %q, want %q", c.info, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBaseplateDefaultServerInterceptors(t *testing.T) {
+	initTracing(t)
+	l, _ := setupServer(t, BaseplateDefaultServerInterceptors(DefaultServerInterceptorsArgs{
+		EdgeContextImpl: ecinterface.Mock(),
+	})...)
+	client := pb.NewTestServiceClient(setupClient(t, l))
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{}); err != nil {
+		t.Errorf("Ping returned error: %v", err)
+	}
+	_, err := client.PingEmpty(context.Background(), &pb.Empty{})
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("Expected code %v, got %v (%v)", codes.Internal, got, err)
+	}
+}
+
+func TestRateLimitInterceptorUnary(t *testing.T) {
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:  "grpcbp-test",
+		Rate:  0.1,
+		Burst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := setupServer(t, grpc.UnaryInterceptor(
+		RateLimitInterceptorUnary(limiter, ratelimitbp.HeaderKey("x-client")),
+	))
+	client := pb.NewTestServiceClient(setupClient(t, l))
+	ping := func(name string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-client", name)
+		_, err := client.Ping(ctx, &pb.PingRequest{})
+		return err
+	}
+
+	if err := ping("foo"); err != nil {
+		t.Errorf("Expected first request to be allowed, got %v", err)
+	}
+	if got := status.Code(ping("foo")); got != codes.ResourceExhausted {
+		t.Errorf("Expected code %v, got %v", codes.ResourceExhausted, got)
+	}
+	if err := ping("bar"); err != nil {
+		t.Errorf("Expected request from another client to be allowed, got %v", err)
+	}
+}
+
+func TestRateLimitInterceptorStreaming(t *testing.T) {
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:  "grpcbp-stream-test",
+		Rate:  0.1,
+		Burst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := RateLimitInterceptorStreaming(limiter, ratelimitbp.HeaderKey("x-client"))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-client", "foo"))
+	info := &grpc.StreamServerInfo{
+		FullMethod: "/mwitkow.testproto.TestService/PingList",
+	}
+	var called int
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		called++
+		return nil
+	}
+
+	if err := interceptor(nil, wrappedServerStream{ctx: ctx}, info, handler); err != nil {
+		t.Errorf("Expected first stream to be allowed, got %v", err)
+	}
+	err = interceptor(nil, wrappedServerStream{ctx: ctx}, info, handler)
+	if got := status.Code(err); got != codes.ResourceExhausted {
+		t.Errorf("Expected code %v, got %v", codes.ResourceExhausted, got)
+	}
+	if called != 1 {
+		t.Errorf("Expected handler to be called once, got %d", called)
+	}
+}
@@ -0,0 +1,153 @@
+package grpcbp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/ecinterface"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+)
+
+type healthCheckerFunc func(ctx context.Context) bool
+
+func (f healthCheckerFunc) IsHealthy(ctx context.Context) bool {
+	return f(ctx)
+}
+
+func setupBaseplateServer(t *testing.T, cfg baseplate.Config, checker baseplate.HealthChecker) (*bufconn.Listener, baseplate.Server, <-chan error) {
+	t.Helper()
+
+	l := bufconn.Listen(1024 * 1024)
+	srv, err := NewBaseplateServer(ServerArgs{
+		Baseplate: baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+			Config:          cfg,
+			EdgeContextImpl: ecinterface.Mock(),
+		}),
+		RegisterServices: func(s *grpc.Server) {
+			pb.RegisterTestServiceServer(s, &mockService{})
+		},
+		HealthChecker: checker,
+		Listener:      l,
+	})
+	if err != nil {
+		t.Fatalf("NewBaseplateServer returned error: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve()
+	}()
+	return l, srv, served
+}
+
+func TestNewBaseplateServerValidation(t *testing.T) {
+	if _, err := NewBaseplateServer(ServerArgs{}); err == nil {
+		t.Error("Expected error for empty ServerArgs, got nil")
+	}
+}
+
+func TestNewBaseplateServer(t *testing.T) {
+	probes := make(chan int64, 1)
+	l, srv, served := setupBaseplateServer(t, baseplate.Config{}, healthCheckerFunc(func(ctx context.Context) bool {
+		probe := GetHealthCheckProbe(ctx)
+		probes <- probe
+		return probe != int64(baseplatethrift.IsHealthyProbe_STARTUP)
+	}))
+	conn := setupClient(t, l)
+
+	if _, err := pb.NewTestServiceClient(conn).Ping(context.Background(), &pb.PingRequest{}); err != nil {
+		t.Errorf("Ping returned error: %v", err)
+	}
+	_, err := pb.NewTestServiceClient(conn).PingEmpty(context.Background(), &pb.Empty{})
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("Expected recovered panic to return code %v, got %v (%v)", codes.Internal, got, err)
+	}
+
+	health := healthpb.NewHealthClient(conn)
+	for _, c := range []struct {
+		service string
+		probe   baseplatethrift.IsHealthyProbe
+		want    healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			service: "",
+			probe:   baseplatethrift.IsHealthyProbe_READINESS,
+			want:    healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			service: HealthCheckServiceName(int64(baseplatethrift.IsHealthyProbe_LIVENESS)),
+			probe:   baseplatethrift.IsHealthyProbe_LIVENESS,
+			want:    healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			service: "startup",
+			probe:   baseplatethrift.IsHealthyProbe_STARTUP,
+			want:    healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	} {
+		t.Run(c.service, func(t *testing.T) {
+			resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{
+				Service: c.service,
+			})
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if got := resp.GetStatus(); got != c.want {
+				t.Errorf("Expected status %v, got %v", c.want, got)
+			}
+			if got := <-probes; got != int64(c.probe) {
+				t.Errorf("Expected probe %v, got %v", c.probe, got)
+			}
+		})
+	}
+
+	_, err = health.Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: "foo",
+	})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("Expected code %v for unknown service, got %v (%v)", codes.NotFound, got, err)
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned error: %v", err)
+	}
+}
+
+func TestNewBaseplateServerStopTimeout(t *testing.T) {
+	started := make(chan struct{})
+	l, srv, served := setupBaseplateServer(
+		t,
+		baseplate.Config{
+			StopTimeout: 10 * time.Millisecond,
+		},
+		healthCheckerFunc(func(ctx context.Context) bool {
+			close(started)
+			<-ctx.Done()
+			return false
+		}),
+	)
+	conn := setupClient(t, l)
+
+	go healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	<-started
+
+	if err := srv.Close(); err == nil {
+		t.Error("Expected Close to return error when the pending request is not finished within StopTimeout")
+	}
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Error("Expected Serve to return after Close")
+	}
+}
@@ -13,31 +13,58 @@ import (
 
 // CreateGRPCContextFromSpan injects span info into a context object that can
 // be used in gRPC client code.
+//
+// The Baseplate tracing headers, the W3C traceparent/tracestate headers and
+// the allowed baggage items are injected.
 func CreateGRPCContextFromSpan(ctx context.Context, span *tracing.Span) context.Context {
 	kvs := []string{
 		transport.HeaderTracingTrace, span.TraceID(),
 		transport.HeaderTracingSpan, span.ID(),
 		transport.HeaderTracingFlags, strconv.FormatInt(span.Flags(), 10),
+		transport.HeaderTracingTraceParent, span.TraceParent(),
+	}
+
+	if state := span.TraceState(); state != "" {
+		kvs = append(kvs, transport.HeaderTracingTraceState, state)
+	}
+
+	var unset []string
+	if baggage := span.Baggage(); baggage != "" {
+		kvs = append(kvs, transport.HeaderTracingBaggage, baggage)
+	} else if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(transport.HeaderTracingBaggage)) > 0 {
+		unset = append(unset, transport.HeaderTracingBaggage)
 	}
 
 	if span.ParentID() != "" {
 		kvs = append(kvs, transport.HeaderTracingParent, span.ParentID())
 	} else {
-		md, _ := metadata.FromIncomingContext(ctx)
-		md.Delete(transport.HeaderTracingParent)
-		ctx = metadata.NewOutgoingContext(ctx, md)
+		unset = append(unset, transport.HeaderTracingParent)
 	}
 
 	if span.Sampled() {
 		kvs = append(kvs, transport.HeaderTracingSampled, transport.HeaderTracingSampledTrue)
 	} else {
+		unset = append(unset, transport.HeaderTracingSampled)
+	}
+
+	if len(unset) > 0 {
 		md, _ := metadata.FromIncomingContext(ctx)
-		md.Delete(transport.HeaderTracingSampled)
+		for _, key := range unset {
+			md.Delete(key)
+		}
 		ctx = metadata.NewOutgoingContext(ctx, md)
 	}
 
-	return metadata.AppendToOutgoingContext(ctx, kvs...)
-
+	// Set instead of append, so the values copied from the incoming metadata
+	// above are overwritten instead of duplicated.
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if md == nil {
+		md = metadata.MD{}
+	}
+	for i := 0; i < len(kvs); i += 2 {
+		md.Set(kvs[i], kvs[i+1])
+	}
+	return metadata.NewOutgoingContext(ctx, md)
 }
 
 func methodSlug(method string) string {
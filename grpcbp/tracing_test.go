@@ -0,0 +1,141 @@
+package grpcbp
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+func TestStartSpanFromGRPCContext(t *testing.T) {
+	t.Run("baseplate", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			transport.HeaderTracingTrace, "12345",
+			transport.HeaderTracingSpan, "67890",
+			transport.HeaderTracingSampled, transport.HeaderTracingSampledTrue,
+		))
+		_, span := StartSpanFromGRPCContext(ctx, "test")
+		if got, want := span.TraceID(), "12345"; got != want {
+			t.Errorf("TraceID got %q, want %q", got, want)
+		}
+		if got, want := span.ParentID(), "67890"; got != want {
+			t.Errorf("ParentID got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("w3c", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			transport.HeaderTracingTraceParent, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			transport.HeaderTracingTraceState, "foo=bar",
+		))
+		_, span := StartSpanFromGRPCContext(ctx, "test")
+		if got, want := span.TraceID(), "0af7651916cd43dd8448eb211c80319c"; got != want {
+			t.Errorf("TraceID got %q, want %q", got, want)
+		}
+		if got, want := span.ParentID(), "b7ad6b7169203331"; got != want {
+			t.Errorf("ParentID got %q, want %q", got, want)
+		}
+		if !span.Sampled() {
+			t.Error("Expected span to be sampled")
+		}
+		if got, want := span.TraceState(), "foo=bar"; got != want {
+			t.Errorf("TraceState got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestStartSpanFromGRPCContextSampled(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		value string
+		want  bool
+	}{
+		{
+			label: "sampled",
+			value: transport.HeaderTracingSampledTrue,
+			want:  true,
+		},
+		{
+			label: "not-sampled",
+			value: "0",
+			want:  false,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				transport.HeaderTracingTrace, "12345",
+				transport.HeaderTracingSpan, "67890",
+				transport.HeaderTracingSampled, c.value,
+			))
+			_, span := StartSpanFromGRPCContext(ctx, "test")
+			if got := span.Sampled(); got != c.want {
+				t.Errorf("Sampled got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCreateGRPCContextFromSpan(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		transport.HeaderTracingTraceParent, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		transport.HeaderTracingTraceState, "foo=bar",
+	))
+	ctx, span := StartSpanFromGRPCContext(ctx, "test")
+
+	md, _ := metadata.FromOutgoingContext(CreateGRPCContextFromSpan(ctx, span))
+	for _, c := range []struct {
+		key  string
+		want string
+	}{
+		{key: transport.HeaderTracingTrace, want: span.TraceID()},
+		{key: transport.HeaderTracingSpan, want: span.ID()},
+		{key: transport.HeaderTracingSampled, want: transport.HeaderTracingSampledTrue},
+		{key: transport.HeaderTracingTraceParent, want: span.TraceParent()},
+		{key: transport.HeaderTracingTraceState, want: "foo=bar"},
+	} {
+		if got, _ := GetHeader(md, c.key); got != c.want {
+			t.Errorf("Header %q got %q, want %q", c.key, got, c.want)
+		}
+	}
+
+	tp, err := tracing.ParseTraceParent(span.TraceParent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.TraceID != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("Expected traceparent to continue the trace, got %q", tp.TraceID)
+	}
+}
+
+func TestBaggagePropagation(t *testing.T) {
+	if err := tracing.InitGlobalTracer(tracing.Config{
+		Baggage: tracing.BaggageConfig{AllowList: []string{"foo"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tracing.InitGlobalTracer(tracing.Config{})
+	})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		transport.HeaderTracingTrace, "12345",
+		transport.HeaderTracingSpan, "67890",
+		transport.HeaderTracingBaggage, "foo=bar,other=value",
+	))
+	ctx, span := StartSpanFromGRPCContext(ctx, "test")
+	if got := span.BaggageItem("foo"); got != "bar" {
+		t.Errorf("Expected baggage foo=bar, got %q", got)
+	}
+	if got := span.BaggageItem("other"); got != "" {
+		t.Errorf("Expected baggage other to be dropped, got %q", got)
+	}
+
+	span.SetBaggageItem("foo", "baz")
+	md, _ := metadata.FromOutgoingContext(CreateGRPCContextFromSpan(ctx, span))
+	if got, want := md.Get(transport.HeaderTracingBaggage), []string{"foo=baz"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("Baggage header got %q, want %q", got, want)
+	}
+}
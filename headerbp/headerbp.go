@@ -128,6 +128,19 @@ func setHeadersOnContext(ctx context.Context, headers map[string]string) context
 	return context.WithValue(ctx, headersKey{}, headers)
 }
 
+// GetHeader returns the value of the baseplate header attached to the context
+// by IncomingHeaders.SetOnContext, if found.
+//
+// The key is case insensitive.
+func GetHeader(ctx context.Context, key string) (string, bool) {
+	headers, ok := ctx.Value(headersKey{}).(map[string]string)
+	if !ok {
+		return "", false
+	}
+	v, ok := headers[normalizeKey(key, false)]
+	return v, ok
+}
+
 // ShouldRemoveClientHeader checks if the header is allowlisted and returns if the header should be removed
 func ShouldRemoveClientHeader(name string, options ...CheckClientHeaderOption) bool {
 	cfg := &shouldRemoveClientHeaders{}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/avast/retry-go"
+	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/reddit/baseplate.go/headerbp"
 	"github.com/reddit/baseplate.go/internal/faults"
@@ -22,6 +23,7 @@ import (
 	//lint:ignore SA1019 This library is internal only, not actually deprecated
 	"github.com/reddit/baseplate.go/internalv2compat"
 	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
 
@@ -301,6 +303,91 @@ func MonitorClient(slug string) ClientMiddleware {
 	}
 }
 
+// SetSpanHeaders sets the Baseplate span headers, the W3C
+// traceparent/tracestate headers and the baggage header from span onto h,
+// so the downstream server continues the same trace.
+func SetSpanHeaders(h http.Header, span *tracing.Span) {
+	setBaseplateSpanHeaders(h, span)
+	setW3CSpanHeaders(h, span)
+	setBaggageHeader(h, span)
+}
+
+func setBaseplateSpanHeaders(h http.Header, span *tracing.Span) {
+	h.Set(TraceIDHeader, span.TraceID())
+	h.Set(SpanIDHeader, span.ID())
+	h.Set(SpanFlagsHeader, strconv.FormatInt(span.Flags(), 10))
+	if span.ParentID() != "" {
+		h.Set(ParentIDHeader, span.ParentID())
+	} else {
+		h.Del(ParentIDHeader)
+	}
+	if span.Sampled() {
+		h.Set(SpanSampledHeader, spanSampledTrue)
+	} else {
+		h.Del(SpanSampledHeader)
+	}
+}
+
+func setW3CSpanHeaders(h http.Header, span *tracing.Span) {
+	h.Set(TraceParentHeader, span.TraceParent())
+	if state := span.TraceState(); state != "" {
+		h.Set(TraceStateHeader, state)
+	} else {
+		h.Del(TraceStateHeader)
+	}
+}
+
+func setBaggageHeader(h http.Header, span *tracing.Span) {
+	if baggage := span.Baggage(); baggage != "" {
+		h.Set(BaggageHeader, baggage)
+	} else {
+		h.Del(BaggageHeader)
+	}
+}
+
+// ForwardSpanHeaders is an HTTP client middleware that sets the span headers
+// of the span in the request context onto the outgoing request,
+// the same as SetSpanHeaders.
+//
+// Each group of the headers (the Baseplate headers, the W3C
+// traceparent/tracestate headers, and the baggage header) is only set when
+// the request doesn't have it set already (by X-Trace, traceparent and
+// baggage respectively), e.g. by the injected v2 tracing middleware.
+// Requests without a tracing.Span in the context are sent unchanged.
+//
+// It's not included by NewClient, as it would leak the trace and the baggage
+// to third-party hosts. Add it to the middlewares of the clients calling
+// internal services only.
+func ForwardSpanHeaders() ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			span, ok := opentracing.SpanFromContext(req.Context()).(*tracing.Span)
+			if !ok || span == nil {
+				return next.RoundTrip(req)
+			}
+			var setters []func(http.Header, *tracing.Span)
+			if !isHeaderSet(req.Header, TraceIDHeader) {
+				setters = append(setters, setBaseplateSpanHeaders)
+			}
+			if !isHeaderSet(req.Header, TraceParentHeader) {
+				setters = append(setters, setW3CSpanHeaders)
+			}
+			if !isHeaderSet(req.Header, BaggageHeader) {
+				setters = append(setters, setBaggageHeader)
+			}
+			if len(setters) == 0 {
+				return next.RoundTrip(req)
+			}
+			// RoundTrippers must not modify the original request.
+			req = req.Clone(req.Context())
+			for _, set := range setters {
+				set(req.Header, span)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
 // PrometheusClientMetrics returns a middleware that tracks Prometheus metrics for client http.
 //
 // It emits the following prometheus metrics:
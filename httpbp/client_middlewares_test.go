@@ -2,6 +2,7 @@ package httpbp
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/internal/faults"
+	"github.com/reddit/baseplate.go/tracing"
 )
 
 func TestNewClient(t *testing.T) {
@@ -522,3 +524,134 @@ func TestFaultInjection(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardSpanHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: WrapTransport(nil, ForwardSpanHeaders()),
+	}
+
+	t.Run("span", func(t *testing.T) {
+		ctx, span := tracing.StartSpanFromHeaders(context.Background(), "test", tracing.Headers{
+			TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			TraceState:  "foo=bar",
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		for key, want := range map[string]string{
+			TraceIDHeader:     span.TraceID(),
+			SpanIDHeader:      span.ID(),
+			ParentIDHeader:    span.ParentID(),
+			SpanSampledHeader: spanSampledTrue,
+			TraceParentHeader: span.TraceParent(),
+			TraceStateHeader:  "foo=bar",
+		} {
+			if got := got.Get(key); got != want {
+				t.Errorf("Header %q got %q, want %q", key, got, want)
+			}
+		}
+		if req.Header.Get(TraceIDHeader) != "" {
+			t.Error("Expected the original request to be unchanged")
+		}
+	})
+
+	t.Run("x-trace-set", func(t *testing.T) {
+		ctx, span := tracing.StartSpanFromHeaders(context.Background(), "test", tracing.Headers{})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(TraceIDHeader, "12345")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := got.Get(TraceIDHeader); got != "12345" {
+			t.Errorf("Expected the existing %s header to be kept, got %q", TraceIDHeader, got)
+		}
+		if got, want := got.Get(TraceParentHeader), span.TraceParent(); got != want {
+			t.Errorf("Header %q got %q, want %q", TraceParentHeader, got, want)
+		}
+	})
+
+	t.Run("new-client", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{Slug: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, _ := tracing.StartSpanFromHeaders(context.Background(), "test", tracing.Headers{})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got.Get(TraceParentHeader) != "" {
+			t.Errorf("Expected NewClient to not forward span headers by default, got %v", got)
+		}
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got.Get(TraceIDHeader) != "" || got.Get(TraceParentHeader) != "" {
+			t.Errorf("Expected no span headers, got %v", got)
+		}
+	})
+}
+
+func TestSetSpanHeadersBaggage(t *testing.T) {
+	if err := tracing.InitGlobalTracer(tracing.Config{
+		Baggage: tracing.BaggageConfig{AllowList: []string{"foo"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tracing.InitGlobalTracer(tracing.Config{})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceIDHeader, "12345")
+	req.Header.Set(BaggageHeader, "foo=bar,other=value")
+	_, span := StartSpanFromTrustedRequest(context.Background(), "test", AlwaysTrustHeaders{}, req)
+	if got := span.BaggageItem("foo"); got != "bar" {
+		t.Errorf("Expected baggage foo=bar, got %q", got)
+	}
+	if got := span.BaggageItem("other"); got != "" {
+		t.Errorf("Expected baggage other to be dropped, got %q", got)
+	}
+
+	h := http.Header{}
+	SetSpanHeaders(h, span)
+	if got, want := h.Get(BaggageHeader), "foo=bar"; got != want {
+		t.Errorf("Baggage header got %q, want %q", got, want)
+	}
+
+	h.Set(BaggageHeader, "foo=stale")
+	span.SetBaggageItem("foo", "")
+	SetSpanHeaders(h, span)
+	if got, want := h.Get(BaggageHeader), "foo="; got != want {
+		t.Errorf("Baggage header got %q, want %q", got, want)
+	}
+}
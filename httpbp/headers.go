@@ -44,6 +44,18 @@ const (
 	// TraceIDHeader is the key use to get the trace ID from the HTTP
 	// request headers.
 	TraceIDHeader = "X-Trace"
+
+	// TraceParentHeader is the key use to get the W3C traceparent from the
+	// HTTP request headers.
+	TraceParentHeader = "Traceparent"
+
+	// TraceStateHeader is the key use to get the W3C tracestate from the HTTP
+	// request headers.
+	TraceStateHeader = "Tracestate"
+
+	// BaggageHeader is the key use to get the W3C baggage from the HTTP
+	// request headers.
+	BaggageHeader = "Baggage"
 )
 
 // Headers is an interface to collect all of the HTTP headers for a particular
@@ -16,6 +16,7 @@ import (
 	"github.com/reddit/baseplate.go/headerbp"
 	"github.com/reddit/baseplate.go/secrets"
 
+	"github.com/reddit/baseplate.go/concurrencybp"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/errorsbp"
 	//lint:ignore SA1019 This library is internal only, not actually deprecated
@@ -23,6 +24,7 @@ import (
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/metricsbp"
 	"github.com/reddit/baseplate.go/prometheusbp"
+	"github.com/reddit/baseplate.go/ratelimitbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -94,6 +96,9 @@ func isHeaderSet(h http.Header, key string) bool {
 // be trusted and the Span headers are provided, otherwise it starts a new
 // server span.
 //
+// Both the Baseplate X-Trace headers and the W3C traceparent/tracestate headers
+// are read, see tracing.StartSpanFromHeaders for the precedence between them.
+//
 // StartSpanFromTrustedRequest is used by InjectServerSpan and should not
 // generally be used directly but is provided for testing purposes or use cases
 // that are not covered by Baseplate.
@@ -120,6 +125,9 @@ func StartSpanFromTrustedRequest(
 			sampled = r.Header.Get(SpanSampledHeader) == spanSampledTrue
 			spanHeaders.Sampled = &sampled
 		}
+		spanHeaders.TraceParent = r.Header.Get(TraceParentHeader)
+		spanHeaders.TraceState = r.Header.Get(TraceStateHeader)
+		spanHeaders.Baggage = r.Header.Get(BaggageHeader)
 	}
 
 	return tracing.StartSpanFromHeaders(ctx, name, spanHeaders)
@@ -603,3 +611,63 @@ func ServerBaseplateHeadersMiddleware(service string, store SecretsStore, path s
 		}
 	}
 }
+
+// ConcurrencyLimit returns a server middleware that sheds load using the given
+// adaptive concurrency limiter.
+//
+// When the limit is reached, the request is rejected with a 503 Service
+// Unavailable error with the Retry-After header set to limiter.RetryAfter().
+// Otherwise the latency and the outcome of the request (see
+// concurrencybp.OutcomeFromError) are reported back to the limiter to adjust
+// the limit.
+//
+// It should usually be the first middleware after the default ones, so that
+// rejected requests are rejected as early and as cheap as possible.
+func ConcurrencyLimit(limiter *concurrencybp.Limiter) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+			release, ok := limiter.Acquire()
+			if !ok {
+				return RawError(
+					ServiceUnavailable().Retryable(w, limiter.RetryAfter()),
+					fmt.Errorf("%q: %w", name, concurrencybp.ErrLimitExceeded),
+					PlainTextContentType,
+				)
+			}
+			defer func() {
+				release(concurrencybp.OutcomeFromError(err))
+			}()
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// RateLimit returns a server middleware that rate limits the requests using
+// the given limiter, with the token bucket keys extracted by key from the
+// request headers.
+//
+// When the request is rate limited, it's rejected with a 429 Too Many Requests
+// error with the Retry-After header set.
+// When the limiter returns an error, the error is logged and the request is
+// allowed.
+func RateLimit(limiter ratelimitbp.Limiter, key ratelimitbp.KeyFunc) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			result, err := limiter.Allow(ctx, key(ctx, r.Header))
+			if err != nil {
+				log.C(ctx).Warnw(
+					"httpbp.RateLimit: limiter failed, allowing the request",
+					"err", err,
+					"endpoint", name,
+				)
+			} else if !result.Allowed {
+				return RawError(
+					TooManyRequests().Retryable(w, result.RetryAfter),
+					fmt.Errorf("%q: %w", name, ratelimitbp.ErrRateLimited),
+					PlainTextContentType,
+				)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
@@ -10,11 +10,18 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/concurrencybp"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
 )
 
 func TestWrap(t *testing.T) {
@@ -357,3 +364,183 @@ func (p *pushableResponseRecorder) Push(target string, opts *http.PushOptions) e
 	p.Pushed = true
 	return nil
 }
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := concurrencybp.New(concurrencybp.Config{
+		Name:         "httpbp-test",
+		InitialLimit: 1,
+		MaxLimit:     1,
+		RetryAfter:   time.Second * 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle := httpbp.Wrap(
+		"test",
+		newTestHandler(testHandlerPlan{}),
+		httpbp.ConcurrencyLimit(limiter),
+	)
+
+	t.Run("accepted", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := handle(context.TODO(), w, newRequest(t, "")); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if got := limiter.Inflight(); got != 0 {
+			t.Errorf("Expected the request to be released, got %d inflight", got)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		release, ok := limiter.Acquire()
+		if !ok {
+			t.Fatal("Failed to acquire from limiter")
+		}
+		defer release(concurrencybp.Ignored)
+
+		w := httptest.NewRecorder()
+		err := handle(context.TODO(), w, newRequest(t, ""))
+		if !errors.Is(err, concurrencybp.ErrLimitExceeded) {
+			t.Errorf("Expected error %v, got %v", concurrencybp.ErrLimitExceeded, err)
+		}
+		var httpErr httpbp.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("Expected HTTPError, got %#v", err)
+		}
+		if got := httpErr.Response().Code; got != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, got)
+		}
+		if got := w.Header().Get(httpbp.RetryAfterHeader); got != "2" {
+			t.Errorf("Expected %s header to be %q, got %q", httpbp.RetryAfterHeader, "2", got)
+		}
+	})
+}
+
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(context.Context, string) (ratelimitbp.Result, error) {
+	return ratelimitbp.Result{}, errors.New("failed")
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:  "httpbp-test",
+		Rate:  0.1,
+		Burst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := httpbp.Wrap(
+		"test",
+		newTestHandler(testHandlerPlan{}),
+		httpbp.RateLimit(limiter, ratelimitbp.ClientNameKey()),
+	)
+	request := func(client string) (*httptest.ResponseRecorder, error) {
+		req := newRequest(t, "")
+		req.Header.Set("User-Agent", client)
+		w := httptest.NewRecorder()
+		return w, handle(context.TODO(), w, req)
+	}
+
+	if _, err := request("foo"); err != nil {
+		t.Errorf("Expected first request to be allowed, got %v", err)
+	}
+	w, err := request("foo")
+	if !errors.Is(err, ratelimitbp.ErrRateLimited) {
+		t.Errorf("Expected error %v, got %v", ratelimitbp.ErrRateLimited, err)
+	}
+	var httpErr httpbp.HTTPError
+	if errors.As(err, &httpErr) {
+		if got := httpErr.Response().Code; got != http.StatusTooManyRequests {
+			t.Errorf("Expected status code %d, got %d", http.StatusTooManyRequests, got)
+		}
+	} else {
+		t.Errorf("Expected HTTPError, got %#v", err)
+	}
+	if w.Header().Get(httpbp.RetryAfterHeader) == "" {
+		t.Errorf("Expected %s header to be set", httpbp.RetryAfterHeader)
+	}
+	if _, err := request("bar"); err != nil {
+		t.Errorf("Expected request from another client to be allowed, got %v", err)
+	}
+
+	t.Run("fail-open", func(t *testing.T) {
+		handle := httpbp.Wrap(
+			"test",
+			newTestHandler(testHandlerPlan{}),
+			httpbp.RateLimit(failingRateLimiter{}, ratelimitbp.ClientNameKey()),
+		)
+		if err := handle(context.TODO(), httptest.NewRecorder(), newRequest(t, "")); err != nil {
+			t.Errorf("Expected request to be allowed when the limiter fails, got %v", err)
+		}
+	})
+}
+
+func TestStartSpanFromTrustedRequestW3C(t *testing.T) {
+	const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	req := newRequest(t, "")
+	req.Header.Set(httpbp.TraceParentHeader, traceParent)
+	req.Header.Set(httpbp.TraceStateHeader, "foo=bar")
+
+	t.Run("trusted", func(t *testing.T) {
+		_, span := httpbp.StartSpanFromTrustedRequest(
+			context.Background(),
+			"test",
+			httpbp.AlwaysTrustHeaders{},
+			req,
+		)
+		if got, want := span.TraceID(), "0af7651916cd43dd8448eb211c80319c"; got != want {
+			t.Errorf("TraceID got %q, want %q", got, want)
+		}
+		if got, want := span.ParentID(), "b7ad6b7169203331"; got != want {
+			t.Errorf("ParentID got %q, want %q", got, want)
+		}
+		if !span.Sampled() {
+			t.Error("Expected span to be sampled")
+		}
+		if got, want := span.TraceState(), "foo=bar"; got != want {
+			t.Errorf("TraceState got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		_, span := httpbp.StartSpanFromTrustedRequest(
+			context.Background(),
+			"test",
+			httpbp.NeverTrustHeaders{},
+			req,
+		)
+		if span.ParentID() != "" || span.TraceState() != "" {
+			t.Errorf("Expected untrusted headers to be ignored, got parent %q, state %q", span.ParentID(), span.TraceState())
+		}
+	})
+}
+
+func TestInjectServerSpan(t *testing.T) {
+	recorder := tracingtest.Setup(t, tracing.Config{})
+
+	handle := httpbp.Wrap(
+		"endpoint",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			child, _ := opentracing.StartSpanFromContext(ctx, "child")
+			child.Finish()
+			return httpbp.JSONError(httpbp.InternalServerError(), nil)
+		},
+		httpbp.InjectServerSpan(httpbp.NeverTrustHeaders{}),
+	)
+	handle(context.Background(), httptest.NewRecorder(), newRequest(t, ""))
+
+	if got, want := recorder.Tree().String(), "endpoint\n  child\n"; got != want {
+		t.Errorf("Span tree expected %q, got %q", want, got)
+	}
+	if span := recorder.MustFindOne(t, "endpoint"); !span.IsServer() || !span.Failed() {
+		t.Errorf("Expected failed server span, got %+v", span)
+	}
+}
@@ -0,0 +1,212 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// LogLevelPath is the path of the log level endpoint on Mux.
+const LogLevelPath = "/loglevel"
+
+// LogLevelRequest is the JSON body of PUT requests to the log level endpoint.
+type LogLevelRequest struct {
+	// The level to set, e.g. "debug".
+	//
+	// It's required when Name is empty.
+	// When Name is set, an empty Level removes the override of Name.
+	Level string `json:"level"`
+
+	// Optional name of the logger to override the level of,
+	// see log.SetNamedLevel.
+	//
+	// When it's empty the global level is changed.
+	Name string `json:"name,omitempty"`
+
+	// Optional duration after which the change is reverted, e.g. "10m".
+	Duration string `json:"duration,omitempty"`
+}
+
+// LogLevelResponse is the JSON body of the responses of the log level
+// endpoint.
+type LogLevelResponse struct {
+	Level string            `json:"level"`
+	Named map[string]string `json:"named,omitempty"`
+
+	// The pending reverts, keyed by the logger names,
+	// with empty name for the global level.
+	RevertAt map[string]time.Time `json:"revertAt,omitempty"`
+}
+
+// logLevelHandler serves the log level endpoint.
+//
+// GET returns the current levels,
+// PUT changes a level with a LogLevelRequest body.
+type logLevelHandler struct {
+	lock    sync.Mutex
+	reverts map[string]*pendingRevert
+}
+
+// pendingRevert is a scheduled revert of the level of a logger.
+type pendingRevert struct {
+	timer *time.Timer
+	at    time.Time
+	// The level before the first change, nil means no override.
+	original *zapcore.Level
+}
+
+func newLogLevelHandler() *logLevelHandler {
+	return &logLevelHandler{
+		reverts: make(map[string]*pendingRevert),
+	}
+}
+
+func (h *logLevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := h.update(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.current())
+}
+
+func (h *logLevelHandler) update(r *http.Request) error {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	if req.Level == "" && req.Name == "" {
+		return errors.New("level is required to change the global level")
+	}
+
+	var level *zapcore.Level
+	if req.Level != "" {
+		level = new(zapcore.Level)
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			return fmt.Errorf("invalid level %q: %w", req.Level, err)
+		}
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid duration %q", req.Duration)
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// When there's already a pending revert, keep its original level so
+	// consecutive changes are all reverted back to where they started.
+	original := currentLevel(req.Name)
+	if pending, ok := h.reverts[req.Name]; ok {
+		pending.timer.Stop()
+		original = pending.original
+		delete(h.reverts, req.Name)
+	}
+
+	setLevel(req.Name, level)
+	log.Infow(
+		"Log level changed via admin endpoint",
+		"name", req.Name,
+		"level", req.Level,
+		"duration", req.Duration,
+	)
+
+	if duration > 0 {
+		pending := &pendingRevert{
+			at:       time.Now().Add(duration),
+			original: original,
+		}
+		pending.timer = time.AfterFunc(duration, func() {
+			h.revert(req.Name, pending)
+		})
+		h.reverts[req.Name] = pending
+	}
+	return nil
+}
+
+func (h *logLevelHandler) revert(name string, pending *pendingRevert) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.reverts[name] != pending {
+		// Superseded by another change.
+		return
+	}
+	delete(h.reverts, name)
+	setLevel(name, pending.original)
+	log.Infow("Log level reverted", "name", name)
+}
+
+func (h *logLevelHandler) current() LogLevelResponse {
+	resp := LogLevelResponse{
+		Level: log.GlobalLevel().Level().String(),
+	}
+	if named := log.NamedLevels(); len(named) > 0 {
+		resp.Named = make(map[string]string, len(named))
+		for name, level := range named {
+			resp.Named[name] = level.String()
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if len(h.reverts) > 0 {
+		resp.RevertAt = make(map[string]time.Time, len(h.reverts))
+		for name, pending := range h.reverts {
+			resp.RevertAt[name] = pending.at
+		}
+	}
+	return resp
+}
+
+// currentLevel returns the level of the logger with name,
+// with empty name for the global level.
+//
+// It returns nil when the named logger has no override.
+func currentLevel(name string) *zapcore.Level {
+	if name == "" {
+		level := log.GlobalLevel().Level()
+		return &level
+	}
+	if level, ok := log.NamedLevels()[name]; ok {
+		return &level
+	}
+	return nil
+}
+
+// setLevel sets the level of the logger with name,
+// with empty name for the global level.
+//
+// A nil level removes the override of the named logger.
+func setLevel(name string, level *zapcore.Level) {
+	switch {
+	case name == "":
+		log.GlobalLevel().SetLevel(*level)
+	case level == nil:
+		log.ResetNamedLevel(name)
+	default:
+		log.SetNamedLevel(name, *level)
+	}
+}
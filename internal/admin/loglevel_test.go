@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+func doLogLevel(t *testing.T, h http.Handler, method, body string) (int, LogLevelResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, LogLevelPath, strings.NewReader(body)))
+	var resp LogLevelResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	log.InitLoggerJSON(log.InfoLevel)
+	t.Cleanup(func() {
+		log.InitLogger(log.InfoLevel)
+		log.ResetNamedLevel("foo")
+	})
+	h := newLogLevelHandler()
+
+	if code, resp := doLogLevel(t, h, http.MethodGet, ""); code != http.StatusOK || resp.Level != "info" {
+		t.Errorf("GET got %d %+v, want info", code, resp)
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"level":"foo"}`,
+		`{"level":""}`,
+		`{}`,
+		`{"level":"debug","duration":"foo"}`,
+		`{"level":"debug","duration":"-1s"}`,
+	} {
+		if code, _ := doLogLevel(t, h, http.MethodPut, body); code != http.StatusBadRequest {
+			t.Errorf("PUT %s got %d, want %d", body, code, http.StatusBadRequest)
+		}
+	}
+	if code, _ := doLogLevel(t, h, http.MethodPost, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST got %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+
+	t.Run("global-revert", func(t *testing.T) {
+		_, resp := doLogLevel(t, h, http.MethodPut, `{"level":"warn","duration":"1h"}`)
+		if resp.Level != "warn" {
+			t.Errorf("Expected level warn, got %q", resp.Level)
+		}
+		_, resp = doLogLevel(t, h, http.MethodPut, `{"level":"debug","duration":"10ms"}`)
+		if resp.Level != "debug" {
+			t.Errorf("Expected level debug, got %q", resp.Level)
+		}
+		if _, ok := resp.RevertAt[""]; !ok {
+			t.Errorf("Expected pending revert, got %+v", resp.RevertAt)
+		}
+		waitForLevel(t, func() bool {
+			return log.GlobalLevel().Level() == zapcore.InfoLevel
+		})
+		if _, resp := doLogLevel(t, h, http.MethodGet, ""); len(resp.RevertAt) != 0 {
+			t.Errorf("Expected no pending revert, got %+v", resp.RevertAt)
+		}
+	})
+
+	t.Run("named", func(t *testing.T) {
+		_, resp := doLogLevel(t, h, http.MethodPut, `{"level":"debug","name":"foo","duration":"10ms"}`)
+		if resp.Named["foo"] != "debug" {
+			t.Errorf("Expected foo=debug, got %+v", resp.Named)
+		}
+		waitForLevel(t, func() bool {
+			_, ok := log.NamedLevels()["foo"]
+			return !ok
+		})
+
+		doLogLevel(t, h, http.MethodPut, `{"level":"error","name":"foo"}`)
+		_, resp = doLogLevel(t, h, http.MethodPut, `{"name":"foo"}`)
+		if len(resp.Named) != 0 {
+			t.Errorf("Expected override to be removed, got %+v", resp.Named)
+		}
+	})
+}
+
+func waitForLevel(t *testing.T, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the level to be reverted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
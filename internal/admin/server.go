@@ -23,6 +23,7 @@ const (
 //
 //	metrics       - serve /metrics for prometheus
 //	profiling     - serve /debug/pprof for profiling, ref: https://pkg.go.dev/net/http/pprof
+//	log level     - serve /loglevel to get (GET) and change (PUT) the log levels at runtime
 var Mux = http.NewServeMux()
 
 var baseplateGoCollectors = collectors.WithGoCollectorRuntimeMetrics(
@@ -40,6 +41,8 @@ func init() {
 
 	Mux.Handle("/metrics", promhttp.Handler())
 
+	Mux.Handle(LogLevelPath, newLogLevelHandler())
+
 	// Unregister the default GoCollector, and reregister with baseplate defaults
 	if prometheus.Unregister(collectors.NewGoCollector()) {
 		// Only register a new collector if we unregistered one to avoid double-reregistration
@@ -0,0 +1,200 @@
+package kafkabp
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// keyedWorkerQueueSize is the size of the queue of each worker in
+// keyedWorkers.
+const keyedWorkerQueueSize = 16
+
+// consumeMessages handles all the messages from messages with handle,
+// until messages is closed or handle returns an error.
+//
+// When concurrency <= 1 the messages are handled sequentially, otherwise they
+// are handled by concurrency workers, with messages of the same key handled by
+// the same worker in order.
+//
+// If received is non-nil, it's called with every message in the order of the
+// messages, before the message is handled.
+//
+// If mark is non-nil, it's called after the message and all the messages
+// before it are handled successfully, in the order of the messages and never
+// concurrently.
+// Some of the messages might be skipped, as marking a message implies all the
+// messages before it are marked.
+//
+// When handle returns an error, neither the message nor any message after it
+// is marked, the rest of the messages are not handled (except the ones
+// already being handled by other workers), and the first error is returned
+// without waiting for more messages.
+func consumeMessages(
+	messages <-chan *sarama.ConsumerMessage,
+	concurrency int,
+	received func(*sarama.ConsumerMessage),
+	handle func(*sarama.ConsumerMessage) error,
+	mark func(*sarama.ConsumerMessage),
+) error {
+	if concurrency <= 1 {
+		for m := range messages {
+			if received != nil {
+				received(m)
+			}
+			if err := handle(m); err != nil {
+				return err
+			}
+			if mark != nil {
+				mark(m)
+			}
+		}
+		return nil
+	}
+
+	var (
+		tracker  offsetTracker
+		errOnce  sync.Once
+		firstErr error
+		// failed is closed after firstErr is set.
+		failed = make(chan struct{})
+	)
+	workers := newKeyedWorkers(concurrency, func(tm *trackedMessage) {
+		select {
+		case <-failed:
+			return
+		default:
+		}
+		if err := handle(tm.msg); err != nil {
+			// tm is never done, so neither it nor any message after it is marked.
+			errOnce.Do(func() {
+				firstErr = err
+				close(failed)
+			})
+			return
+		}
+		tracker.done(tm, mark)
+	})
+	// Stop as soon as a worker failed, instead of waiting for the next message,
+	// which might never come.
+loop:
+	for {
+		select {
+		case <-failed:
+			break loop
+		case m, ok := <-messages:
+			if !ok {
+				break loop
+			}
+			if received != nil {
+				received(m)
+			}
+			workers.dispatch(tracker.add(m))
+		}
+	}
+	// firstErr is only written by the workers, which all exited after close.
+	workers.close()
+	return firstErr
+}
+
+// trackedMessage is a message tracked by offsetTracker.
+type trackedMessage struct {
+	msg  *sarama.ConsumerMessage
+	done bool
+}
+
+// offsetTracker tracks the messages of a partition being handled concurrently.
+type offsetTracker struct {
+	lock sync.Mutex
+	// The messages not yet handled, or handled but with earlier messages not
+	// yet handled, in the order they are added.
+	pending []*trackedMessage
+}
+
+// add starts tracking msg.
+func (t *offsetTracker) add(msg *sarama.ConsumerMessage) *trackedMessage {
+	tm := &trackedMessage{msg: msg}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pending = append(t.pending, tm)
+	return tm
+}
+
+// done marks tm as handled.
+//
+// It returns the latest message that itself and all the messages before it are
+// handled, or nil if there's no new such message.
+// If mark is non-nil, it's called with the returned message when it's non-nil,
+// while holding the lock, so the marks are in order.
+func (t *offsetTracker) done(tm *trackedMessage, mark func(*sarama.ConsumerMessage)) *sarama.ConsumerMessage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	tm.done = true
+
+	var latest *sarama.ConsumerMessage
+	i := 0
+	for ; i < len(t.pending) && t.pending[i].done; i++ {
+		latest = t.pending[i].msg
+	}
+	if i > 0 {
+		// Avoid holding the handled messages in the underlying array.
+		clear(t.pending[:i])
+		t.pending = t.pending[i:]
+	}
+	if latest != nil && mark != nil {
+		mark(latest)
+	}
+	return latest
+}
+
+// keyedWorkers handles messages with a fixed number of workers.
+//
+// Messages with the same key are always dispatched to the same worker,
+// so they are handled in order.
+// Messages without key are spread by their offsets.
+type keyedWorkers struct {
+	queues []chan *trackedMessage
+	wg     sync.WaitGroup
+}
+
+func newKeyedWorkers(n int, handle func(*trackedMessage)) *keyedWorkers {
+	w := &keyedWorkers{
+		queues: make([]chan *trackedMessage, n),
+	}
+	w.wg.Add(n)
+	for i := range w.queues {
+		queue := make(chan *trackedMessage, keyedWorkerQueueSize)
+		w.queues[i] = queue
+		go func() {
+			defer w.wg.Done()
+			for tm := range queue {
+				handle(tm)
+			}
+		}()
+	}
+	return w
+}
+
+// dispatch queues tm to its worker, it blocks when the queue of the worker is
+// full.
+func (w *keyedWorkers) dispatch(tm *trackedMessage) {
+	var index uint64
+	if tm.msg.Key == nil {
+		index = uint64(tm.msg.Offset)
+	} else {
+		h := fnv.New32a()
+		h.Write(tm.msg.Key)
+		index = uint64(h.Sum32())
+	}
+	w.queues[index%uint64(len(w.queues))] <- tm
+}
+
+// close waits for all the dispatched messages to be handled and stops the
+// workers.
+func (w *keyedWorkers) close() {
+	for _, queue := range w.queues {
+		close(queue)
+	}
+	w.wg.Wait()
+}
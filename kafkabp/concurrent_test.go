@@ -0,0 +1,245 @@
+package kafkabp
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	var tracker offsetTracker
+	tms := make([]*trackedMessage, 4)
+	for i := range tms {
+		tms[i] = tracker.add(&sarama.ConsumerMessage{Offset: int64(i)})
+	}
+
+	for _, c := range []struct {
+		done     int
+		expected int64 // -1 means nil
+	}{
+		{done: 1, expected: -1},
+		{done: 2, expected: -1},
+		{done: 0, expected: 2},
+		{done: 3, expected: 3},
+	} {
+		latest := tracker.done(tms[c.done], nil)
+		switch {
+		case c.expected < 0 && latest != nil:
+			t.Errorf("done(%d): expected nil, got offset %d", c.done, latest.Offset)
+		case c.expected >= 0 && latest == nil:
+			t.Errorf("done(%d): expected offset %d, got nil", c.done, c.expected)
+		case c.expected >= 0 && latest.Offset != c.expected:
+			t.Errorf("done(%d): expected offset %d, got %d", c.done, c.expected, latest.Offset)
+		}
+	}
+	if len(tracker.pending) != 0 {
+		t.Errorf("expected no pending messages, got %d", len(tracker.pending))
+	}
+}
+
+// keysOnDifferentWorkers returns two keys dispatched to different workers.
+func keysOnDifferentWorkers(n int) (string, string) {
+	index := func(key string) uint32 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return h.Sum32() % uint32(n)
+	}
+	first := "key0"
+	for i := 1; ; i++ {
+		key := "key" + strconv.Itoa(i)
+		if index(key) != index(first) {
+			return first, key
+		}
+	}
+}
+
+func TestConsumeMessages_Concurrent(t *testing.T) {
+	const (
+		concurrency = 4
+		// Less than keyedWorkerQueueSize, so the dispatching is not blocked by the
+		// queue of the slow key.
+		perKey = 10
+	)
+	slow, fast := keysOnDifferentWorkers(concurrency)
+
+	messages := make(chan *sarama.ConsumerMessage, 2*perKey)
+	var offset int64
+	for i := 0; i < perKey; i++ {
+		for _, key := range []string{slow, fast} {
+			messages <- &sarama.ConsumerMessage{
+				Key:    []byte(key),
+				Value:  []byte(strconv.Itoa(i)),
+				Offset: offset,
+			}
+			offset++
+		}
+	}
+	close(messages)
+
+	// The first message of the slow key is blocked until all the messages of
+	// the fast key are handled, which would deadlock if they were handled
+	// sequentially.
+	fastDone := make(chan struct{})
+	var lock sync.Mutex
+	handled := make(map[string][]string)
+	var marked []int64
+	consumeMessages(
+		messages,
+		concurrency,
+		nil, // received
+		func(m *sarama.ConsumerMessage) error {
+			key := string(m.Key)
+			if key == slow && string(m.Value) == "0" {
+				select {
+				case <-fastDone:
+				case <-time.After(5 * time.Second):
+					t.Error("messages were not handled concurrently")
+				}
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			handled[key] = append(handled[key], string(m.Value))
+			if key == fast && len(handled[fast]) == perKey {
+				close(fastDone)
+			}
+			return nil
+		},
+		func(m *sarama.ConsumerMessage) {
+			lock.Lock()
+			defer lock.Unlock()
+			marked = append(marked, m.Offset)
+		},
+	)
+
+	for _, key := range []string{slow, fast} {
+		if len(handled[key]) != perKey {
+			t.Fatalf("expected %d messages handled for %q, got %v", perKey, key, handled[key])
+		}
+		for i, v := range handled[key] {
+			if v != strconv.Itoa(i) {
+				t.Errorf("messages of %q handled out of order: %v", key, handled[key])
+				break
+			}
+		}
+	}
+
+	// The fast key messages are interleaved with the slow key messages, so none
+	// of them can be marked before the first slow message is handled.
+	if len(marked) == 0 {
+		t.Fatal("expected messages marked")
+	}
+	if marked[0] < 1 {
+		t.Errorf("expected the first mark to be after the blocked message, got %d", marked[0])
+	}
+	for i := 1; i < len(marked); i++ {
+		if marked[i] <= marked[i-1] {
+			t.Errorf("marks not in order: %v", marked)
+			break
+		}
+	}
+	if last := marked[len(marked)-1]; last != offset-1 {
+		t.Errorf("expected the last mark to be %d, got %d", offset-1, last)
+	}
+}
+
+func TestConsumeMessages_Sequential(t *testing.T) {
+	messages := make(chan *sarama.ConsumerMessage, 3)
+	for i := int64(0); i < 3; i++ {
+		messages <- &sarama.ConsumerMessage{Offset: i}
+	}
+	close(messages)
+
+	var handled, marked []int64
+	consumeMessages(
+		messages,
+		0,   // concurrency
+		nil, // received
+		func(m *sarama.ConsumerMessage) error {
+			handled = append(handled, m.Offset)
+			return nil
+		},
+		func(m *sarama.ConsumerMessage) {
+			marked = append(marked, m.Offset)
+		},
+	)
+	if len(handled) != 3 || len(marked) != 3 {
+		t.Errorf("expected 3 messages handled and marked, got %v and %v", handled, marked)
+	}
+}
+
+func TestConsumeMessages_Error(t *testing.T) {
+	const failedOffset = 2
+	handleErr := errors.New("handle error")
+	for _, concurrency := range []int{1, 4} {
+		t.Run(strconv.Itoa(concurrency), func(t *testing.T) {
+			messages := make(chan *sarama.ConsumerMessage, 5)
+			for i := int64(0); i < 5; i++ {
+				messages <- &sarama.ConsumerMessage{Offset: i}
+			}
+			close(messages)
+
+			var lock sync.Mutex
+			var marked []int64
+			err := consumeMessages(
+				messages,
+				concurrency,
+				nil, // received
+				func(m *sarama.ConsumerMessage) error {
+					if m.Offset == failedOffset {
+						return handleErr
+					}
+					return nil
+				},
+				func(m *sarama.ConsumerMessage) {
+					lock.Lock()
+					defer lock.Unlock()
+					marked = append(marked, m.Offset)
+				},
+			)
+			if !errors.Is(err, handleErr) {
+				t.Errorf("expected error %v, got %v", handleErr, err)
+			}
+			for _, offset := range marked {
+				if offset >= failedOffset {
+					t.Errorf("expected no message marked at or after offset %d, got %v", failedOffset, marked)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestConsumeMessages_ErrorWithoutMoreMessages(t *testing.T) {
+	handleErr := errors.New("handle error")
+	// The channel is never closed and no more messages come after the failed
+	// one, like a quiet partition.
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Offset: 0}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- consumeMessages(
+			messages,
+			4,   // concurrency
+			nil, // received
+			func(*sarama.ConsumerMessage) error {
+				return handleErr
+			},
+			nil, // mark
+		)
+	}()
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, handleErr) {
+			t.Errorf("expected error %v, got %v", handleErr, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumeMessages did not return after the handle error")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/Shopify/sarama"
+	"github.com/avast/retry-go"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/reddit/baseplate.go/log"
@@ -16,6 +17,22 @@ const (
 	OffsetNewest = "newest"
 )
 
+// Allowed RequiredAcks values
+const (
+	AcksAll   = "all"
+	AcksLocal = "local"
+	AcksNone  = "none"
+)
+
+// Allowed Compression values
+const (
+	CompressionNone   = "none"
+	CompressionGZIP   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionLZ4    = "lz4"
+	CompressionZSTD   = "zstd"
+)
+
 var (
 	// ErrBrokersEmpty is thrown when the slice of brokers is empty.
 	ErrBrokersEmpty = errors.New("kafkabp: Brokers are empty")
@@ -32,6 +49,13 @@ var (
 	// ErrNilConsumePartitionFunc is thrown when ConsumePartitionFuncProvider
 	// returns a nil ConsumePartitionFunc.
 	ErrNilConsumePartitionFunc = errors.New("kafkabp: ConsumePartitionFunc is nil")
+
+	// ErrRequiredAcksInvalid is thrown when an invalid RequiredAcks is
+	// specified.
+	ErrRequiredAcksInvalid = errors.New("kafkabp: RequiredAcks is invalid")
+
+	// ErrCompressionInvalid is thrown when an invalid Compression is specified.
+	ErrCompressionInvalid = errors.New("kafkabp: Compression is invalid")
 )
 
 // ConsumerConfig can be used to configure a kafkabp Consumer.
@@ -121,6 +145,46 @@ type ConsumerConfig struct {
 	// or it might make things worse.
 	// You are advised to test before using non-empty rack id in production.
 	RackID RackIDFunc `yaml:"rackID"`
+
+	// Optional. TLS configures TLS connections to the brokers.
+	TLS TLSConfig `yaml:"tls"`
+
+	// Optional. SASL configures SASL authentication with the brokers.
+	SASL SASLConfig `yaml:"sasl"`
+
+	// Optional. The retry policy applied to every message when the
+	// ConsumeMessageErrorFunc passed to ConsumeWithError returns error.
+	//
+	// Defaults to no retries.
+	// The ConsumeMessageErrorFunc can return retrybp.Unrecoverable errors to
+	// skip the retries of a message.
+	RetryOptions []retry.Option `yaml:"-"`
+
+	// Optional. The dead-letter topic the messages are sent to when the
+	// ConsumeMessageErrorFunc passed to ConsumeWithError still returns error
+	// after retries.
+	//
+	// Sending to the dead-letter topic is retried with RetryOptions as well.
+	// If it still fails, the error is passed to the ConsumeErrorFunc.
+	// When GroupID is non-empty, the send is then retried with backoff until it
+	// succeeds, without marking the message or any message after it in the same
+	// partition, so the message is never lost. If the partition is reassigned
+	// in the meantime, the message is redelivered to its new consumer.
+	DeadLetter DeadLetterConfig `yaml:"deadLetter"`
+
+	// Optional. The number of workers handling the messages of each partition
+	// concurrently. Defaults to 1, which handles the messages sequentially.
+	//
+	// Messages with the same key are always handled by the same worker,
+	// so the order of the messages with the same key is preserved,
+	// but there's no ordering guarantee among different keys.
+	//
+	// When GroupID is non-empty, a message is only marked as consumed after it
+	// and all the messages before it in the same partition are handled.
+	//
+	// When it's > 1, the message handling function passed to Consume or
+	// ConsumeWithError must be safe for concurrent use.
+	Concurrency int `yaml:"concurrency"`
 }
 
 // Since not all sarama's default config are zero values,
@@ -143,16 +207,9 @@ func (cfg *ConsumerConfig) NewSaramaConfig() (*sarama.Config, error) {
 		return nil, ErrClientIDEmpty
 	}
 
-	version := defaultSaramaConfig.Version
-	if cfg.Version != "" {
-		var err error
-		version, err = sarama.ParseKafkaVersion(cfg.Version)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"kafkabp: ParseKafkaVersion error: %w",
-				err,
-			)
-		}
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return nil, err
 	}
 	kafkaVersionGauge.With(prometheus.Labels{
 		"kafka_version": version.String(),
@@ -170,6 +227,13 @@ func (cfg *ConsumerConfig) NewSaramaConfig() (*sarama.Config, error) {
 		c.RackID = cfg.RackID()
 	}
 
+	if err := cfg.TLS.apply(c); err != nil {
+		return nil, err
+	}
+	if err := cfg.SASL.apply(c); err != nil {
+		return nil, err
+	}
+
 	if cfg.GroupID == "" {
 		var offset int64
 		switch cfg.Offset {
@@ -187,3 +251,150 @@ func (cfg *ConsumerConfig) NewSaramaConfig() (*sarama.Config, error) {
 
 	return c, nil
 }
+
+// ProducerConfig can be used to configure a kafkabp Producer.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	kafka:
+//	  brokers:
+//	    - 127.0.0.1:9090
+//	    - 127.0.0.2:9090
+//	  topic: sample-topic
+//	  clientID: myclient
+//	  version: 2.4.0
+//	  requiredAcks: all
+//	  compression: snappy
+type ProducerConfig struct {
+	// Required. Brokers specifies a slice of broker addresses.
+	Brokers []string `yaml:"brokers"`
+
+	// Optional. Topic is the default topic to produce messages to,
+	// used when the Topic of the message is empty.
+	Topic string `yaml:"topic"`
+
+	// Required. ClientID is used by Kafka broker to identify the client.
+	//
+	// In most cases, every instance is expected to have a unique ClientID.
+	// The Kubernetes pod ID is usually a good candidate for this unique ID.
+	ClientID string `yaml:"clientID"`
+
+	// Optional. The version of the kafka broker this producer is connected to.
+	// In format of "0.10.2.0" or "2.4.0".
+	//
+	// When omitted, Sarama library would pick the oldest supported version in
+	// order to maintain maximum backward compatibility, but some of the newer
+	// features (e.g. message headers, which requires at least "0.11.0.0") might
+	// be unavailable.
+	Version string `yaml:"version"`
+
+	// Optional. The acknowledgement required from the brokers for a message to
+	// be considered sent. Valid values are "all", "local" and "none".
+	//
+	// Defaults to "local", which waits for the leader of the partition only.
+	RequiredAcks string `yaml:"requiredAcks"`
+
+	// Optional. The compression of the messages. Valid values are "none",
+	// "gzip", "snappy", "lz4" and "zstd".
+	//
+	// Defaults to "none".
+	Compression string `yaml:"compression"`
+
+	// Optional. If non-nil, will be used to log the errors of the messages sent
+	// via SendMessageAsync.
+	Logger log.Wrapper `yaml:"logger"`
+
+	// Optional. The function to set rack id for this kafka client.
+	// See ConsumerConfig.RackID for more details.
+	RackID RackIDFunc `yaml:"rackID"`
+
+	// Optional. TLS configures TLS connections to the brokers.
+	TLS TLSConfig `yaml:"tls"`
+
+	// Optional. SASL configures SASL authentication with the brokers.
+	SASL SASLConfig `yaml:"sasl"`
+}
+
+// NewSaramaConfig instantiates a sarama.Config with sane producer defaults
+// from sarama.NewConfig(), overwritten by values parsed from cfg.
+func (cfg *ProducerConfig) NewSaramaConfig() (*sarama.Config, error) {
+	// Validate input parameters.
+	if len(cfg.Brokers) == 0 {
+		return nil, ErrBrokersEmpty
+	}
+
+	if cfg.ClientID == "" {
+		return nil, ErrClientIDEmpty
+	}
+
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	c := sarama.NewConfig()
+
+	// Both are required by Producer to track the results of the messages.
+	c.Producer.Return.Successes = true
+	c.Producer.Return.Errors = true
+
+	c.ClientID = cfg.ClientID
+	c.Version = version
+
+	switch cfg.RequiredAcks {
+	default:
+		return nil, ErrRequiredAcksInvalid
+	case "", AcksLocal:
+		c.Producer.RequiredAcks = sarama.WaitForLocal
+	case AcksAll:
+		c.Producer.RequiredAcks = sarama.WaitForAll
+	case AcksNone:
+		c.Producer.RequiredAcks = sarama.NoResponse
+	}
+
+	switch cfg.Compression {
+	default:
+		return nil, ErrCompressionInvalid
+	case "", CompressionNone:
+		c.Producer.Compression = sarama.CompressionNone
+	case CompressionGZIP:
+		c.Producer.Compression = sarama.CompressionGZIP
+	case CompressionSnappy:
+		c.Producer.Compression = sarama.CompressionSnappy
+	case CompressionLZ4:
+		c.Producer.Compression = sarama.CompressionLZ4
+	case CompressionZSTD:
+		c.Producer.Compression = sarama.CompressionZSTD
+	}
+
+	if cfg.RackID != nil {
+		c.RackID = cfg.RackID()
+	}
+
+	if err := cfg.TLS.apply(c); err != nil {
+		return nil, err
+	}
+	if err := cfg.SASL.apply(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parseVersion parses the kafka version from config,
+// empty version means sarama's default version.
+func parseVersion(v string) (sarama.KafkaVersion, error) {
+	if v == "" {
+		return defaultSaramaConfig.Version, nil
+	}
+	version, err := sarama.ParseKafkaVersion(v)
+	if err != nil {
+		return version, fmt.Errorf(
+			"kafkabp: ParseKafkaVersion error: %w",
+			err,
+		)
+	}
+	return version, nil
+}
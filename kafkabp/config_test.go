@@ -4,6 +4,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/Shopify/sarama"
+
 	"github.com/reddit/baseplate.go/kafkabp"
 )
 
@@ -109,3 +111,183 @@ func TestConsumerConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestProducerConfig(t *testing.T) {
+	var cfg kafkabp.ProducerConfig
+
+	t.Run("no-brokers", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrBrokersEmpty) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrBrokersEmpty, err)
+		}
+	})
+	cfg.Brokers = []string{"127.0.0.1:9090", "127.0.0.2:9090"}
+
+	t.Run("no-client-id", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrClientIDEmpty) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrClientIDEmpty, err)
+		}
+	})
+	cfg.ClientID = "i am unique"
+
+	t.Run("invalid-acks", func(t *testing.T) {
+		cfg.RequiredAcks = "some"
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrRequiredAcksInvalid) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrRequiredAcksInvalid, err)
+		}
+	})
+	cfg.RequiredAcks = kafkabp.AcksAll
+
+	t.Run("invalid-compression", func(t *testing.T) {
+		cfg.Compression = "zip"
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrCompressionInvalid) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrCompressionInvalid, err)
+		}
+	})
+	cfg.Compression = kafkabp.CompressionSnappy
+
+	t.Run("valid-config", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if sc == nil {
+			t.Fatal("expected config to be non-nil, got nil")
+		}
+		if sc.ClientID != cfg.ClientID {
+			t.Errorf("expected sarama client id to be %q, got %q", cfg.ClientID, sc.ClientID)
+		}
+		if sc.Producer.RequiredAcks != sarama.WaitForAll {
+			t.Errorf("expected required acks to be %v, got %v", sarama.WaitForAll, sc.Producer.RequiredAcks)
+		}
+		if sc.Producer.Compression != sarama.CompressionSnappy {
+			t.Errorf("expected compression to be %v, got %v", sarama.CompressionSnappy, sc.Producer.Compression)
+		}
+		if !sc.Producer.Return.Successes || !sc.Producer.Return.Errors {
+			t.Error("expected producer to return both successes and errors")
+		}
+	})
+}
+
+func TestSecurityConfig(t *testing.T) {
+	cfg := kafkabp.ConsumerConfig{
+		Brokers:  []string{"127.0.0.1:9090"},
+		Topic:    "test-topic",
+		ClientID: "i am unique",
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sc.Net.TLS.Enable {
+			t.Error("expected TLS to be disabled")
+		}
+		if sc.Net.SASL.Enable {
+			t.Error("expected SASL to be disabled")
+		}
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		cfg := cfg
+		cfg.TLS = kafkabp.TLSConfig{
+			Enabled:            true,
+			InsecureSkipVerify: true,
+		}
+		sc, err := cfg.NewSaramaConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !sc.Net.TLS.Enable {
+			t.Error("expected TLS to be enabled")
+		}
+		if sc.Net.TLS.Config == nil || !sc.Net.TLS.Config.InsecureSkipVerify {
+			t.Errorf("expected InsecureSkipVerify TLS config, got %#v", sc.Net.TLS.Config)
+		}
+	})
+
+	t.Run("tls-cert-without-key", func(t *testing.T) {
+		cfg := cfg
+		cfg.TLS = kafkabp.TLSConfig{
+			Enabled:  true,
+			CertFile: "cert.pem",
+		}
+		_, err := cfg.NewSaramaConfig()
+		if !errors.Is(err, kafkabp.ErrTLSCertKeyMismatch) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrTLSCertKeyMismatch, err)
+		}
+	})
+
+	t.Run("tls-missing-ca", func(t *testing.T) {
+		cfg := cfg
+		cfg.TLS = kafkabp.TLSConfig{
+			Enabled: true,
+			CAFile:  "/path/does/not/exist.pem",
+		}
+		_, err := cfg.NewSaramaConfig()
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("sasl-plain", func(t *testing.T) {
+		cfg := cfg
+		cfg.SASL = kafkabp.SASLConfig{
+			Username: "user",
+			Password: "pass",
+		}
+		sc, err := cfg.NewSaramaConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !sc.Net.SASL.Enable {
+			t.Error("expected SASL to be enabled")
+		}
+		if sc.Net.SASL.Mechanism != sarama.SASLTypePlaintext {
+			t.Errorf("expected mechanism %q, got %q", sarama.SASLTypePlaintext, sc.Net.SASL.Mechanism)
+		}
+		if sc.Net.SASL.User != "user" || sc.Net.SASL.Password != "pass" {
+			t.Errorf("unexpected SASL credentials %q/%q", sc.Net.SASL.User, sc.Net.SASL.Password)
+		}
+	})
+
+	t.Run("sasl-scram-without-client", func(t *testing.T) {
+		cfg := cfg
+		cfg.SASL = kafkabp.SASLConfig{
+			Mechanism: sarama.SASLTypeSCRAMSHA512,
+			Username:  "user",
+		}
+		_, err := cfg.NewSaramaConfig()
+		if !errors.Is(err, kafkabp.ErrSASLSCRAMClientNil) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrSASLSCRAMClientNil, err)
+		}
+	})
+
+	t.Run("sasl-invalid-mechanism", func(t *testing.T) {
+		cfg := cfg
+		cfg.SASL = kafkabp.SASLConfig{
+			Mechanism: "GSSAPI",
+			Username:  "user",
+		}
+		_, err := cfg.NewSaramaConfig()
+		if !errors.Is(err, kafkabp.ErrSASLMechanismInvalid) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrSASLMechanismInvalid, err)
+		}
+	})
+}
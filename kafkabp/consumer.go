@@ -2,25 +2,38 @@ package kafkabp
 
 import (
 	"context"
+	"errors"
 	"io"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/prometheus/client_golang/prometheus"
-
-	"github.com/reddit/baseplate.go/tracing"
 )
 
 // ConsumeMessageFunc is a function type for consuming consumer messages.
 //
+// The ctx passed in has a server span continuing the trace from the headers of
+// msg (see StartSpanFromMessage), which is finished after the function returns.
+//
 // The implementation is expected to handle all consuming errors.
 // For example, if there was anything wrong with handling the message and it
 // needs to be retried, the ConsumeMessageFunc implementation should handle the
 // retry (usually put the message into a retry topic).
 type ConsumeMessageFunc func(ctx context.Context, msg *sarama.ConsumerMessage)
 
+// ConsumeMessageErrorFunc is a function type for consuming consumer messages
+// that returns the error of handling the message.
+//
+// It's used by ErrorConsumer.ConsumeWithError. When it returns an error, the message
+// is retried with ConsumerConfig.RetryOptions. If it still fails after
+// retries, the message is sent to ConsumerConfig.DeadLetter when configured,
+// otherwise the error is passed to the ConsumeErrorFunc.
+//
+// Like ConsumeMessageFunc, the ctx passed in has a server span continuing the
+// trace from the headers of msg, which is finished with the final error.
+type ConsumeMessageErrorFunc func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
 // ConsumeErrorFunc is a function type for consuming consumer errors.
 //
 // Note that these are usually system level consuming errors (e.g. read from
@@ -79,9 +92,37 @@ type Consumer interface {
 	Consume(ConsumeMessageFunc, ConsumeErrorFunc) error
 
 	// IsHealthy returns false after Consume returns.
+	//
+	// See NewLagHealthChecker for a variant also checking the lag.
 	IsHealthy(ctx context.Context) bool
 }
 
+// ErrorConsumer is a Consumer that can also handle the messages with a
+// ConsumeMessageErrorFunc.
+//
+// The Consumers created by NewConsumer all implement it, for example:
+//
+//	consumer, err := kafkabp.NewConsumer(cfg)
+//	if err != nil {
+//	  // handle err
+//	}
+//	err = consumer.(kafkabp.ErrorConsumer).ConsumeWithError(
+//	  consumeMessageErrorFunc,
+//	  consumeErrorFunc,
+//	)
+type ErrorConsumer interface {
+	Consumer
+
+	// ConsumeWithError is the same as Consume, except that the messages are
+	// handled by a ConsumeMessageErrorFunc, with retries and dead-letter.
+	ConsumeWithError(ConsumeMessageErrorFunc, ConsumeErrorFunc) error
+}
+
+var (
+	_ ErrorConsumer = (*consumer)(nil)
+	_ ErrorConsumer = (*groupConsumer)(nil)
+)
+
 // consumer implements a Kafka consumer.
 type consumer struct {
 	cfg ConsumerConfig
@@ -97,6 +138,12 @@ type consumer struct {
 
 	offset int64
 
+	// deadLetterProducer is the dead-letter producer created by the consumer,
+	// closed in Close.
+	deadLetterProducer Producer
+
+	lags *partitionLags
+
 	wg sync.WaitGroup
 }
 
@@ -118,7 +165,7 @@ func NewConsumer(cfg ConsumerConfig) (Consumer, error) {
 }
 
 // SaramaConfigOverrider provides a way for users to override certain fields in
-// *sarama.Config generated from ConsumerConfig.
+// *sarama.Config generated from ConsumerConfig or ProducerConfig.
 type SaramaConfigOverrider func(*sarama.Config)
 
 // NewConsumerWithConfigOverriders is provided as an escape hatch for use cases
@@ -133,15 +180,28 @@ func NewConsumerWithConfigOverriders(cfg ConsumerConfig, overriders ...SaramaCon
 		override(sc)
 	}
 
+	dlqProducer, err := newDeadLetterProducer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if dlqProducer != nil {
+		cfg.DeadLetter.Producer = dlqProducer
+	}
+
+	var c Consumer
 	switch {
 	default:
-		return newTopicConsumer(cfg, sc)
+		c, err = newTopicConsumer(cfg, sc, dlqProducer)
 	case cfg.GroupID != "":
-		return newGroupConsumer(cfg, sc)
+		c, err = newGroupConsumer(cfg, sc, dlqProducer)
 	}
+	if err != nil && dlqProducer != nil {
+		dlqProducer.Close()
+	}
+	return c, err
 }
 
-func newTopicConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
+func newTopicConsumer(cfg ConsumerConfig, sc *sarama.Config, dlqProducer Producer) (Consumer, error) {
 	if cfg.ConsumePartitionFuncProvider == nil {
 		cfg.ConsumePartitionFuncProvider = ConsumeAllPartitionsFuncProvider
 	}
@@ -151,6 +211,8 @@ func newTopicConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
 		sc:                        sc,
 		offset:                    sc.Consumer.Offsets.Initial,
 		partitionSelectorProvider: cfg.ConsumePartitionFuncProvider,
+		deadLetterProducer:        dlqProducer,
+		lags:                      newPartitionLags(consumerLagDesc, cfg.Topic),
 	}
 
 	// Initialize Sarama consumer and set atomic values.
@@ -235,10 +297,14 @@ func (kc *consumer) Close() error {
 	}
 	// wait for the Consume function to return
 	kc.wg.Wait()
+	var errs []error
 	if c := kc.getConsumer(); c != nil {
-		return c.Close()
+		errs = append(errs, c.Close())
 	}
-	return nil
+	if kc.deadLetterProducer != nil {
+		errs = append(errs, kc.deadLetterProducer.Close())
+	}
+	return errors.Join(errs...)
 }
 
 // Consume consumes Kafka messages and errors from each partition's consumer.
@@ -247,6 +313,15 @@ func (kc *consumer) Close() error {
 func (kc *consumer) Consume(
 	messagesFunc ConsumeMessageFunc,
 	errorsFunc ConsumeErrorFunc,
+) error {
+	return kc.ConsumeWithError(ignoreErrors(messagesFunc), errorsFunc)
+}
+
+// ConsumeWithError is the same as Consume, with the messages handled by
+// messagesFunc with retries and dead-letter.
+func (kc *consumer) ConsumeWithError(
+	messagesFunc ConsumeMessageErrorFunc,
+	errorsFunc ConsumeErrorFunc,
 ) error {
 	defer kc.consumeReturned.Store(1)
 	kc.wg.Add(1)
@@ -256,6 +331,17 @@ func (kc *consumer) Consume(
 	// two cases, where we want different behavior:
 	//   - in case of partition rebalance: restart goroutines
 	//   - in case of call to Close/AsyncClose: exit
+	handler := messageHandler{
+		spanName: "consumer." + kc.cfg.Topic,
+		timer: consumerTimer.With(prometheus.Labels{
+			topicLabel: kc.cfg.Topic,
+		}),
+		callback:     messagesFunc,
+		errorsFunc:   errorsFunc,
+		retryOptions: kc.cfg.RetryOptions,
+		deadLetter:   kc.cfg.DeadLetter,
+	}
+
 	var wg sync.WaitGroup
 	for {
 		// create a partition consumer for each partition
@@ -285,28 +371,27 @@ func (kc *consumer) Consume(
 
 			// consume partition consumer messages
 			wg.Add(1)
-			go func(pc sarama.PartitionConsumer) {
+			// kc.offset is either OffsetNewest or OffsetOldest resolved by sarama,
+			// so the offset of the first message is unknown until it's received.
+			kc.lags.start(p, -1, partitionConsumer.HighWaterMarkOffset)
+			go func(p int32, pc sarama.PartitionConsumer) {
 				defer wg.Done()
-				for m := range pc.Messages() {
-					// Wrap in anonymous function for easier defer.
-					func() {
-						ctx := context.Background()
-						var span *tracing.Span
-						spanName := "consumer." + kc.cfg.Topic
-						ctx, span = tracing.StartTopLevelServerSpan(ctx, spanName)
-						defer func(start time.Time) {
-							consumerTimer.With(prometheus.Labels{
-								topicLabel: kc.cfg.Topic,
-							}).Observe(time.Since(start).Seconds())
-							span.FinishWithOptions(tracing.FinishOptions{
-								Ctx: ctx,
-							}.Convert())
-						}(time.Now())
-
-						messagesFunc(ctx, m)
-					}()
-				}
-			}(partitionConsumer)
+				defer kc.lags.stop(p)
+				consumeMessages(
+					pc.Messages(),
+					kc.cfg.Concurrency,
+					kc.lags.received,
+					func(m *sarama.ConsumerMessage) error {
+						// The topic consumer never commits the offsets, so just report
+						// the error and move on.
+						if err := handler.handle(m); err != nil {
+							errorsFunc(err)
+						}
+						return nil
+					},
+					kc.lags.processed,
+				)
+			}(p, partitionConsumer)
 
 			// consume partition consumer errors
 			wg.Add(1)
@@ -340,3 +425,7 @@ func (kc *consumer) Consume(
 func (kc *consumer) IsHealthy(_ context.Context) bool {
 	return kc.consumeReturned.Load() == 0
 }
+
+func (kc *consumer) maxLag() int64 {
+	return kc.lags.max()
+}
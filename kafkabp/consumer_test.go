@@ -9,8 +9,10 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/Shopify/sarama/mocks"
+	"github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/tracing"
 )
 
 // Make sure that Consumer also implements baseplate.HealthChecker.
@@ -70,6 +72,40 @@ func TestKafkaConsumer_Consume(t *testing.T) {
 	}
 }
 
+func TestKafkaConsumer_ConsumeTraceHeaders(t *testing.T) {
+	kc := getTestMockConsumer(t)
+	pc, _ := setupPartitionConsumers(t, kc)
+
+	_, producerSpan := tracing.StartTopLevelServerSpan(context.Background(), "producer")
+	defer producerSpan.Finish()
+	msg := &sarama.ProducerMessage{Topic: kc.cfg.Topic}
+	SetSpanHeaders(msg, producerSpan)
+	pc.YieldMessage(toConsumerMessage(msg))
+
+	spans := make(chan *tracing.Span, 1)
+	go func() {
+		kc.Consume(
+			func(ctx context.Context, _ *sarama.ConsumerMessage) {
+				span, _ := opentracing.SpanFromContext(ctx).(*tracing.Span)
+				spans <- span
+			},
+			func(error) {},
+		)
+	}()
+	defer kc.Close()
+
+	span := <-spans
+	if span == nil {
+		t.Fatal("expected span in the context")
+	}
+	if span.TraceID() != producerSpan.TraceID() {
+		t.Errorf("trace id expected %q, got %q", producerSpan.TraceID(), span.TraceID())
+	}
+	if span.ParentID() != producerSpan.ID() {
+		t.Errorf("parent id expected %q, got %q", producerSpan.ID(), span.ParentID())
+	}
+}
+
 // This tests that when Close() is called on a KafkaConsumer instance
 // the messages and errors channel for every partition consumer is
 // drained before the parent consumer is closed.
@@ -144,6 +180,7 @@ func getTestMockConsumer(t *testing.T) *consumer {
 		sc:                        sc,
 		offset:                    sc.Consumer.Offsets.Initial,
 		partitionSelectorProvider: ConsumeAllPartitionsFuncProvider,
+		lags:                      newPartitionLags(consumerLagDesc, cfg.Topic),
 	}
 	consumer, partitions := createMockConsumer(t, cfg.Topic)
 	c.consumer.Store(&consumer)
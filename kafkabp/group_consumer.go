@@ -2,21 +2,30 @@ package kafkabp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/avast/retry-go"
 	"github.com/prometheus/client_golang/prometheus"
-
-	"github.com/reddit/baseplate.go/tracing"
 )
 
 type groupConsumer struct {
 	consumer sarama.ConsumerGroup
 	cfg      ConsumerConfig
 
+	// deadLetterProducer is the dead-letter producer created by the consumer,
+	// closed in Close.
+	deadLetterProducer Producer
+
+	lags *partitionLags
+
+	// joined is set after the first session of the consumer,
+	// so the following sessions are counted as rebalances.
+	joined atomic.Bool
+
 	wg sync.WaitGroup
 
 	consumeReturned atomic.Int64
@@ -24,20 +33,29 @@ type groupConsumer struct {
 }
 
 // newGroupConsumer creates a new group Consumer.
-func newGroupConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
+func newGroupConsumer(cfg ConsumerConfig, sc *sarama.Config, dlqProducer Producer) (Consumer, error) {
 	consumer, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID, sc)
 	if err != nil {
 		return nil, err
 	}
 	return &groupConsumer{
-		consumer: consumer,
-		cfg:      cfg,
+		consumer:           consumer,
+		cfg:                cfg,
+		deadLetterProducer: dlqProducer,
+		lags:               newPartitionLags(groupConsumerLagDesc, cfg.Topic),
 	}, nil
 }
 
 func (gc *groupConsumer) Consume(
 	messagesFunc ConsumeMessageFunc,
 	errorsFunc ConsumeErrorFunc,
+) error {
+	return gc.ConsumeWithError(ignoreErrors(messagesFunc), errorsFunc)
+}
+
+func (gc *groupConsumer) ConsumeWithError(
+	messagesFunc ConsumeMessageErrorFunc,
+	errorsFunc ConsumeErrorFunc,
 ) error {
 	defer gc.consumeReturned.Store(1)
 	gc.wg.Add(1)
@@ -52,8 +70,16 @@ func (gc *groupConsumer) Consume(
 	}()
 
 	handler := GroupConsumerHandler{
-		Callback: messagesFunc,
-		Topic:    gc.cfg.Topic,
+		ErrorCallback: messagesFunc,
+		Topic:         gc.cfg.Topic,
+		GroupID:       gc.cfg.GroupID,
+		ErrorFunc:     errorsFunc,
+		RetryOptions:  gc.cfg.RetryOptions,
+		DeadLetter:    gc.cfg.DeadLetter,
+		Concurrency:   gc.cfg.Concurrency,
+
+		lags:   gc.lags,
+		joined: &gc.joined,
 	}
 
 	// gc.consumer.Consume returns when either:
@@ -76,16 +102,24 @@ func (gc *groupConsumer) Consume(
 func (gc *groupConsumer) Close() error {
 	gc.closed.Store(1)
 
+	err := gc.consumer.Close()
 	// wait for the Consume function to return
-	defer gc.wg.Wait()
+	gc.wg.Wait()
 
-	return gc.consumer.Close()
+	if gc.deadLetterProducer != nil {
+		err = errors.Join(err, gc.deadLetterProducer.Close())
+	}
+	return err
 }
 
 func (gc *groupConsumer) IsHealthy(_ context.Context) bool {
 	return gc.consumeReturned.Load() == 0
 }
 
+func (gc *groupConsumer) maxLag() int64 {
+	return gc.lags.max()
+}
+
 // GroupConsumerHandler implements sarama.ConsumerGroupHandler.
 //
 // It's exported so that users of this library can write mocks to test their
@@ -93,45 +127,115 @@ func (gc *groupConsumer) IsHealthy(_ context.Context) bool {
 type GroupConsumerHandler struct {
 	Callback ConsumeMessageFunc
 	Topic    string
+
+	// Optional. The consumer group, used as a label of the metrics.
+	GroupID string
+
+	// Optional. When non-nil, it's used instead of Callback to handle the
+	// messages, with RetryOptions and DeadLetter applied to the errors returned.
+	ErrorCallback ConsumeMessageErrorFunc
+
+	// Optional. The errors of the messages failed after retries and not sent to
+	// DeadLetter, and the errors sending the messages to DeadLetter,
+	// are passed to it.
+	ErrorFunc ConsumeErrorFunc
+
+	// Optional. See ConsumerConfig.RetryOptions and ConsumerConfig.DeadLetter.
+	//
+	// DeadLetter.Producer must be set for DeadLetter to be used.
+	RetryOptions []retry.Option
+	DeadLetter   DeadLetterConfig
+
+	// Optional. See ConsumerConfig.Concurrency.
+	//
+	// A message is only marked after it and all the messages before it in the
+	// same partition are handled.
+	Concurrency int
+
+	// lags tracks the lag of the claims for LagHealthChecker, optional.
+	lags *partitionLags
+
+	// joined is set after the first session, optional.
+	// Rebalances are only reported when it's non-nil.
+	joined *atomic.Bool
+}
+
+func (h GroupConsumerHandler) metricsLabels() prometheus.Labels {
+	return prometheus.Labels{
+		topicLabel: h.Topic,
+		groupLabel: h.GroupID,
+	}
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim.
-func (h GroupConsumerHandler) Setup(sarama.ConsumerGroupSession) error {
-	// Do nothing.
+//
+// It reports the rebalance (every session except the first one of the
+// consumer) and adds the number of partitions assigned to the session.
+func (h GroupConsumerHandler) Setup(session sarama.ConsumerGroupSession) error {
+	if h.joined != nil && h.joined.Swap(true) {
+		groupRebalancesCounter.With(h.metricsLabels()).Inc()
+	}
+	groupAssignedPartitionsGauge.With(h.metricsLabels()).Add(float64(len(session.Claims()[h.Topic])))
 	return nil
 }
 
 // Cleanup is run at the end of a session,
 // once all ConsumeClaim goroutines have exited.
-func (h GroupConsumerHandler) Cleanup(sarama.ConsumerGroupSession) error {
-	// Do nothing.
+//
+// It removes the number of partitions assigned to the session added by Setup,
+// so other consumers of the same topic and group in the same process are not
+// affected.
+func (h GroupConsumerHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	groupAssignedPartitionsGauge.With(h.metricsLabels()).Sub(float64(len(session.Claims()[h.Topic])))
 	return nil
 }
 
 // ConsumeClaim starts a consumer loop of ConsumerGroupClaim's Messages() chan.
+//
+// When a message failed to be sent to DeadLetter, the error is passed to
+// ErrorFunc and the send is retried with backoff until it succeeds, so that the
+// message is never skipped. The messages after it in the same partition are
+// not marked in the meantime.
+// If the session ends before the send succeeds, the message is not marked,
+// and is redelivered in the next session.
 func (h GroupConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for m := range claim.Messages() {
-		// Wrap in anonymous function for easier defer.
-		func() {
-			ctx := context.Background()
-			var span *tracing.Span
-			spanName := "group-consumer." + h.Topic
-			ctx, span = tracing.StartTopLevelServerSpan(ctx, spanName)
-			defer func(start time.Time) {
-				groupConsumerTimer.With(prometheus.Labels{
-					topicLabel: h.Topic,
-				}).Observe(time.Since(start).Seconds())
-				span.FinishWithOptions(tracing.FinishOptions{
-					Ctx: ctx,
-				}.Convert())
-			}(time.Now())
-
-			h.Callback(ctx, m)
+	callback := h.ErrorCallback
+	if callback == nil {
+		callback = ignoreErrors(h.Callback)
+	}
+	handler := messageHandler{
+		spanName: "group-consumer." + h.Topic,
+		timer: groupConsumerTimer.With(prometheus.Labels{
+			topicLabel: h.Topic,
+		}),
+		callback:     callback,
+		errorsFunc:   h.ErrorFunc,
+		retryOptions: h.RetryOptions,
+		deadLetter:   h.DeadLetter,
+		done:         session.Context().Done(),
+	}
+	lags := h.lags
+	if lags == nil {
+		lags = newPartitionLags(groupConsumerLagDesc, h.Topic)
+	}
+	lags.start(claim.Partition(), claim.InitialOffset(), claim.HighWaterMarkOffset)
+	defer lags.stop(claim.Partition())
+
+	// Returning an error from ConsumeClaim tears down the whole session,
+	// for all the partitions. handle only returns an error after the session
+	// is already done, which is already reported to ErrorFunc, so it's dropped.
+	consumeMessages(
+		claim.Messages(),
+		h.Concurrency,
+		lags.received,
+		handler.handle,
+		func(m *sarama.ConsumerMessage) {
 			session.MarkMessage(
 				m,
 				"", // metadata
 			)
-		}()
-	}
+			lags.processed(m)
+		},
+	)
 	return nil
 }
@@ -0,0 +1,257 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/avast/retry-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/prometheusbp"
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Headers added to the messages sent to the dead-letter topic,
+// in addition to the headers of the original message.
+const (
+	DeadLetterHeaderTopic     = "Dead-Letter-Original-Topic"
+	DeadLetterHeaderPartition = "Dead-Letter-Original-Partition"
+	DeadLetterHeaderOffset    = "Dead-Letter-Original-Offset"
+	DeadLetterHeaderError     = "Dead-Letter-Error"
+)
+
+// DeadLetterConfig can be used to configure the dead-letter topic of a
+// consumer, where the messages failed to be consumed are sent to.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	deadLetter:
+//	  topic: sample-topic-dlq
+type DeadLetterConfig struct {
+	// Topic is the dead-letter topic. Dead-letter is disabled when it's empty.
+	Topic string `yaml:"topic"`
+
+	// Optional. The producer used to send messages to the dead-letter topic.
+	//
+	// When omitted, NewConsumer creates one from the Brokers, ClientID, Version,
+	// RackID, TLS and SASL of the ConsumerConfig, which is closed when the
+	// consumer is closed.
+	Producer Producer `yaml:"-"`
+}
+
+// The backoff between the rounds of attempts to send a message to the
+// dead-letter topic, when messageHandler.done is set.
+const (
+	deadLetterInitialBackoff = 100 * time.Millisecond
+	deadLetterMaxBackoff     = 10 * time.Second
+)
+
+// messageHandler handles the messages consumed,
+// shared by both the topic consumer and the group consumer.
+type messageHandler struct {
+	spanName string
+	timer    prometheus.Observer
+
+	callback     ConsumeMessageErrorFunc
+	errorsFunc   ConsumeErrorFunc
+	retryOptions []retry.Option
+	deadLetter   DeadLetterConfig
+
+	// Optional. When set, failed sends to the dead-letter topic are reported
+	// and retried with backoff until they succeed or done is closed.
+	done <-chan struct{}
+}
+
+// ignoreErrors converts a ConsumeMessageFunc into a ConsumeMessageErrorFunc
+// always returning nil.
+func ignoreErrors(messageFunc ConsumeMessageFunc) ConsumeMessageErrorFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		messageFunc(ctx, msg)
+		return nil
+	}
+}
+
+// handle calls the callback with msg, in a span started from the trace
+// headers of msg (see StartSpanFromMessage).
+//
+// The callback is retried with the retry options when it returns error,
+// after that msg is sent to the dead-letter topic if configured, otherwise
+// the error is passed to errorsFunc.
+//
+// Sending to the dead-letter topic is also retried with the retry options.
+// If it still fails and done is set, the error is passed to errorsFunc and the
+// send is retried again with backoff, until it succeeds or done is closed.
+// The error is returned when the send finally failed, in which case msg must
+// not be marked as consumed, as it would be lost otherwise.
+// handle returns nil in all the other cases.
+//
+// The span is finished with the final error of the callback.
+// If the callback panics, the span is finished with the panic as the error
+// before the panic is propagated.
+func (h messageHandler) handle(msg *sarama.ConsumerMessage) (dlqErr error) {
+	ctx, span := StartSpanFromMessage(context.Background(), h.spanName, msg)
+	var err error
+	defer func(start time.Time) {
+		r := recover()
+		if r != nil {
+			err = fmt.Errorf("kafkabp: ConsumeMessageFunc panicked: %v", r)
+		}
+		h.timer.Observe(time.Since(start).Seconds())
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+		if r != nil {
+			panic(r)
+		}
+	}(time.Now())
+
+	err = h.retry(ctx, func() error {
+		return h.callback(ctx, msg)
+	})
+	if err == nil {
+		return nil
+	}
+
+	if h.deadLetter.Topic != "" && h.deadLetter.Producer != nil {
+		return h.sendToDeadLetterUntilDone(ctx, msg, err)
+	}
+
+	h.reportError(fmt.Errorf(
+		"kafkabp: failed to consume message (topic %q, partition %d, offset %d): %w",
+		msg.Topic,
+		msg.Partition,
+		msg.Offset,
+		err,
+	))
+	return nil
+}
+
+// retry calls fn with the retry options, or only once when there's none.
+func (h messageHandler) retry(ctx context.Context, fn func() error) error {
+	options := h.retryOptions
+	if len(options) == 0 {
+		options = []retry.Option{retry.Attempts(1)}
+	}
+	return retrybp.Do(ctx, fn, options...)
+}
+
+func (h messageHandler) reportError(err error) {
+	if h.errorsFunc != nil {
+		h.errorsFunc(err)
+	}
+}
+
+// sendToDeadLetterUntilDone calls sendToDeadLetter, and keeps calling it with
+// backoff until it succeeds or h.done is closed.
+//
+// It only calls sendToDeadLetter once when h.done is nil.
+func (h messageHandler) sendToDeadLetterUntilDone(ctx context.Context, msg *sarama.ConsumerMessage, err error) error {
+	backoff := deadLetterInitialBackoff
+	for {
+		dlqErr := h.sendToDeadLetter(ctx, msg, err)
+		if dlqErr == nil {
+			return nil
+		}
+		dlqErr = fmt.Errorf(
+			"kafkabp: failed to send message (topic %q, partition %d, offset %d) to dead-letter topic %q: %w",
+			msg.Topic,
+			msg.Partition,
+			msg.Offset,
+			h.deadLetter.Topic,
+			errors.Join(err, dlqErr),
+		)
+		if h.done == nil {
+			return dlqErr
+		}
+		h.reportError(dlqErr)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-h.done:
+			timer.Stop()
+			return dlqErr
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, deadLetterMaxBackoff)
+	}
+}
+
+// sendToDeadLetter sends msg, failed with err, to the dead-letter topic,
+// with retries.
+func (h messageHandler) sendToDeadLetter(ctx context.Context, msg *sarama.ConsumerMessage, err error) (dlqErr error) {
+	defer func() {
+		deadLetterCounter.With(prometheus.Labels{
+			topicLabel:   msg.Topic,
+			successLabel: prometheusbp.BoolString(dlqErr == nil),
+		}).Inc()
+	}()
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+4)
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers = append(headers, *h)
+		}
+	}
+	headers = append(
+		headers,
+		sarama.RecordHeader{
+			Key:   []byte(DeadLetterHeaderTopic),
+			Value: []byte(msg.Topic),
+		},
+		sarama.RecordHeader{
+			Key:   []byte(DeadLetterHeaderPartition),
+			Value: []byte(strconv.FormatInt(int64(msg.Partition), 10)),
+		},
+		sarama.RecordHeader{
+			Key:   []byte(DeadLetterHeaderOffset),
+			Value: []byte(strconv.FormatInt(msg.Offset, 10)),
+		},
+		sarama.RecordHeader{
+			Key:   []byte(DeadLetterHeaderError),
+			Value: []byte(err.Error()),
+		},
+	)
+
+	return h.retry(ctx, func() error {
+		// The producer sets the fields of the message (e.g. headers, partition and
+		// offset), so send a new one in each attempt.
+		dlqMsg := &sarama.ProducerMessage{
+			Topic:   h.deadLetter.Topic,
+			Value:   sarama.ByteEncoder(msg.Value),
+			Headers: append([]sarama.RecordHeader(nil), headers...),
+		}
+		if msg.Key != nil {
+			dlqMsg.Key = sarama.ByteEncoder(msg.Key)
+		}
+		_, _, err := h.deadLetter.Producer.SendMessage(ctx, dlqMsg)
+		return err
+	})
+}
+
+// newDeadLetterProducer creates the producer for the dead-letter topic from
+// cfg.
+//
+// It returns nil producer when dead-letter is disabled, or the producer is
+// already provided in cfg.
+func newDeadLetterProducer(cfg ConsumerConfig) (Producer, error) {
+	if cfg.DeadLetter.Topic == "" || cfg.DeadLetter.Producer != nil {
+		return nil, nil
+	}
+	return NewProducer(ProducerConfig{
+		Brokers:  cfg.Brokers,
+		ClientID: cfg.ClientID,
+		Version:  cfg.Version,
+		Logger:   cfg.Logger,
+		RackID:   cfg.RackID,
+		TLS:      cfg.TLS,
+		SASL:     cfg.SASL,
+	})
+}
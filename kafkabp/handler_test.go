@@ -0,0 +1,423 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/avast/retry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMessageHandler_Retry(t *testing.T) {
+	var calls int
+	var reported []error
+	h := messageHandler{
+		spanName: "test",
+		timer:    prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test"}),
+		callback: func(context.Context, *sarama.ConsumerMessage) error {
+			calls++
+			if calls < 3 {
+				return errors.New("transient")
+			}
+			return nil
+		},
+		errorsFunc: func(err error) {
+			reported = append(reported, err)
+		},
+		retryOptions: []retry.Option{retry.Attempts(3), retry.Delay(0)},
+	}
+	h.handle(getTestKafkaMessage("key", "value"))
+
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if len(reported) != 0 {
+		t.Errorf("expected no errors reported, got %v", reported)
+	}
+}
+
+func TestMessageHandler_NoDeadLetter(t *testing.T) {
+	consumeErr := errors.New("consume error")
+	var calls int
+	var reported []error
+	h := messageHandler{
+		spanName: "test",
+		timer:    prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test"}),
+		callback: func(context.Context, *sarama.ConsumerMessage) error {
+			calls++
+			return consumeErr
+		},
+		errorsFunc: func(err error) {
+			reported = append(reported, err)
+		},
+		retryOptions: []retry.Option{retry.Attempts(2), retry.Delay(0)},
+	}
+	h.handle(getTestKafkaMessage("key", "value"))
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("expected 1 error reported, got %v", reported)
+	}
+	if !errors.Is(reported[0], consumeErr) {
+		t.Errorf("expected reported error to wrap %v, got %v", consumeErr, reported[0])
+	}
+}
+
+func TestMessageHandler_DeadLetter(t *testing.T) {
+	const dlqTopic = "kafkabp-test-dlq"
+	p, sp, _ := getTestMockProducer(t, nil)
+	defer p.Close()
+
+	msg := getTestKafkaMessage("key", "value")
+	msg.Topic = "kafkabp-test"
+	msg.Partition = 3
+	msg.Offset = 42
+	msg.Headers = []*sarama.RecordHeader{
+		{Key: []byte("foo"), Value: []byte("bar")},
+	}
+
+	var reported []error
+	h := messageHandler{
+		spanName: "test",
+		timer:    prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test"}),
+		callback: func(context.Context, *sarama.ConsumerMessage) error {
+			return errors.New("consume error")
+		},
+		errorsFunc: func(err error) {
+			reported = append(reported, err)
+		},
+		deadLetter: DeadLetterConfig{
+			Topic:    dlqTopic,
+			Producer: p,
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		reported = nil
+		sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(pm *sarama.ProducerMessage) error {
+			if pm.Topic != dlqTopic {
+				return fmt.Errorf("expected topic %q, got %q", dlqTopic, pm.Topic)
+			}
+			for key, expected := range map[string]string{
+				"foo":                     "bar",
+				DeadLetterHeaderTopic:     "kafkabp-test",
+				DeadLetterHeaderPartition: "3",
+				DeadLetterHeaderOffset:    "42",
+				DeadLetterHeaderError:     "consume error",
+			} {
+				if got := getHeader(pm, key); got != expected {
+					return fmt.Errorf("expected header %q to be %q, got %q", key, expected, got)
+				}
+			}
+			key, _ := pm.Key.Encode()
+			value, _ := pm.Value.Encode()
+			if string(key) != "key" || string(value) != "value" {
+				return fmt.Errorf("unexpected key/value %q/%q", key, value)
+			}
+			return nil
+		})
+		if err := h.handle(msg); err != nil {
+			t.Errorf("expected no error returned, got %v", err)
+		}
+		if len(reported) != 0 {
+			t.Errorf("expected no errors reported, got %v", reported)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		reported = nil
+		dlqErr := errors.New("dlq error")
+		sp.ExpectSendMessageAndFail(dlqErr)
+		if err := h.handle(msg); !errors.Is(err, dlqErr) {
+			t.Errorf("expected returned error to wrap %v, got %v", dlqErr, err)
+		}
+		if len(reported) != 0 {
+			t.Errorf("expected no errors reported, got %v", reported)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		reported = nil
+		h := h
+		h.retryOptions = []retry.Option{retry.Attempts(2), retry.Delay(0)}
+		sp.ExpectSendMessageAndFail(errors.New("dlq error"))
+		sp.ExpectSendMessageAndSucceed()
+		if err := h.handle(msg); err != nil {
+			t.Errorf("expected no error returned, got %v", err)
+		}
+	})
+}
+
+func TestMessageHandler_Panic(t *testing.T) {
+	h := messageHandler{
+		spanName: "test",
+		timer:    prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test"}),
+		callback: func(context.Context, *sarama.ConsumerMessage) error {
+			panic("oops")
+		},
+	}
+	defer func() {
+		if r := recover(); r != "oops" {
+			t.Errorf("expected panic to be propagated, got %v", r)
+		}
+	}()
+	h.handle(getTestKafkaMessage("key", "value"))
+}
+
+type testGroupSession struct {
+	sarama.ConsumerGroupSession
+
+	// Optional, defaults to context.Background().
+	ctx context.Context
+
+	lock   sync.Mutex
+	marked []int64
+}
+
+func (s *testGroupSession) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
+}
+
+func (s *testGroupSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func (s *testGroupSession) Claims() map[string][]int32 {
+	return map[string][]int32{
+		"kafkabp-test": {0, 1, 2},
+	}
+}
+
+type testGroupClaim struct {
+	sarama.ConsumerGroupClaim
+
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c testGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func (c testGroupClaim) Partition() int32 {
+	return 0
+}
+
+func (c testGroupClaim) InitialOffset() int64 {
+	return 0
+}
+
+func (c testGroupClaim) HighWaterMarkOffset() int64 {
+	return int64(cap(c.messages))
+}
+
+func TestGroupConsumerHandler_ErrorCallback(t *testing.T) {
+	consumeErr := errors.New("consume error")
+	var reported []error
+	h := GroupConsumerHandler{
+		Topic: "kafkabp-test",
+		ErrorCallback: func(_ context.Context, msg *sarama.ConsumerMessage) error {
+			if msg.Offset%2 == 1 {
+				return consumeErr
+			}
+			return nil
+		},
+		ErrorFunc: func(err error) {
+			reported = append(reported, err)
+		},
+	}
+
+	session := new(testGroupSession)
+	claim := testGroupClaim{
+		messages: make(chan *sarama.ConsumerMessage, 4),
+	}
+	for i := int64(0); i < 4; i++ {
+		msg := getTestKafkaMessage("key", "value")
+		msg.Offset = i
+		claim.messages <- msg
+	}
+	close(claim.messages)
+
+	if err := h.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("ConsumeClaim returned error: %v", err)
+	}
+	if len(session.marked) != 4 {
+		t.Errorf("expected all 4 messages marked, got %v", session.marked)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("expected 2 errors reported, got %v", reported)
+	}
+	for _, err := range reported {
+		if !errors.Is(err, consumeErr) {
+			t.Errorf("expected reported error to wrap %v, got %v", consumeErr, err)
+		}
+	}
+}
+
+func TestGroupConsumerHandler_DeadLetterFailure(t *testing.T) {
+	newClaim := func() testGroupClaim {
+		claim := testGroupClaim{
+			messages: make(chan *sarama.ConsumerMessage, 3),
+		}
+		for i := int64(0); i < 3; i++ {
+			msg := getTestKafkaMessage("key", "value")
+			msg.Offset = i
+			claim.messages <- msg
+		}
+		close(claim.messages)
+		return claim
+	}
+	callback := func(_ context.Context, msg *sarama.ConsumerMessage) error {
+		if msg.Offset == 1 {
+			return errors.New("consume error")
+		}
+		return nil
+	}
+	dlqErr := errors.New("dlq error")
+
+	t.Run("recovered", func(t *testing.T) {
+		p, sp, _ := getTestMockProducer(t, nil)
+		defer p.Close()
+		sp.ExpectSendMessageAndFail(dlqErr)
+		sp.ExpectSendMessageAndFail(dlqErr)
+		sp.ExpectSendMessageAndSucceed()
+
+		var reported []error
+		h := GroupConsumerHandler{
+			Topic:         "kafkabp-test",
+			ErrorCallback: callback,
+			ErrorFunc: func(err error) {
+				reported = append(reported, err)
+			},
+			DeadLetter: DeadLetterConfig{
+				Topic:    "kafkabp-test-dlq",
+				Producer: p,
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		session := &testGroupSession{ctx: ctx}
+		// The session is kept alive instead of being torn down by an error,
+		// and all the messages are eventually marked.
+		if err := h.ConsumeClaim(session, newClaim()); err != nil {
+			t.Errorf("expected ConsumeClaim to return nil, got %v", err)
+		}
+		if want := []int64{0, 1, 2}; !slices.Equal(session.marked, want) {
+			t.Errorf("expected offsets %v marked, got %v", want, session.marked)
+		}
+		if len(reported) != 2 {
+			t.Fatalf("expected 2 errors reported, got %v", reported)
+		}
+		for _, err := range reported {
+			if !errors.Is(err, dlqErr) {
+				t.Errorf("expected reported error to wrap %v, got %v", dlqErr, err)
+			}
+		}
+	})
+
+	t.Run("session-done", func(t *testing.T) {
+		p, sp, _ := getTestMockProducer(t, nil)
+		defer p.Close()
+		sp.ExpectSendMessageAndFail(dlqErr)
+		sp.ExpectSendMessageAndFail(dlqErr)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var reported int
+		h := GroupConsumerHandler{
+			Topic:         "kafkabp-test",
+			ErrorCallback: callback,
+			ErrorFunc: func(err error) {
+				reported++
+				if reported == 2 {
+					// Simulate the end of the session, e.g. from a rebalance.
+					cancel()
+				}
+			},
+			DeadLetter: DeadLetterConfig{
+				Topic:    "kafkabp-test-dlq",
+				Producer: p,
+			},
+		}
+		session := &testGroupSession{ctx: ctx}
+		if err := h.ConsumeClaim(session, newClaim()); err != nil {
+			t.Errorf("expected ConsumeClaim to return nil, got %v", err)
+		}
+		if want := []int64{0}; !slices.Equal(session.marked, want) {
+			t.Errorf("expected offsets %v marked, got %v", want, session.marked)
+		}
+	})
+}
+
+func TestGroupConsumerHandler_SetupCleanup(t *testing.T) {
+	var joined atomic.Bool
+	h := GroupConsumerHandler{
+		Topic:   "kafkabp-test",
+		GroupID: "kafkabp-test-group",
+		joined:  &joined,
+	}
+	// Another consumer of the same topic and group in the same process.
+	other := GroupConsumerHandler{
+		Topic:   h.Topic,
+		GroupID: h.GroupID,
+		joined:  new(atomic.Bool),
+	}
+	labels := prometheus.Labels{topicLabel: h.Topic, groupLabel: h.GroupID}
+	rebalances := testutil.ToFloat64(groupRebalancesCounter.With(labels))
+	session := new(testGroupSession)
+
+	checkRebalances := func(t *testing.T, want float64) {
+		t.Helper()
+		if got := testutil.ToFloat64(groupRebalancesCounter.With(labels)) - rebalances; got != want {
+			t.Errorf("rebalances expected to increase by %v, got %v", want, got)
+		}
+	}
+	checkAssigned := func(t *testing.T, want float64) {
+		t.Helper()
+		if got := testutil.ToFloat64(groupAssignedPartitionsGauge.With(labels)); got != want {
+			t.Errorf("assigned partitions expected %v, got %v", want, got)
+		}
+	}
+
+	// The initial joins are not rebalances.
+	if err := h.Setup(session); err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	if err := other.Setup(session); err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	checkRebalances(t, 0)
+	checkAssigned(t, 6)
+
+	if err := h.Cleanup(session); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+	checkAssigned(t, 3)
+
+	// The next session of h is a rebalance.
+	if err := h.Setup(session); err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	checkRebalances(t, 1)
+	checkAssigned(t, 6)
+
+	for _, h := range []GroupConsumerHandler{h, other} {
+		if err := h.Cleanup(session); err != nil {
+			t.Fatalf("Cleanup returned error: %v", err)
+		}
+	}
+	checkAssigned(t, 0)
+}
@@ -0,0 +1,273 @@
+package kafkabp
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+// partitionLag is the lag state of a partition.
+type partitionLag struct {
+	// The high-water mark of the partition,
+	// which is the offset of the next message produced to it.
+	highWaterMark func() int64
+	// The offset of the next message to be processed,
+	// negative means unknown (no message received yet).
+	next int64
+}
+
+func (pl *partitionLag) lag() (int64, bool) {
+	if pl.next < 0 {
+		return 0, false
+	}
+	lag := pl.highWaterMark() - pl.next
+	if lag < 0 {
+		lag = 0
+	}
+	return lag, true
+}
+
+// partitionLags tracks the lag of the partitions being consumed.
+//
+// The lag is reported by lagExporter, computed when the metrics are collected.
+type partitionLags struct {
+	desc  *prometheus.Desc
+	topic string
+
+	lock       sync.Mutex
+	partitions map[int32]*partitionLag
+}
+
+func newPartitionLags(desc *prometheus.Desc, topic string) *partitionLags {
+	return &partitionLags{
+		desc:       desc,
+		topic:      topic,
+		partitions: make(map[int32]*partitionLag),
+	}
+}
+
+// start starts tracking partition, with the offset of the first message to be
+// consumed (negative when unknown) and the function to get the high-water mark
+// of the partition.
+func (l *partitionLags) start(partition int32, initialOffset int64, highWaterMark func() int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.partitions[partition] = &partitionLag{
+		highWaterMark: highWaterMark,
+		next:          initialOffset,
+	}
+	lagExporter.add(l)
+}
+
+// received seeds the offset of the next message to be processed of the
+// partition of msg, when it's unknown.
+//
+// It's called when msg is received from the partition, before it's processed,
+// so a consumer stuck on its first message still reports its lag.
+func (l *partitionLags) received(msg *sarama.ConsumerMessage) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if pl, ok := l.partitions[msg.Partition]; ok && pl.next < 0 {
+		pl.next = msg.Offset
+	}
+}
+
+// processed updates the lag of the partition of msg after msg is processed.
+func (l *partitionLags) processed(msg *sarama.ConsumerMessage) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	pl, ok := l.partitions[msg.Partition]
+	if !ok {
+		return
+	}
+	if next := msg.Offset + 1; next > pl.next {
+		pl.next = next
+	}
+}
+
+// stop stops tracking partition, when it's no longer consumed.
+func (l *partitionLags) stop(partition int32) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.partitions, partition)
+	if len(l.partitions) == 0 {
+		lagExporter.remove(l)
+	}
+}
+
+// lags calls f with the lag of each partition with known lag.
+func (l *partitionLags) lags(f func(partition int32, lag int64)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for partition, pl := range l.partitions {
+		if lag, ok := pl.lag(); ok {
+			f(partition, lag)
+		}
+	}
+}
+
+// max returns the max lag of all the partitions being tracked.
+func (l *partitionLags) max() int64 {
+	var result int64
+	l.lags(func(_ int32, lag int64) {
+		if lag > result {
+			result = lag
+		}
+	})
+	return result
+}
+
+// lagCollector is a prometheus.Collector reporting the lag of all the
+// partitionLags tracking at least one partition.
+//
+// The lag is computed when collected, so it keeps growing when the consumer is
+// stuck without processing any message.
+type lagCollector struct {
+	descs []*prometheus.Desc
+
+	lock sync.Mutex
+	lags map[*partitionLags]struct{}
+}
+
+var lagExporter = func() *lagCollector {
+	c := &lagCollector{
+		descs: []*prometheus.Desc{consumerLagDesc, groupConsumerLagDesc},
+		lags:  make(map[*partitionLags]struct{}),
+	}
+	prometheusbpint.GlobalRegistry.MustRegister(c)
+	return c
+}()
+
+func (c *lagCollector) add(l *partitionLags) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lags[l] = struct{}{}
+}
+
+func (c *lagCollector) remove(l *partitionLags) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.lags, l)
+}
+
+// Describe implements prometheus.Collector.
+func (c *lagCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *lagCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	all := make([]*partitionLags, 0, len(c.lags))
+	for l := range c.lags {
+		all = append(all, l)
+	}
+	c.lock.Unlock()
+
+	type key struct {
+		desc      *prometheus.Desc
+		topic     string
+		partition int32
+	}
+	// Multiple consumers of the same topic in the same process would report
+	// the same partitions, only report the max lag of them.
+	maxLags := make(map[key]int64)
+	for _, l := range all {
+		l.lags(func(partition int32, lag int64) {
+			k := key{
+				desc:      l.desc,
+				topic:     l.topic,
+				partition: partition,
+			}
+			if prev, ok := maxLags[k]; !ok || lag > prev {
+				maxLags[k] = lag
+			}
+		})
+	}
+	for k, lag := range maxLags {
+		ch <- prometheus.MustNewConstMetric(
+			k.desc,
+			prometheus.GaugeValue,
+			float64(lag),
+			k.topic,
+			strconv.FormatInt(int64(k.partition), 10),
+		)
+	}
+}
+
+// LagHealthCheckArgs defines the args used in NewLagHealthChecker.
+type LagHealthCheckArgs struct {
+	// The max lag (in number of messages) of any partition considered healthy.
+	MaxLag int64
+
+	// How long the lag can stay above MaxLag before the consumer is considered
+	// unhealthy.
+	MaxDuration time.Duration
+}
+
+// LagHealthChecker is a health checker of a Consumer,
+// which also fails when the lag of the consumer stays above a threshold for
+// too long.
+//
+// It implements baseplate.HealthChecker.
+type LagHealthChecker struct {
+	consumer Consumer
+	args     LagHealthCheckArgs
+
+	lock       sync.Mutex
+	aboveSince time.Time
+}
+
+// lagReporter is implemented by the consumers created by NewConsumer.
+type lagReporter interface {
+	maxLag() int64
+}
+
+// NewLagHealthChecker creates a LagHealthChecker for consumer.
+//
+// The lag is the high-water mark of a partition minus the offset of the next
+// message to be processed, as reported by the kafkabp_consumer_lag_messages
+// and kafkabp_group_consumer_lag_messages gauges.
+// The lag of a partition is unknown (and ignored) until its first message is
+// received.
+// The duration above MaxLag is measured between the IsHealthy calls,
+// so the health check is expected to be called periodically.
+//
+// It only checks consumer.IsHealthy if consumer is not created by NewConsumer.
+func NewLagHealthChecker(consumer Consumer, args LagHealthCheckArgs) *LagHealthChecker {
+	return &LagHealthChecker{
+		consumer: consumer,
+		args:     args,
+	}
+}
+
+// IsHealthy returns false if either the consumer is unhealthy, or its lag has
+// stayed above MaxLag for longer than MaxDuration.
+func (c *LagHealthChecker) IsHealthy(ctx context.Context) bool {
+	if !c.consumer.IsHealthy(ctx) {
+		return false
+	}
+	lr, ok := c.consumer.(lagReporter)
+	if !ok {
+		return true
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if lr.maxLag() <= c.args.MaxLag {
+		c.aboveSince = time.Time{}
+		return true
+	}
+	if c.aboveSince.IsZero() {
+		c.aboveSince = time.Now()
+	}
+	return time.Since(c.aboveSince) <= c.args.MaxDuration
+}
@@ -0,0 +1,156 @@
+package kafkabp
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collectLag returns the lag of the partition collected by lagExporter.
+func collectLag(t *testing.T, topic string, partition int32) (float64, bool) {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	lagExporter.Collect(ch)
+	close(ch)
+	for m := range ch {
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		labels := make(map[string]string)
+		for _, pair := range metric.GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		if labels[topicLabel] == topic && labels[partitionLabel] == strconv.Itoa(int(partition)) {
+			return metric.GetGauge().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func TestPartitionLags(t *testing.T) {
+	const topic = "kafkabp-test-partition-lags"
+	lags := newPartitionLags(consumerLagDesc, topic)
+
+	var hwm atomic.Int64
+	hwm.Store(10)
+	lags.start(0, -1, hwm.Load)
+	lags.start(1, 5, func() int64 { return 8 })
+
+	// Partition 0 has no message received yet, so only partition 1 counts.
+	if got, want := lags.max(), int64(3); got != want {
+		t.Errorf("max lag expected %d, got %d", want, got)
+	}
+	if _, ok := collectLag(t, topic, 0); ok {
+		t.Error("expected no lag collected for partition 0 before any message received")
+	}
+
+	// The first message received is stuck.
+	lags.received(&sarama.ConsumerMessage{Partition: 0, Offset: 2})
+	if got, want := lags.max(), int64(8); got != want {
+		t.Errorf("max lag expected %d, got %d", want, got)
+	}
+
+	lags.processed(&sarama.ConsumerMessage{Partition: 0, Offset: 2})
+	if got, _ := collectLag(t, topic, 0); got != 7 {
+		t.Errorf("lag of partition 0 expected %v, got %v", 7, got)
+	}
+	if got, want := lags.max(), int64(7); got != want {
+		t.Errorf("max lag expected %d, got %d", want, got)
+	}
+
+	// The lag grows without any message processed.
+	hwm.Store(20)
+	if got, _ := collectLag(t, topic, 0); got != 17 {
+		t.Errorf("lag of partition 0 expected %v, got %v", 17, got)
+	}
+
+	lags.stop(0)
+	if got, want := lags.max(), int64(3); got != want {
+		t.Errorf("max lag expected %d after stop, got %d", want, got)
+	}
+	if _, ok := collectLag(t, topic, 0); ok {
+		t.Error("expected no lag collected for partition 0 after stop")
+	}
+
+	lags.stop(1)
+	lagExporter.lock.Lock()
+	_, ok := lagExporter.lags[lags]
+	lagExporter.lock.Unlock()
+	if ok {
+		t.Error("expected lags to be removed from lagExporter after all partitions stopped")
+	}
+}
+
+func TestLagCollectorGather(t *testing.T) {
+	lags := newPartitionLags(groupConsumerLagDesc, "kafkabp-test-lag-collector")
+	lags.start(0, 1, func() int64 { return 5 })
+	defer lags.stop(0)
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(lagExporter)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == "kafkabp_group_consumer_lag_messages" {
+			return
+		}
+	}
+	t.Error("expected kafkabp_group_consumer_lag_messages to be gathered")
+}
+
+type testLagConsumer struct {
+	Consumer
+
+	healthy bool
+	lag     atomic.Int64
+}
+
+func (c *testLagConsumer) IsHealthy(context.Context) bool {
+	return c.healthy
+}
+
+func (c *testLagConsumer) maxLag() int64 {
+	return c.lag.Load()
+}
+
+func TestLagHealthChecker(t *testing.T) {
+	const maxDuration = 50 * time.Millisecond
+	consumer := &testLagConsumer{healthy: true}
+	checker := NewLagHealthChecker(consumer, LagHealthCheckArgs{
+		MaxLag:      100,
+		MaxDuration: maxDuration,
+	})
+	ctx := context.Background()
+
+	if !checker.IsHealthy(ctx) {
+		t.Error("expected healthy without lag")
+	}
+
+	consumer.lag.Store(101)
+	if !checker.IsHealthy(ctx) {
+		t.Error("expected healthy right after lag goes above MaxLag")
+	}
+	time.Sleep(maxDuration * 2)
+	if checker.IsHealthy(ctx) {
+		t.Error("expected unhealthy after lag stays above MaxLag for longer than MaxDuration")
+	}
+
+	consumer.lag.Store(100)
+	if !checker.IsHealthy(ctx) {
+		t.Error("expected healthy after lag goes back to MaxLag")
+	}
+
+	consumer.healthy = false
+	if checker.IsHealthy(ctx) {
+		t.Error("expected unhealthy when the consumer is unhealthy")
+	}
+}
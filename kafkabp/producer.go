@@ -0,0 +1,291 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/prometheusbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// ErrProducerClosed is returned when sending messages via a closed Producer.
+var ErrProducerClosed = errors.New("kafkabp: Producer is closed")
+
+// Producer defines the interface of a producer struct.
+//
+// Every message sent is wrapped in a client span, which is injected into the
+// message headers (see SetSpanHeaders).
+type Producer interface {
+	// Close flushes all the messages sent via SendMessageAsync,
+	// then closes the producer.
+	//
+	// It's suitable to be used in baseplate.ServeArgs.PostShutdown.
+	io.Closer
+
+	// SendMessage sends msg and waits for it to be acknowledged by the brokers.
+	//
+	// If the Topic of msg is empty, the Topic from ProducerConfig is used.
+	SendMessage(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+
+	// SendMessageAsync queues msg to be sent in the background.
+	//
+	// If the Topic of msg is empty, the Topic from ProducerConfig is used.
+	//
+	// It only returns error when msg can't be queued, for example the producer
+	// is closed or ctx is canceled before the queue has room for msg.
+	// Errors happened while sending msg are logged via ProducerConfig.Logger.
+	// msg shall not be reused after it's queued.
+	SendMessageAsync(ctx context.Context, msg *sarama.ProducerMessage) error
+}
+
+// producer implements a Kafka producer.
+type producer struct {
+	cfg ProducerConfig
+
+	// client is nil when the producers are not created from it (in tests).
+	client sarama.Client
+	sync   sarama.SyncProducer
+	async  sarama.AsyncProducer
+
+	// lock guards closed, sending holds the read lock so that Close waits for
+	// the ongoing sends.
+	lock   sync.RWMutex
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewProducer creates a new Kafka producer.
+//
+// The overriders can be used to set specific sarama config not supported by
+// ProducerConfig. Producer.Return.Successes and Producer.Return.Errors are
+// always set to true after the overriders, as they are required by the
+// producer.
+//
+// Example:
+//
+//	producer, err := kafkabp.NewProducer(cfg.Kafka)
+//	if err != nil {
+//	  log.Fatalw("Failed to create kafka producer", "err", err)
+//	}
+//	baseplate.Serve(ctx, baseplate.ServeArgs{
+//	  Server:       server,
+//	  PostShutdown: []io.Closer{producer},
+//	})
+func NewProducer(cfg ProducerConfig, overriders ...SaramaConfigOverrider) (Producer, error) {
+	sc, err := cfg.NewSaramaConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, override := range overriders {
+		override(sc)
+	}
+	sc.Producer.Return.Successes = true
+	sc.Producer.Return.Errors = true
+
+	client, err := sarama.NewClient(cfg.Brokers, sc)
+	if err != nil {
+		return nil, err
+	}
+	syncProducer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	asyncProducer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		syncProducer.Close()
+		client.Close()
+		return nil, err
+	}
+	return newProducer(cfg, client, syncProducer, asyncProducer), nil
+}
+
+func newProducer(
+	cfg ProducerConfig,
+	client sarama.Client,
+	syncProducer sarama.SyncProducer,
+	asyncProducer sarama.AsyncProducer,
+) *producer {
+	p := &producer{
+		cfg:    cfg,
+		client: client,
+		sync:   syncProducer,
+		async:  asyncProducer,
+	}
+
+	// Both channels are closed by the async producer after it's closed and
+	// flushed.
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		for msg := range asyncProducer.Successes() {
+			p.finishAsync(msg, nil)
+		}
+	}()
+	go func() {
+		defer p.wg.Done()
+		for pe := range asyncProducer.Errors() {
+			p.finishAsync(pe.Msg, pe.Err)
+		}
+	}()
+
+	return p
+}
+
+// asyncMessage is stored in the Metadata of the messages sent via
+// SendMessageAsync to finish their spans and metrics.
+type asyncMessage struct {
+	ctx   context.Context
+	span  *tracing.Span
+	start time.Time
+
+	// The original Metadata of the message.
+	metadata interface{}
+}
+
+// startSpan fills the default topic of msg and starts the client span for it.
+func (p *producer) startSpan(ctx context.Context, msg *sarama.ProducerMessage) (context.Context, *tracing.Span, error) {
+	if msg.Topic == "" {
+		msg.Topic = p.cfg.Topic
+	}
+	if msg.Topic == "" {
+		return nil, nil, ErrTopicEmpty
+	}
+
+	otSpan, ctx := opentracing.StartSpanFromContext(
+		ctx,
+		"producer."+msg.Topic,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	span := tracing.AsSpan(otSpan)
+	SetSpanHeaders(msg, span)
+	return ctx, span, nil
+}
+
+func (p *producer) SendMessage(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return 0, 0, ErrProducerClosed
+	}
+
+	ctx, span, err := p.startSpan(ctx, msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func(start time.Time) {
+		producerTimer.With(prometheus.Labels{
+			topicLabel:   msg.Topic,
+			successLabel: prometheusbp.BoolString(err == nil),
+			asyncLabel:   prometheusbp.BoolString(false),
+		}).Observe(time.Since(start).Seconds())
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}(time.Now())
+
+	return p.sync.SendMessage(msg)
+}
+
+func (p *producer) SendMessageAsync(ctx context.Context, msg *sarama.ProducerMessage) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	ctx, span, err := p.startSpan(ctx, msg)
+	if err != nil {
+		return err
+	}
+	am := &asyncMessage{
+		ctx:      ctx,
+		span:     span,
+		start:    time.Now(),
+		metadata: msg.Metadata,
+	}
+	msg.Metadata = am
+	inflight := producerAsyncInflight.With(prometheus.Labels{
+		topicLabel: msg.Topic,
+	})
+	inflight.Inc()
+
+	select {
+	case p.async.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		inflight.Dec()
+		msg.Metadata = am.metadata
+		err := ctx.Err()
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+		return err
+	}
+}
+
+// finishAsync finishes the span and metrics of msg sent via SendMessageAsync.
+func (p *producer) finishAsync(msg *sarama.ProducerMessage, err error) {
+	if msg == nil {
+		return
+	}
+	am, ok := msg.Metadata.(*asyncMessage)
+	if !ok {
+		return
+	}
+	msg.Metadata = am.metadata
+
+	producerAsyncInflight.With(prometheus.Labels{
+		topicLabel: msg.Topic,
+	}).Dec()
+	producerTimer.With(prometheus.Labels{
+		topicLabel:   msg.Topic,
+		successLabel: prometheusbp.BoolString(err == nil),
+		asyncLabel:   prometheusbp.BoolString(true),
+	}).Observe(time.Since(am.start).Seconds())
+	if err != nil {
+		p.cfg.Logger.Log(am.ctx, fmt.Sprintf(
+			"kafkabp.producer: Failed to send message to topic %q: %v",
+			msg.Topic,
+			err,
+		))
+	}
+	am.span.FinishWithOptions(tracing.FinishOptions{
+		Ctx: am.ctx,
+		Err: err,
+	}.Convert())
+}
+
+// Close flushes the async producer first, then closes the sync producer and
+// the client.
+func (p *producer) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.lock.Unlock()
+
+	p.async.AsyncClose()
+	// wait for the results of all the flushed messages to be handled
+	p.wg.Wait()
+
+	errs := []error{p.sync.Close()}
+	if p.client != nil {
+		errs = append(errs, p.client.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,161 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+func TestProducer_SendMessage(t *testing.T) {
+	p, sp, _ := getTestMockProducer(t, nil)
+	defer p.Close()
+
+	ctx, parent := tracing.StartTopLevelServerSpan(context.Background(), "test")
+	defer parent.Finish()
+
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		if msg.Topic != p.cfg.Topic {
+			return fmt.Errorf("expected topic %q, got %q", p.cfg.Topic, msg.Topic)
+		}
+		if got := getHeader(msg, transport.HeaderTracingTrace); got != parent.TraceID() {
+			return fmt.Errorf("expected trace header %q, got %q", parent.TraceID(), got)
+		}
+		if got := getHeader(msg, transport.HeaderTracingParent); got != parent.ID() {
+			return fmt.Errorf("expected parent header %q, got %q", parent.ID(), got)
+		}
+		if got := getHeader(msg, transport.HeaderTracingTraceParent); got == "" {
+			return errors.New("expected traceparent header to be set")
+		}
+		if got := getHeader(msg, "foo"); got != "bar" {
+			return fmt.Errorf("expected existing header to be kept, got %q", got)
+		}
+		return nil
+	})
+	if _, _, err := p.SendMessage(ctx, &sarama.ProducerMessage{
+		Value: sarama.StringEncoder("value"),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("foo"), Value: []byte("bar")},
+			{Key: []byte(transport.HeaderTracingTrace), Value: []byte("stale")},
+		},
+	}); err != nil {
+		t.Errorf("SendMessage returned error: %v", err)
+	}
+
+	sendErr := errors.New("send error")
+	sp.ExpectSendMessageAndFail(sendErr)
+	if _, _, err := p.SendMessage(ctx, &sarama.ProducerMessage{
+		Value: sarama.StringEncoder("value"),
+	}); !errors.Is(err, sendErr) {
+		t.Errorf("expected error %v, got %v", sendErr, err)
+	}
+}
+
+func TestProducer_NoTopic(t *testing.T) {
+	p, _, _ := getTestMockProducer(t, nil)
+	defer p.Close()
+	p.cfg.Topic = ""
+
+	msg := &sarama.ProducerMessage{
+		Value: sarama.StringEncoder("value"),
+	}
+	if _, _, err := p.SendMessage(context.Background(), msg); !errors.Is(err, ErrTopicEmpty) {
+		t.Errorf("expected error %v, got %v", ErrTopicEmpty, err)
+	}
+	if err := p.SendMessageAsync(context.Background(), msg); !errors.Is(err, ErrTopicEmpty) {
+		t.Errorf("expected error %v, got %v", ErrTopicEmpty, err)
+	}
+}
+
+func TestProducer_SendMessageAsync(t *testing.T) {
+	var logged []string
+	var lock sync.Mutex
+	logger := func(_ context.Context, msg string) {
+		lock.Lock()
+		defer lock.Unlock()
+		logged = append(logged, msg)
+	}
+	p, _, ap := getTestMockProducer(t, logger)
+
+	const n = 5
+	for i := 0; i < n; i++ {
+		ap.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			if getHeader(msg, transport.HeaderTracingTrace) == "" {
+				return errors.New("expected trace header to be set")
+			}
+			return nil
+		})
+	}
+	ap.ExpectInputAndFail(errors.New("send error"))
+
+	msgs := make([]*sarama.ProducerMessage, 0, n+1)
+	for i := 0; i < n+1; i++ {
+		msg := &sarama.ProducerMessage{
+			Value:    sarama.StringEncoder("value"),
+			Metadata: i,
+		}
+		msgs = append(msgs, msg)
+		if err := p.SendMessageAsync(context.Background(), msg); err != nil {
+			t.Fatalf("SendMessageAsync returned error: %v", err)
+		}
+	}
+
+	// Close shall flush all the messages before returning.
+	if err := p.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	for i, msg := range msgs {
+		if msg.Metadata != i {
+			t.Errorf("expected metadata of message %d to be restored, got %#v", i, msg.Metadata)
+		}
+	}
+	if len(logged) != 1 {
+		t.Errorf("expected 1 error logged, got %q", logged)
+	}
+
+	if err := p.SendMessageAsync(context.Background(), &sarama.ProducerMessage{}); !errors.Is(err, ErrProducerClosed) {
+		t.Errorf("expected error %v after Close, got %v", ErrProducerClosed, err)
+	}
+	if _, _, err := p.SendMessage(context.Background(), &sarama.ProducerMessage{}); !errors.Is(err, ErrProducerClosed) {
+		t.Errorf("expected error %v after Close, got %v", ErrProducerClosed, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("second Close returned error: %v", err)
+	}
+}
+
+// Helper functions
+
+func getTestMockProducer(t *testing.T, logger func(context.Context, string)) (*producer, *mocks.SyncProducer, *mocks.AsyncProducer) {
+	t.Helper()
+
+	cfg := ProducerConfig{
+		Brokers:  []string{"127.0.0.1:9090", "127.0.0.2:9090"},
+		Topic:    "kafkabp-test",
+		ClientID: "test-mock-producer",
+		Logger:   logger,
+	}
+	sc, err := cfg.NewSaramaConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := mocks.NewSyncProducer(t, sc)
+	ap := mocks.NewAsyncProducer(t, sc)
+	return newProducer(cfg, nil, sp, ap), sp, ap
+}
+
+func getHeader(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -13,9 +13,13 @@ const (
 
 	subsystemConsumer      = "consumer"
 	subsystemGroupConsumer = "group_consumer"
+	subsystemProducer      = "producer"
 
-	successLabel = "kafka_success"
-	topicLabel   = "kafka_topic"
+	successLabel   = "kafka_success"
+	topicLabel     = "kafka_topic"
+	groupLabel     = "kafka_group"
+	partitionLabel = "kafka_partition"
+	asyncLabel     = "kafka_async"
 )
 
 var (
@@ -55,6 +59,81 @@ var (
 	}, timerLabels)
 )
 
+var (
+	lagLabels = []string{
+		topicLabel,
+		partitionLabel,
+	}
+
+	consumerLagDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(promNamespace, subsystemConsumer, "lag_messages"),
+		"The high-water mark minus the offset of the next message to be processed of each partition consumed by a non-group consumer",
+		lagLabels,
+		nil,
+	)
+
+	groupConsumerLagDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(promNamespace, subsystemGroupConsumer, "lag_messages"),
+		"The high-water mark minus the offset of the next message to be processed of each partition claimed by a group consumer",
+		lagLabels,
+		nil,
+	)
+
+	groupLabels = []string{
+		topicLabel,
+		groupLabel,
+	}
+
+	groupRebalancesCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemGroupConsumer,
+		Name:      "rebalances_total",
+		Help:      "The number of rebalances of the group consumers, excluding their initial joins of the group",
+	}, groupLabels)
+
+	groupAssignedPartitionsGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemGroupConsumer,
+		Name:      "assigned_partitions",
+		Help:      "The number of partitions assigned to the group consumers in their current sessions",
+	}, groupLabels)
+)
+
+var (
+	deadLetterCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemConsumer,
+		Name:      "dead_letter_messages_total",
+		Help:      "The number of messages failed to be consumed and sent to the dead-letter topic",
+	}, []string{
+		topicLabel,
+		successLabel,
+	})
+)
+
+var (
+	producerLabels = []string{
+		topicLabel,
+		successLabel,
+		asyncLabel,
+	}
+
+	producerTimer = promauto.With(prometheusbpint.GlobalRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemProducer,
+		Name:      "duration_seconds",
+		Help:      "The time took for a producer to send a single kafka message, until it's acknowledged by the brokers",
+		Buckets:   prometheusbp.DefaultLatencyBuckets,
+	}, producerLabels)
+
+	producerAsyncInflight = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemProducer,
+		Name:      "async_inflight_messages",
+		Help:      "The number of messages sent via SendMessageAsync not yet acknowledged by the brokers",
+	}, []string{topicLabel})
+)
+
 var (
 	awsRackFailure = promauto.With(prometheusbpint.GlobalRegistry).NewCounter(prometheus.CounterOpts{
 		Namespace: promNamespace,
@@ -0,0 +1,144 @@
+package kafkabp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Shopify/sarama"
+)
+
+var (
+	// ErrTLSCertKeyMismatch is thrown when only one of CertFile and KeyFile is
+	// specified in TLSConfig.
+	ErrTLSCertKeyMismatch = errors.New("kafkabp: TLS CertFile and KeyFile must be specified together")
+
+	// ErrSASLMechanismInvalid is thrown when an unsupported SASL mechanism is
+	// specified.
+	ErrSASLMechanismInvalid = errors.New("kafkabp: SASL Mechanism is invalid")
+
+	// ErrSASLSCRAMClientNil is thrown when a SCRAM SASL mechanism is specified
+	// without SCRAMClientGeneratorFunc.
+	ErrSASLSCRAMClientNil = errors.New("kafkabp: SASL SCRAMClientGeneratorFunc is nil")
+)
+
+// TLSConfig can be used to configure TLS connections to the kafka brokers.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	tls:
+//	  enabled: true
+//	  caFile: /etc/kafka/ca.pem
+//	  certFile: /etc/kafka/client.pem
+//	  keyFile: /etc/kafka/client-key.pem
+type TLSConfig struct {
+	// Enabled turns on TLS.
+	Enabled bool `yaml:"enabled"`
+
+	// Optional. The PEM encoded CA certificates used to verify the brokers.
+	//
+	// When omitted, the system CA certificates are used.
+	CAFile string `yaml:"caFile"`
+
+	// Optional. The PEM encoded client certificate and its key, used for
+	// mutual TLS. They must be specified together.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// Optional. InsecureSkipVerify disables the verification of the brokers'
+	// certificates. It should only be used in tests.
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+}
+
+// apply applies cfg to the Net config of c.
+func (cfg TLSConfig) apply(c *sarama.Config) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	tc := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("kafkabp: failed to read TLS CAFile: %w", err)
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("kafkabp: no valid certificates in TLS CAFile %q", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return ErrTLSCertKeyMismatch
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("kafkabp: failed to load TLS certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	c.Net.TLS.Enable = true
+	c.Net.TLS.Config = tc
+	return nil
+}
+
+// SASLConfig can be used to configure SASL authentication with the kafka
+// brokers.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	sasl:
+//	  mechanism: PLAIN
+//	  username: myuser
+//	  password: mypassword
+type SASLConfig struct {
+	// Optional. The SASL mechanism, one of "PLAIN", "SCRAM-SHA-256" and
+	// "SCRAM-SHA-512". Defaults to "PLAIN".
+	Mechanism string `yaml:"mechanism"`
+
+	// SASL is enabled when Username is non-empty.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// Required when Mechanism is one of the SCRAM mechanisms.
+	// It generates the SCRAM clients used in the authentication,
+	// for example based on github.com/xdg-go/scram.
+	SCRAMClientGeneratorFunc func() sarama.SCRAMClient `yaml:"-"`
+}
+
+// apply applies cfg to the Net config of c.
+func (cfg SASLConfig) apply(c *sarama.Config) error {
+	if cfg.Username == "" {
+		return nil
+	}
+
+	var mechanism sarama.SASLMechanism
+	switch cfg.Mechanism {
+	default:
+		return fmt.Errorf("%w: %q", ErrSASLMechanismInvalid, cfg.Mechanism)
+	case "", sarama.SASLTypePlaintext:
+		mechanism = sarama.SASLTypePlaintext
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		if cfg.SCRAMClientGeneratorFunc == nil {
+			return ErrSASLSCRAMClientNil
+		}
+		mechanism = sarama.SASLMechanism(cfg.Mechanism)
+		c.Net.SASL.SCRAMClientGeneratorFunc = cfg.SCRAMClientGeneratorFunc
+	}
+
+	c.Net.SASL.Enable = true
+	c.Net.SASL.Handshake = true
+	c.Net.SASL.Mechanism = mechanism
+	c.Net.SASL.User = cfg.Username
+	c.Net.SASL.Password = cfg.Password
+	return nil
+}
@@ -0,0 +1,121 @@
+package kafkabp
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+// SetSpanHeaders sets the Baseplate and W3C trace headers and the baggage
+// header from span onto the headers of msg, replacing the existing ones.
+//
+// Producer calls it automatically with the client span of every message.
+// Message headers require the kafka version to be at least "0.11.0.0".
+func SetSpanHeaders(msg *sarama.ProducerMessage, span *tracing.Span) {
+	set := map[string]string{
+		transport.HeaderTracingTrace:       span.TraceID(),
+		transport.HeaderTracingSpan:        span.ID(),
+		transport.HeaderTracingFlags:       strconv.FormatInt(span.Flags(), 10),
+		transport.HeaderTracingTraceParent: span.TraceParent(),
+	}
+	if parent := span.ParentID(); parent != "" {
+		set[transport.HeaderTracingParent] = parent
+	}
+	if span.Sampled() {
+		set[transport.HeaderTracingSampled] = transport.HeaderTracingSampledTrue
+	}
+	if state := span.TraceState(); state != "" {
+		set[transport.HeaderTracingTraceState] = state
+	}
+	if baggage := span.Baggage(); baggage != "" {
+		set[transport.HeaderTracingBaggage] = baggage
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+len(set))
+	for _, h := range msg.Headers {
+		if isTracingHeader(string(h.Key)) {
+			continue
+		}
+		headers = append(headers, h)
+	}
+	for _, key := range tracingHeaders {
+		if value, ok := set[key]; ok {
+			headers = append(headers, sarama.RecordHeader{
+				Key:   []byte(key),
+				Value: []byte(value),
+			})
+		}
+	}
+	msg.Headers = headers
+}
+
+// MessageSpanHeaders returns the trace headers set by SetSpanHeaders (or any
+// other producer using the same Baseplate or W3C headers) on msg.
+//
+// The header keys are matched case-insensitively.
+func MessageSpanHeaders(msg *sarama.ConsumerMessage) tracing.Headers {
+	var headers tracing.Headers
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		value := string(h.Value)
+		switch key := string(h.Key); {
+		case strings.EqualFold(key, transport.HeaderTracingTrace):
+			headers.TraceID = value
+		case strings.EqualFold(key, transport.HeaderTracingSpan):
+			headers.SpanID = value
+		case strings.EqualFold(key, transport.HeaderTracingFlags):
+			headers.Flags = value
+		case strings.EqualFold(key, transport.HeaderTracingSampled):
+			sampled := value == transport.HeaderTracingSampledTrue
+			headers.Sampled = &sampled
+		case strings.EqualFold(key, transport.HeaderTracingTraceParent):
+			headers.TraceParent = value
+		case strings.EqualFold(key, transport.HeaderTracingTraceState):
+			headers.TraceState = value
+		case strings.EqualFold(key, transport.HeaderTracingBaggage):
+			headers.Baggage = value
+		}
+	}
+	return headers
+}
+
+// StartSpanFromMessage starts a server span for consuming msg,
+// continuing the trace from the headers of msg (see MessageSpanHeaders).
+//
+// When msg has no trace headers, a new top-level server span is started.
+//
+// Consumer calls it automatically for every message, it's exported for the
+// users writing their own sarama.ConsumerGroupHandler.
+func StartSpanFromMessage(ctx context.Context, name string, msg *sarama.ConsumerMessage) (context.Context, *tracing.Span) {
+	return tracing.StartSpanFromHeaders(ctx, name, MessageSpanHeaders(msg))
+}
+
+// tracingHeaders are all the headers set by SetSpanHeaders, in order.
+var tracingHeaders = []string{
+	transport.HeaderTracingTrace,
+	transport.HeaderTracingSpan,
+	transport.HeaderTracingParent,
+	transport.HeaderTracingSampled,
+	transport.HeaderTracingFlags,
+	transport.HeaderTracingTraceParent,
+	transport.HeaderTracingTraceState,
+	transport.HeaderTracingBaggage,
+}
+
+// isTracingHeader returns true if key is one of tracingHeaders,
+// case-insensitively, the same way as MessageSpanHeaders.
+func isTracingHeader(key string) bool {
+	for _, h := range tracingHeaders {
+		if strings.EqualFold(key, h) {
+			return true
+		}
+	}
+	return false
+}
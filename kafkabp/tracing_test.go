@@ -0,0 +1,109 @@
+package kafkabp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// toConsumerMessage converts the headers of a produced message into a consumed
+// message, like they are transferred through kafka.
+func toConsumerMessage(msg *sarama.ProducerMessage) *sarama.ConsumerMessage {
+	cm := &sarama.ConsumerMessage{
+		Topic: msg.Topic,
+	}
+	for i := range msg.Headers {
+		cm.Headers = append(cm.Headers, &msg.Headers[i])
+	}
+	return cm
+}
+
+func TestSpanHeadersRoundTrip(t *testing.T) {
+	_, producerSpan := tracing.StartTopLevelServerSpan(context.Background(), "producer")
+	defer producerSpan.Finish()
+
+	msg := &sarama.ProducerMessage{Topic: "topic"}
+	SetSpanHeaders(msg, producerSpan)
+
+	ctx, span := StartSpanFromMessage(context.Background(), "consumer", toConsumerMessage(msg))
+	defer span.Finish()
+
+	if span.TraceID() != producerSpan.TraceID() {
+		t.Errorf("trace id expected %q, got %q", producerSpan.TraceID(), span.TraceID())
+	}
+	if span.ParentID() != producerSpan.ID() {
+		t.Errorf("parent id expected %q, got %q", producerSpan.ID(), span.ParentID())
+	}
+	if span.Sampled() != producerSpan.Sampled() {
+		t.Errorf("sampled expected %v, got %v", producerSpan.Sampled(), span.Sampled())
+	}
+	if span.SpanType() != tracing.SpanTypeServer {
+		t.Errorf("span type expected %v, got %v", tracing.SpanTypeServer, span.SpanType())
+	}
+	if got := opentracing.SpanFromContext(ctx); got != span {
+		t.Errorf("expected span to be attached to the context, got %v", got)
+	}
+}
+
+func TestSetSpanHeadersReplacesCaseInsensitively(t *testing.T) {
+	_, span := tracing.StartTopLevelServerSpan(context.Background(), "producer")
+	defer span.Finish()
+
+	msg := &sarama.ProducerMessage{
+		Topic: "topic",
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("trace"), Value: []byte("1234")},
+			{Key: []byte("SPAN"), Value: []byte("5678")},
+			{Key: []byte("foo"), Value: []byte("bar")},
+		},
+	}
+	SetSpanHeaders(msg, span)
+
+	counts := make(map[string]int)
+	for _, h := range msg.Headers {
+		counts[strings.ToLower(string(h.Key))]++
+	}
+	for _, key := range []string{"trace", "span", "foo"} {
+		if counts[key] != 1 {
+			t.Errorf("Expected header %q once, got %d", key, counts[key])
+		}
+	}
+	if got := MessageSpanHeaders(toConsumerMessage(msg)).TraceID; got != span.TraceID() {
+		t.Errorf("TraceID expected %q, got %q", span.TraceID(), got)
+	}
+}
+
+func TestMessageSpanHeaders(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("trace"), Value: []byte("1234")},
+			{Key: []byte("SPAN"), Value: []byte("5678")},
+			{Key: []byte("Sampled"), Value: []byte("1")},
+			nil,
+			{Key: []byte("Traceparent"), Value: []byte("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")},
+			{Key: []byte("foo"), Value: []byte("bar")},
+		},
+	}
+	headers := MessageSpanHeaders(msg)
+	if headers.TraceID != "1234" {
+		t.Errorf("TraceID expected %q, got %q", "1234", headers.TraceID)
+	}
+	if headers.SpanID != "5678" {
+		t.Errorf("SpanID expected %q, got %q", "5678", headers.SpanID)
+	}
+	if sampled, ok := headers.ParseSampled(); !ok || !sampled {
+		t.Errorf("Sampled expected true, got %v, %v", sampled, ok)
+	}
+	if headers.TraceParent == "" {
+		t.Error("TraceParent expected to be set")
+	}
+
+	if MessageSpanHeaders(&sarama.ConsumerMessage{}).AnySet() {
+		t.Error("expected no headers set for message without headers")
+	}
+}
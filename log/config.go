@@ -1,11 +1,30 @@
 package log
 
+import (
+	"go.uber.org/zap"
+)
+
 // Config is the confuration struct for the log package.
 //
 // Can be deserialized from YAML.
 type Config struct {
 	// Level is the log level you want to set your service to.
 	Level Level `yaml:"level"`
+
+	// SetSlogDefault sets a SlogHandler as the handler of slog.Default,
+	// so logs from log/slog are also written through the baseplate logger.
+	SetSlogDefault bool `yaml:"setSlogDefault"`
+
+	// Sampling configures the sampling of the logs to protect against noisy
+	// log lines, default to no sampling.
+	Sampling *SamplingConfig `yaml:"sampling"`
+
+	// Redaction configures the redaction of sensitive data in logs and Sentry
+	// events.
+	//
+	// It's applied by InitRedaction (called by baseplate.New),
+	// not by InitFromConfig.
+	Redaction RedactionConfig `yaml:"redaction"`
 }
 
 // InitFromConfig initializes the log package using the given Config and JSON
@@ -15,5 +34,12 @@ func InitFromConfig(cfg Config) {
 		cfg.Level = InfoLevel
 	}
 	level := cfg.Level
-	InitLoggerJSON(level)
+	var sampling *zap.SamplingConfig
+	if cfg.Sampling != nil {
+		sampling = cfg.Sampling.ToZap()
+	}
+	initLoggerJSON(level, sampling)
+	if cfg.SetSlogDefault {
+		SetSlogDefault()
+	}
 }
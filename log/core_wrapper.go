@@ -1,9 +1,11 @@
 package log
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,6 +49,9 @@ func (w wrappedCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 	defer func(start time.Time) {
 		logWriteDurationSeconds.Observe(time.Since(start).Seconds())
 	}(time.Now())
+	if r := globalRedactor.Load(); r != nil {
+		entry.Message = r.RedactString(entry.Message)
+	}
 	return w.Core.Write(entry, wrapFields(fields))
 }
 
@@ -58,7 +63,11 @@ func (w wrappedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore
 }
 
 func wrapFields(fields []zapcore.Field) []zapcore.Field {
+	redactor := globalRedactor.Load()
 	for i, f := range fields {
+		if redactor != nil {
+			f = redactField(redactor, f)
+		}
 		switch f.Type {
 		// To make sure larger int64/uint64 logged will not be treated as float64
 		// and lose precision.
@@ -77,3 +86,52 @@ func wrapFields(fields []zapcore.Field) []zapcore.Field {
 	}
 	return fields
 }
+
+// redactField redacts the string, []byte, fmt.Stringer and error fields.
+//
+// Other fields (e.g. objects) are only redacted by their keys.
+func redactField(r *Redactor, f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f
+	}
+	if r.RedactKey(f.Key) {
+		return zap.String(f.Key, RedactedValue)
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.RedactString(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			if s := r.RedactString(string(b)); s != string(b) {
+				return zap.String(f.Key, s)
+			}
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			if str, ok := safeString(s.String); ok {
+				return zap.String(f.Key, r.RedactString(str))
+			}
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			if msg, ok := safeString(err.Error); ok {
+				if redacted := r.RedactString(msg); redacted != msg {
+					return zap.String(f.Key, redacted)
+				}
+			}
+		}
+	}
+	return f
+}
+
+// safeString calls fn and recovers from panics (e.g. nil pointer receivers),
+// leaving them to be handled by the zap encoder.
+func safeString(fn func() string) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return fn(), true
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default values for DedupWrapperArgs.
+const (
+	DefaultDedupWindow      = time.Minute
+	DefaultDedupMaxMessages = 1000
+)
+
+// DedupWrapperArgs defines the args used in DedupWrapper.
+type DedupWrapperArgs struct {
+	// The window identical messages are collapsed into one.
+	//
+	// If it's <= 0, DefaultDedupWindow will be used.
+	Window time.Duration
+
+	// The max number of distinct messages tracked at the same time.
+	// When it's reached, new messages are passed through without dedup.
+	//
+	// If it's <= 0, DefaultDedupMaxMessages will be used.
+	MaxMessages int
+}
+
+// dedupEntry tracks a message within its window.
+type dedupEntry struct {
+	suppressed int
+	// The context object of the last suppressed call,
+	// used to log the summary.
+	ctx context.Context
+}
+
+// DedupWrapper returns a Wrapper implementation that collapses identical
+// messages within a window.
+//
+// The first occurrence of a message is passed to delegate immediately,
+// the following identical ones within the window are suppressed.
+// When the window ends, if any were suppressed, a summary with the suppressed
+// count is passed to delegate, using the context object of the last suppressed
+// call.
+//
+// It's useful to wrap a Wrapper that could be called repeatedly with the same
+// error, for example a background refresh failing every second:
+//
+//	cfg.Config.Secrets.Logger = log.DedupWrapper(
+//	  cfg.Config.Secrets.Logger, // delegate
+//	  log.DedupWrapperArgs{},
+//	)
+func DedupWrapper(delegate Wrapper, args DedupWrapperArgs) Wrapper {
+	if args.Window <= 0 {
+		args.Window = DefaultDedupWindow
+	}
+	if args.MaxMessages <= 0 {
+		args.MaxMessages = DefaultDedupMaxMessages
+	}
+
+	var lock sync.Mutex
+	entries := make(map[string]*dedupEntry)
+
+	flush := func(msg string) {
+		lock.Lock()
+		entry := entries[msg]
+		delete(entries, msg)
+		lock.Unlock()
+
+		if entry != nil && entry.suppressed > 0 {
+			delegate.Log(entry.ctx, fmt.Sprintf(
+				"%s (suppressed %d identical messages in the last %v)",
+				msg,
+				entry.suppressed,
+				args.Window,
+			))
+		}
+	}
+
+	return func(ctx context.Context, msg string) {
+		lock.Lock()
+		if entry, ok := entries[msg]; ok {
+			entry.suppressed++
+			entry.ctx = ctx
+			lock.Unlock()
+			return
+		}
+		if len(entries) < args.MaxMessages {
+			entries[msg] = new(dedupEntry)
+			time.AfterFunc(args.Window, func() {
+				flush(msg)
+			})
+		}
+		lock.Unlock()
+
+		delegate.Log(ctx, msg)
+	}
+}
@@ -0,0 +1,61 @@
+package log_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+func TestDedupWrapper(t *testing.T) {
+	const window = time.Millisecond * 50
+
+	var lock sync.Mutex
+	var msgs []string
+	delegate := func(_ context.Context, msg string) {
+		lock.Lock()
+		defer lock.Unlock()
+		msgs = append(msgs, msg)
+	}
+	getMsgs := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), msgs...)
+	}
+
+	logger := log.DedupWrapper(delegate, log.DedupWrapperArgs{
+		Window:      window,
+		MaxMessages: 2,
+	})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		logger.Log(ctx, "foo")
+	}
+	logger.Log(ctx, "bar")
+	// Over MaxMessages, passed through.
+	logger.Log(ctx, "fizz")
+	logger.Log(ctx, "fizz")
+
+	want := []string{"foo", "bar", "fizz", "fizz"}
+	if got := getMsgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Before window ends expected %q, got %q", want, got)
+	}
+
+	want = append(want, "foo (suppressed 2 identical messages in the last 50ms)")
+	deadline := time.Now().Add(time.Second)
+	for len(getMsgs()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := getMsgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("After window ends expected %q, got %q", want, got)
+	}
+
+	logger.Log(ctx, "foo")
+	want = append(want, "foo")
+	if got := getMsgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("After window ends expected %q, got %q", want, got)
+	}
+}
@@ -14,4 +14,11 @@
 // instead of creating one to use logger, you should use the global one:
 //
 //	log.Errorw("Something went wrong!", "err", err)
+//
+// For code using log/slog, SlogHandler writes the records through the same zap
+// logger, with the trace ID, span ID and edge context fields added from the
+// context object. Use SetSlogDefault (or Config.SetSlogDefault) to use it for
+// the top level slog functions:
+//
+//	slog.ErrorContext(ctx, "Something went wrong!", "err", err)
 package log
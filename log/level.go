@@ -0,0 +1,195 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	//lint:ignore SA1019 This library is internal only, not actually deprecated
+	"github.com/reddit/baseplate.go/internalv2compat"
+)
+
+// levelState is an immutable snapshot of the levels of the global logger.
+type levelState struct {
+	global zap.AtomicLevel
+	named  map[string]zapcore.Level
+	// The lowest level in named, only valid when named is not empty.
+	minNamed zapcore.Level
+}
+
+// levelRegistry holds the levels of the global logger.
+//
+// Reads are lock-free as they happen on every log,
+// writes are serialized by mu and replace the whole state.
+type levelRegistry struct {
+	mu    sync.Mutex
+	state atomic.Pointer[levelState]
+}
+
+func newLevelRegistry() *levelRegistry {
+	r := new(levelRegistry)
+	r.state.Store(&levelState{
+		global: zap.NewAtomicLevelAt(zapcore.InfoLevel),
+	})
+	return r
+}
+
+var globalLevels = newLevelRegistry()
+
+// update replaces the state with the one returned by fn,
+// fn shall not modify the state passed in.
+func (r *levelRegistry) update(fn func(old *levelState) *levelState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := fn(r.state.Load())
+	state.minNamed = ZapNopLevel
+	for _, level := range state.named {
+		if level < state.minNamed {
+			state.minNamed = level
+		}
+	}
+	r.state.Store(state)
+}
+
+func (r *levelRegistry) setGlobal(level zap.AtomicLevel) {
+	r.update(func(old *levelState) *levelState {
+		return &levelState{
+			global: level,
+			named:  old.named,
+		}
+	})
+}
+
+func (r *levelRegistry) setNamed(name string, level zapcore.Level) {
+	r.update(func(old *levelState) *levelState {
+		named := make(map[string]zapcore.Level, len(old.named)+1)
+		for k, v := range old.named {
+			named[k] = v
+		}
+		named[name] = level
+		return &levelState{
+			global: old.global,
+			named:  named,
+		}
+	})
+}
+
+func (r *levelRegistry) resetNamed(name string) {
+	r.update(func(old *levelState) *levelState {
+		named := make(map[string]zapcore.Level, len(old.named))
+		for k, v := range old.named {
+			if k != name {
+				named[k] = v
+			}
+		}
+		return &levelState{
+			global: old.global,
+			named:  named,
+		}
+	})
+}
+
+// enabled returns true if level is enabled by either the global level or any
+// of the named overrides.
+func (r *levelRegistry) enabled(level zapcore.Level) bool {
+	state := r.state.Load()
+	if state.global.Enabled(level) {
+		return true
+	}
+	return len(state.named) > 0 && state.minNamed.Enabled(level)
+}
+
+// enabledFor returns true if level is enabled for the logger with name.
+//
+// The override of the closest name is used, for example a logger named
+// "foo.bar.baz" uses the override of "foo.bar" over "foo".
+// If there's no override for the name, the global level is used.
+func (r *levelRegistry) enabledFor(name string, level zapcore.Level) bool {
+	state := r.state.Load()
+	if len(state.named) > 0 {
+		for name != "" {
+			if l, ok := state.named[name]; ok {
+				return l.Enabled(level)
+			}
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+	return state.global.Enabled(level)
+}
+
+// levelCore is a zapcore.Core applying the levels in a levelRegistry.
+//
+// The level of the core it wraps shall be permissive enough to allow all the
+// levels of the registry.
+type levelCore struct {
+	zapcore.Core
+
+	levels *levelRegistry
+}
+
+func (c levelCore) Enabled(level zapcore.Level) bool {
+	return c.levels.enabled(level)
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{
+		Core:   c.Core.With(fields),
+		levels: c.levels,
+	}
+}
+
+func (c levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.levels.enabledFor(ent.LoggerName, ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
+
+// GlobalLevel returns the zap.AtomicLevel of the global logger.
+//
+// Changing it changes the level of the global logger and all the loggers
+// derived from it, including the ones attached to context objects.
+//
+// The returned level is replaced by InitLogger, InitLoggerJSON,
+// InitLoggerWithConfig and InitFromConfig, so it shouldn't be held on to.
+func GlobalLevel() zap.AtomicLevel {
+	return globalLevels.state.Load().global
+}
+
+// NamedLevels returns the level overrides of the named loggers.
+func NamedLevels() map[string]zapcore.Level {
+	state := globalLevels.state.Load()
+	named := make(map[string]zapcore.Level, len(state.named))
+	for k, v := range state.named {
+		named[k] = v
+	}
+	return named
+}
+
+// SetNamedLevel overrides the level of the loggers named name
+// (see zap.Logger.Named and Named) and their descendants,
+// for example an override of "redis" applies to "redis" and "redis.pool",
+// but not to "redisbp".
+//
+// The override can be either more or less verbose than the global level.
+func SetNamedLevel(name string, level zapcore.Level) {
+	globalLevels.setNamed(name, level)
+}
+
+// ResetNamedLevel removes the level override set by SetNamedLevel.
+func ResetNamedLevel(name string) {
+	globalLevels.resetNamed(name)
+}
+
+// Named returns the global logger with name added,
+// so its level can be overridden by SetNamedLevel.
+func Named(name string) *zap.SugaredLogger {
+	return internalv2compat.GlobalLogger().Named(name)
+}
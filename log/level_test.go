@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelCore(t *testing.T) {
+	buf := new(bytes.Buffer)
+	levels := newLevelRegistry()
+	logger := zap.New(levelCore{
+		Core:   initCore(buf),
+		levels: levels,
+	})
+
+	for _, c := range []struct {
+		label string
+		setup func()
+		log   func()
+		want  string
+	}{
+		{
+			label: "global",
+			log: func() {
+				logger.Debug("debug")
+				logger.Info("info")
+			},
+			want: `{"level":"info","msg":"info"}`,
+		},
+		{
+			label: "global-changed",
+			setup: func() {
+				levels.state.Load().global.SetLevel(zapcore.WarnLevel)
+			},
+			log: func() {
+				logger.Info("info")
+				logger.Warn("warn")
+			},
+			want: `{"level":"warn","msg":"warn"}`,
+		},
+		{
+			label: "named-more-verbose",
+			setup: func() {
+				levels.setNamed("foo", zapcore.DebugLevel)
+			},
+			log: func() {
+				logger.Info("global")
+				logger.Named("foo").Named("bar").Debug("foo.bar")
+				logger.Named("foobar").Info("foobar")
+			},
+			want: `{"level":"debug","logger":"foo.bar","msg":"foo.bar"}`,
+		},
+		{
+			label: "named-closest",
+			setup: func() {
+				levels.setNamed("foo.bar", zapcore.ErrorLevel)
+			},
+			log: func() {
+				logger.Named("foo").Debug("foo")
+				logger.Named("foo").Named("bar").Warn("foo.bar")
+			},
+			want: `{"level":"debug","logger":"foo","msg":"foo"}`,
+		},
+		{
+			label: "reset",
+			setup: func() {
+				levels.resetNamed("foo")
+				levels.resetNamed("foo.bar")
+			},
+			log: func() {
+				logger.Named("foo").Info("foo")
+				logger.Named("foo").Named("bar").Warn("foo.bar")
+			},
+			want: `{"level":"warn","logger":"foo.bar","msg":"foo.bar"}`,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			buf.Reset()
+			if c.setup != nil {
+				c.setup()
+			}
+			c.log()
+			if got := strings.TrimSpace(buf.String()); got != c.want {
+				t.Errorf("Expected log %s, got %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestLevelRegistryEnabled(t *testing.T) {
+	levels := newLevelRegistry()
+	if levels.enabled(zapcore.DebugLevel) {
+		t.Error("Expected debug to be disabled by default")
+	}
+	levels.setNamed("foo", zapcore.DebugLevel)
+	if !levels.enabled(zapcore.DebugLevel) {
+		t.Error("Expected debug to be enabled with a named debug override")
+	}
+	levels.resetNamed("foo")
+	if levels.enabled(zapcore.DebugLevel) {
+		t.Error("Expected debug to be disabled after reset")
+	}
+}
@@ -66,6 +66,10 @@ func InitLogger(logLevel Level) {
 // The JSON format is also compatible with logdna's ingestion format:
 // https://docs.logdna.com/docs/ingestion
 func InitLoggerJSON(logLevel Level) {
+	initLoggerJSON(logLevel, nil)
+}
+
+func initLoggerJSON(logLevel Level, sampling *zap.SamplingConfig) {
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(logLevel.ToZapLevel())
 	config.Encoding = "json"
@@ -77,7 +81,7 @@ func InitLoggerJSON(logLevel Level) {
 	config.EncoderConfig.MessageKey = "message"
 	config.EncoderConfig.TimeKey = "timestamp"
 
-	if err := InitLoggerWithConfig(logLevel, config); err != nil {
+	if err := initLoggerWithConfig(logLevel, config, sampling); err != nil {
 		// shouldn't happen, but just in case
 		panic(err)
 	}
@@ -92,15 +96,44 @@ func InitLoggerJSON(logLevel Level) {
 // fields to strings, to prevent the loss of precision by json log ingester.
 // As a result, some of the cfg might get lost during this wrapping, namely
 // OutputPaths and ErrorOutputPaths.
+//
+// cfg.Level becomes the GlobalLevel, and defaults to logLevel if unset.
+// The named logger overrides set by SetNamedLevel are kept.
+//
+// cfg.Sampling is ignored, use Config.Sampling with InitFromConfig to enable
+// sampling instead.
 func InitLoggerWithConfig(logLevel Level, cfg zap.Config) error {
+	return initLoggerWithConfig(logLevel, cfg, nil)
+}
+
+// initLoggerWithConfig is InitLoggerWithConfig with sampling applied per
+// second (SamplingTick) after the level checks when it's non-nil.
+func initLoggerWithConfig(logLevel Level, cfg zap.Config, sampling *zap.SamplingConfig) error {
 	if logLevel == NopLevel {
 		internalv2compat.SetGlobalLogger(zap.NewNop().Sugar())
 		return nil
 	}
+	if cfg.Level == (zap.AtomicLevel{}) {
+		cfg.Level = zap.NewAtomicLevelAt(logLevel.ToZapLevel())
+	}
+	globalLevels.setGlobal(cfg.Level)
+	// The actual levels are checked by levelCore,
+	// so the underlying core needs to allow all of them.
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	// Sampling is applied by us on top of wrappedCore,
+	// as wrappedCore.Check bypasses the Check of the cores it wraps.
+	cfg.Sampling = nil
 	l, err := cfg.Build(
 		zap.AddCallerSkip(1),
 		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-			return wrappedCore{Core: core}
+			core = wrappedCore{Core: core}
+			if sampling != nil {
+				core = newSampler(core, sampling)
+			}
+			return levelCore{
+				Core:   core,
+				levels: globalLevels,
+			}
 		}),
 	)
 	if err != nil {
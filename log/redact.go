@@ -0,0 +1,227 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RedactedValue is the value replacing the redacted values.
+const RedactedValue = "[REDACTED]"
+
+// MinSecretValueLength is the min length of the secret values masked by
+// SetSecretValues, to avoid masking short and common values.
+const MinSecretValueLength = 6
+
+// Built-in value detectors to be used in RedactionConfig.Detectors.
+const (
+	// DetectorToken detects bearer tokens and JWTs.
+	DetectorToken = "token"
+
+	// DetectorEmail detects email addresses.
+	DetectorEmail = "email"
+)
+
+var builtinDetectors = map[string][]*regexp.Regexp{
+	DetectorToken: {
+		regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`),
+		regexp.MustCompile(`\beyJ[a-zA-Z0-9_-]+\.eyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]*`),
+	},
+	DetectorEmail: {
+		regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+	},
+}
+
+// DefaultRedactionKeys are the default key patterns used when
+// RedactionConfig.Keys is empty.
+var DefaultRedactionKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"cookie",
+	"api[-_]?key",
+}
+
+// RedactionConfig is the configuration of the redaction of logs and Sentry
+// events.
+//
+// Can be deserialized from YAML.
+type RedactionConfig struct {
+	// Enabled turns on redaction.
+	Enabled bool `yaml:"enabled"`
+
+	// Regular expressions matched against the keys of log fields,
+	// Sentry tags, extra data and request headers, case-insensitive.
+	// The whole value of a matched key is redacted.
+	//
+	// If it's empty, DefaultRedactionKeys will be used.
+	Keys []string `yaml:"keys"`
+
+	// Names of the built-in value detectors (DetectorToken, DetectorEmail)
+	// applied to the string values, only the detected parts are redacted.
+	Detectors []string `yaml:"detectors"`
+
+	// Additional regular expressions applied to the string values,
+	// only the matched parts are redacted.
+	ValuePatterns []string `yaml:"valuePatterns"`
+}
+
+// Redactor redacts sensitive data out of keys and values.
+//
+// In addition to the patterns it's created with, it also masks the values
+// registered via SetSecretValues.
+type Redactor struct {
+	keys   *regexp.Regexp
+	values []*regexp.Regexp
+}
+
+// NewRedactor creates a Redactor from cfg.
+//
+// It returns an error if any of the patterns or detectors is invalid.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	keys := cfg.Keys
+	if len(keys) == 0 {
+		keys = DefaultRedactionKeys
+	}
+	var r Redactor
+	var err error
+	r.keys, err = regexp.Compile(`(?i)(?:` + strings.Join(keys, `)|(?:`) + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("log.NewRedactor: invalid keys %q: %w", keys, err)
+	}
+	for _, name := range cfg.Detectors {
+		detectors, ok := builtinDetectors[name]
+		if !ok {
+			return nil, fmt.Errorf("log.NewRedactor: unknown detector %q", name)
+		}
+		r.values = append(r.values, detectors...)
+	}
+	for _, pattern := range cfg.ValuePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("log.NewRedactor: invalid value pattern %q: %w", pattern, err)
+		}
+		r.values = append(r.values, re)
+	}
+	return &r, nil
+}
+
+// RedactKey returns true if the value of key shall be redacted as a whole.
+func (r *Redactor) RedactKey(key string) bool {
+	return r.keys.MatchString(key)
+}
+
+// RedactString returns s with the detected sensitive parts redacted.
+func (r *Redactor) RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+	s = globalSecretValues.replace(s)
+	for _, re := range r.values {
+		s = re.ReplaceAllLiteralString(s, RedactedValue)
+	}
+	return s
+}
+
+// Redact returns the redacted value of key and value.
+func (r *Redactor) Redact(key, value string) string {
+	if r.RedactKey(key) {
+		return RedactedValue
+	}
+	return r.RedactString(value)
+}
+
+var globalRedactor atomic.Pointer[Redactor]
+
+// InitRedaction sets the Redactor used by the global logger and Sentry.
+//
+// It's called by baseplate.New with the Redaction of the log config.
+// If cfg.Enabled is false, redaction is turned off.
+func InitRedaction(cfg RedactionConfig) error {
+	if !cfg.Enabled {
+		SetRedactor(nil)
+		return nil
+	}
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	SetRedactor(r)
+	return nil
+}
+
+// SetRedactor sets the Redactor used by the global logger and Sentry.
+//
+// Set it to nil to turn off redaction.
+func SetRedactor(r *Redactor) {
+	globalRedactor.Store(r)
+}
+
+// secretValues holds the secret values to be masked, by their sources.
+type secretValues struct {
+	lock     sync.Mutex
+	sources  map[string][]string
+	replacer atomic.Pointer[strings.Replacer]
+}
+
+var globalSecretValues = &secretValues{
+	sources: make(map[string][]string),
+}
+
+func (s *secretValues) set(source string, values []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(values) == 0 {
+		delete(s.sources, source)
+	} else {
+		s.sources[source] = values
+	}
+
+	var all []string
+	for _, values := range s.sources {
+		for _, v := range values {
+			if len(v) >= MinSecretValueLength {
+				all = append(all, v)
+			}
+		}
+	}
+	if len(all) == 0 {
+		s.replacer.Store(nil)
+		return
+	}
+	// Longer values first, so a value containing another one is masked as a
+	// whole.
+	sort.Slice(all, func(i, j int) bool {
+		return len(all[i]) > len(all[j])
+	})
+	pairs := make([]string, 0, len(all)*2)
+	for _, v := range all {
+		pairs = append(pairs, v, RedactedValue)
+	}
+	s.replacer.Store(strings.NewReplacer(pairs...))
+}
+
+func (s *secretValues) replace(v string) string {
+	if replacer := s.replacer.Load(); replacer != nil {
+		return replacer.Replace(v)
+	}
+	return v
+}
+
+// SetSecretValues sets the secret values from source to be masked in logs and
+// Sentry events when redaction is enabled, replacing the previous values from
+// the same source.
+//
+// Values shorter than MinSecretValueLength are ignored.
+// Set nil values to remove the source.
+//
+// secrets.Store calls it automatically with all the secrets it loads.
+func SetSecretValues(source string, values []string) {
+	globalSecretValues.set(source, values)
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap"
+)
+
+func setRedactor(t *testing.T, cfg RedactionConfig) {
+	t.Helper()
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRedactor(r)
+	t.Cleanup(func() {
+		SetRedactor(nil)
+	})
+}
+
+func TestNewRedactorErrors(t *testing.T) {
+	for _, cfg := range []RedactionConfig{
+		{Keys: []string{"("}},
+		{Detectors: []string{"foo"}},
+		{ValuePatterns: []string{"("}},
+	} {
+		if _, err := NewRedactor(cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	SetSecretValues("test", []string{"hunter2hunter2", "short"})
+	t.Cleanup(func() {
+		SetSecretValues("test", nil)
+	})
+	r, err := NewRedactor(RedactionConfig{
+		Detectors:     []string{DetectorToken, DetectorEmail},
+		ValuePatterns: []string{`\d{3}-\d{4}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		key, value, want string
+	}{
+		{key: "X-API-Key", value: "foo", want: RedactedValue},
+		{key: "db_password", value: "foo", want: RedactedValue},
+		{key: "Authorization", value: "Bearer abc", want: RedactedValue},
+		{key: "msg", value: "header Bearer abc.def-123 sent", want: "header [REDACTED] sent"},
+		{key: "msg", value: "jwt eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig", want: "jwt [REDACTED]"},
+		{key: "msg", value: "user foo.bar+baz@example.com failed", want: "user [REDACTED] failed"},
+		{key: "msg", value: "call 555-1234", want: "call [REDACTED]"},
+		{key: "msg", value: "secret is hunter2hunter2!", want: "secret is [REDACTED]!"},
+		{key: "msg", value: "short is too short to mask", want: "short is too short to mask"},
+		{key: "msg", value: "nothing to see", want: "nothing to see"},
+	} {
+		if got := r.Redact(c.key, c.value); got != c.want {
+			t.Errorf("Redact(%q, %q) got %q, want %q", c.key, c.value, got, c.want)
+		}
+	}
+}
+
+type testStringer string
+
+func (s testStringer) String() string {
+	return string(s)
+}
+
+func TestRedactCore(t *testing.T) {
+	setRedactor(t, RedactionConfig{Detectors: []string{DetectorEmail}})
+
+	buf := new(bytes.Buffer)
+	logger := zap.New(wrappedCore{Core: initCore(buf)}).With(zap.String("token", "foo"))
+	var nilStringer *testStringer
+	logger.Info(
+		"login foo@example.com",
+		zap.String("user", "foo@example.com"),
+		zap.ByteString("raw", []byte("foo@example.com")),
+		zap.Stringer("stringer", testStringer("foo@example.com")),
+		zap.Stringer("nil", nilStringer),
+		zap.Error(errors.New("foo@example.com not found")),
+		zap.Int("password", 1234),
+	)
+
+	got := buf.String()
+	if strings.Contains(got, "foo@example.com") || strings.Contains(got, "1234") {
+		t.Errorf("Expected sensitive data to be redacted, got %s", got)
+	}
+	for _, want := range []string{
+		`"msg":"login [REDACTED]"`,
+		`"token":"[REDACTED]"`,
+		`"user":"[REDACTED]"`,
+		`"raw":"[REDACTED]"`,
+		`"stringer":"[REDACTED]"`,
+		`"error":"[REDACTED] not found"`,
+		`"password":"[REDACTED]"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in log, got %s", want, got)
+		}
+	}
+}
+
+func TestSentryBeforeSendRedact(t *testing.T) {
+	event := &sentry.Event{
+		Message:   "foo@example.com",
+		Exception: []sentry.Exception{{Value: "foo@example.com"}},
+		Tags:      map[string]string{"token": "foo", "user": "foo@example.com", "ok": "bar"},
+		Extra:     map[string]interface{}{"secret": 1, "user": "foo@example.com"},
+		Request: &sentry.Request{
+			Cookies: "session=foo",
+			Headers: map[string]string{"Authorization": "foo", "Accept": "*/*"},
+		},
+	}
+	if got := SentryBeforeSendRedact(event, nil); got.Tags["token"] != "foo" {
+		t.Errorf("Expected no redaction when disabled, got %+v", got.Tags)
+	}
+
+	setRedactor(t, RedactionConfig{Detectors: []string{DetectorEmail}})
+	event = SentryBeforeSendRedact(event, nil)
+	for label, got := range map[string]interface{}{
+		"message":       event.Message,
+		"exception":     event.Exception[0].Value,
+		"tag-key":       event.Tags["token"],
+		"tag-value":     event.Tags["user"],
+		"extra-key":     event.Extra["secret"],
+		"extra-value":   event.Extra["user"],
+		"cookies":       event.Request.Cookies,
+		"authorization": event.Request.Headers["Authorization"],
+	} {
+		if got != RedactedValue {
+			t.Errorf("%s: expected %q, got %q", label, RedactedValue, got)
+		}
+	}
+	if event.Tags["ok"] != "bar" || event.Request.Headers["Accept"] != "*/*" {
+		t.Errorf("Expected other values to be kept, got %+v %+v", event.Tags, event.Request.Headers)
+	}
+	if SentryBeforeSendRedact(nil, nil) != nil {
+		t.Error("Expected nil event to stay nil")
+	}
+}
+
+func TestInitRedaction(t *testing.T) {
+	t.Cleanup(func() {
+		SetRedactor(nil)
+	})
+	if err := InitRedaction(RedactionConfig{Enabled: true, Detectors: []string{"foo"}}); err == nil {
+		t.Error("Expected error for invalid config")
+	}
+	if err := InitRedaction(RedactionConfig{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if globalRedactor.Load() == nil {
+		t.Error("Expected redactor to be set")
+	}
+	if err := InitRedaction(RedactionConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if globalRedactor.Load() != nil {
+		t.Error("Expected redactor to be unset")
+	}
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+// SamplingTick is the interval SamplingConfig applies to.
+const SamplingTick = time.Second
+
+var logSampledDroppedTotal = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "baseplate_log_sampled_dropped_total",
+		Help: "Total number of logs dropped by sampling",
+	},
+	[]string{"level"},
+)
+
+// SamplingConfig is the configuration of log sampling.
+//
+// Logs are sampled per level and message every second (SamplingTick):
+// the first Initial logs are kept, then only every Thereafter-th log is kept.
+// The fields of the logs are not considered,
+// so the message should be constant for sampling to be effective:
+//
+//	// good
+//	log.C(ctx).Errorw("Failed to get user", "err", err)
+//	// bad
+//	log.C(ctx).Errorf("Failed to get user: %v", err)
+//
+// Can be deserialized from YAML.
+type SamplingConfig struct {
+	// The number of logs with the same level and message kept every second
+	// before sampling kicks in.
+	Initial int `yaml:"initial"`
+
+	// After Initial, every Thereafter-th log with the same level and message
+	// is kept within the same second.
+	//
+	// If it's <= 0, all of them are dropped.
+	Thereafter int `yaml:"thereafter"`
+}
+
+// ToZap converts SamplingConfig into zap.SamplingConfig.
+//
+// The dropped logs are counted by baseplate_log_sampled_dropped_total
+// prometheus counter.
+func (c SamplingConfig) ToZap() *zap.SamplingConfig {
+	thereafter := c.Thereafter
+	if thereafter < 0 {
+		thereafter = 0
+	}
+	return &zap.SamplingConfig{
+		Initial:    c.Initial,
+		Thereafter: thereafter,
+		Hook: func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped != 0 {
+				logSampledDroppedTotal.WithLabelValues(ent.Level.String()).Inc()
+			}
+		},
+	}
+}
+
+// newSampler wraps core with sampling configured by cfg.
+func newSampler(core zapcore.Core, cfg *zap.SamplingConfig) zapcore.Core {
+	var opts []zapcore.SamplerOption
+	if cfg.Hook != nil {
+		opts = append(opts, zapcore.SamplerHook(cfg.Hook))
+	}
+	return zapcore.NewSamplerWithOptions(
+		core,
+		SamplingTick,
+		cfg.Initial,
+		cfg.Thereafter,
+		opts...,
+	)
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/reddit/baseplate.go/internalv2compat"
+)
+
+func TestSampling(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sampling := SamplingConfig{
+		Initial:    2,
+		Thereafter: 3,
+	}.ToZap()
+	logger := zap.New(newSampler(wrappedCore{Core: initCore(buf)}, sampling))
+
+	dropped := logSampledDroppedTotal.WithLabelValues("error")
+	before := testutil.ToFloat64(dropped)
+	for i := 0; i < 8; i++ {
+		logger.Error("foo")
+	}
+	logger.Error("bar")
+
+	// 1, 2 by Initial, then 5, 8 by Thereafter.
+	if got, want := strings.Count(buf.String(), `"msg":"foo"`), 4; got != want {
+		t.Errorf("Expected %d foo logs, got %d:\n%s", want, got, buf.String())
+	}
+	if got, want := strings.Count(buf.String(), `"msg":"bar"`), 1; got != want {
+		t.Errorf("Expected %d bar logs, got %d:\n%s", want, got, buf.String())
+	}
+	if got, want := testutil.ToFloat64(dropped)-before, 4.0; got != want {
+		t.Errorf("Expected %v dropped, got %v", want, got)
+	}
+}
+
+func TestSamplingNegativeThereafter(t *testing.T) {
+	if got := (SamplingConfig{Thereafter: -1}).ToZap().Thereafter; got != 0 {
+		t.Errorf("Expected Thereafter to be 0, got %d", got)
+	}
+}
+
+func TestInitLoggerWithConfigIgnoresZapSampling(t *testing.T) {
+	prevLogger := internalv2compat.GlobalLogger()
+	prevLevel := GlobalLevel()
+	t.Cleanup(func() {
+		internalv2compat.SetGlobalLogger(prevLogger)
+		globalLevels.setGlobal(prevLevel)
+	})
+
+	path := filepath.Join(t.TempDir(), "log")
+	// zap.NewProductionConfig enables sampling with Initial and Thereafter of
+	// 100 by default.
+	cfg := zap.NewProductionConfig()
+	cfg.OutputPaths = []string{path}
+	if err := InitLoggerWithConfig(InfoLevel, cfg); err != nil {
+		t.Fatal(err)
+	}
+	const n = 150
+	for i := 0; i < n; i++ {
+		Info("foo")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(content), `"msg":"foo"`); got != n {
+		t.Errorf("Expected %d foo logs, got %d", n, got)
+	}
+}
@@ -94,7 +94,8 @@ func InitSentry(cfg SentryConfig) (io.Closer, error) {
 				}
 			}
 		}
-		if cfg.BeforeSend != nil {
+		event = SentryBeforeSendRedact(event, hint)
+		if cfg.BeforeSend != nil && event != nil {
 			return cfg.BeforeSend(event, hint)
 		}
 		return event
@@ -156,6 +157,58 @@ func SentryBeforeSendSwapExceptionTypeAndValue(event *sentry.Event, hint *sentry
 	return event
 }
 
+// SentryBeforeSendRedact is a sentry.BeforeSend implementation that redacts
+// the event using the Redactor set by InitRedaction or SetRedactor.
+//
+// It redacts the message, exception values, tags, extra data, user email,
+// request and breadcrumb messages.
+// It does nothing when redaction is not enabled.
+//
+// It's already used by InitSentry,
+// and only needs to be used directly when calling sentry.Init directly.
+func SentryBeforeSendRedact(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	r := globalRedactor.Load()
+	if event == nil || r == nil {
+		return event
+	}
+
+	event.Message = r.RedactString(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = r.RedactString(event.Exception[i].Value)
+	}
+	for k, v := range event.Tags {
+		event.Tags[k] = r.Redact(k, v)
+	}
+	for k, v := range event.Extra {
+		if r.RedactKey(k) {
+			event.Extra[k] = RedactedValue
+		} else if str, ok := v.(string); ok {
+			event.Extra[k] = r.RedactString(str)
+		}
+	}
+	event.User.Email = r.RedactString(event.User.Email)
+	if req := event.Request; req != nil {
+		req.URL = r.RedactString(req.URL)
+		req.QueryString = r.RedactString(req.QueryString)
+		req.Data = r.RedactString(req.Data)
+		if req.Cookies != "" {
+			req.Cookies = RedactedValue
+		}
+		for k, v := range req.Headers {
+			req.Headers[k] = r.Redact(k, v)
+		}
+		for k, v := range req.Env {
+			req.Env[k] = r.Redact(k, v)
+		}
+	}
+	for _, b := range event.Breadcrumbs {
+		if b != nil {
+			b.Message = r.RedactString(b.Message)
+		}
+	}
+	return event
+}
+
 type closer time.Duration
 
 func (c closer) Close() error {
@@ -0,0 +1,248 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ContextAttrsFunc returns the attributes to be added to the records logged
+// by SlogHandler from the context object.
+type ContextAttrsFunc func(ctx context.Context) []slog.Attr
+
+var contextAttrs struct {
+	sync.RWMutex
+	funcs []ContextAttrsFunc
+}
+
+// RegisterContextAttrs registers fn to add attributes from the context object
+// to all the records logged by SlogHandler.
+//
+// It's usually called in the init function of the packages putting the values
+// into the context object, for example the tracing package registers one to
+// add the span ID.
+func RegisterContextAttrs(fn ContextAttrsFunc) {
+	contextAttrs.Lock()
+	defer contextAttrs.Unlock()
+	contextAttrs.funcs = append(contextAttrs.funcs, fn)
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	contextAttrs.RLock()
+	defer contextAttrs.RUnlock()
+	var attrs []slog.Attr
+	for _, fn := range contextAttrs.funcs {
+		attrs = append(attrs, fn(ctx)...)
+	}
+	return attrs
+}
+
+// SlogHandler is a slog.Handler that writes the records through the zap core
+// of the baseplate logger.
+//
+// The attributes returned by the functions registered via
+// RegisterContextAttrs, for example the span ID and the edge context fields,
+// are added to every record logged with a context.
+// When created with a nil core, the fields attached to the logger in the
+// context object (see Attach and C), for example the trace ID, are also added,
+// as the record is written through that logger's core.
+// When created with a non-nil core, those fields are not added.
+type SlogHandler struct {
+	core   zapcore.Core
+	fields []zapcore.Field
+	// The groups not yet added to fields via zap.Namespace.
+	groups []string
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler creates a new SlogHandler writing to core.
+//
+// If core is nil, the core of the logger from the context object (see C) is
+// used for every record, which falls back to the global logger.
+// This is the recommended way to use it,
+// as it's the only way to get the fields attached via Attach, like the trace
+// ID, added to the records.
+func NewSlogHandler(core zapcore.Core) *SlogHandler {
+	return &SlogHandler{core: core}
+}
+
+// SetSlogDefault sets a SlogHandler with nil core as the handler of
+// slog.Default, so the top level slog functions are logged through the
+// baseplate logger.
+//
+// Note that slog.SetDefault also redirects the output of the standard log
+// package.
+func SetSlogDefault() {
+	slog.SetDefault(slog.New(NewSlogHandler(nil)))
+}
+
+func (h *SlogHandler) baseCore(ctx context.Context) zapcore.Core {
+	if h.core != nil {
+		return h.core
+	}
+	return C(ctx).Desugar().Core()
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return h.baseCore(ctx).Enabled(slogToZapLevel(level))
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	core := h.baseCore(ctx)
+	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+		var fields []zapcore.Field
+		for _, attr := range attrs {
+			fields = appendSlogAttr(fields, attr)
+		}
+		core = core.With(fields)
+	}
+
+	entry := zapcore.Entry{
+		Level:   slogToZapLevel(record.Level),
+		Time:    record.Time,
+		Message: record.Message,
+	}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+	ce := core.Check(entry, nil)
+	if ce == nil {
+		return nil
+	}
+
+	fields := make([]zapcore.Field, 0, len(h.fields)+len(h.groups)+record.NumAttrs())
+	fields = append(fields, h.fields...)
+	if record.NumAttrs() > 0 {
+		for _, group := range h.groups {
+			fields = append(fields, zap.Namespace(group))
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			fields = appendSlogAttr(fields, attr)
+			return true
+		})
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]zapcore.Field, 0, len(h.fields)+len(h.groups)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, group := range h.groups {
+		fields = append(fields, zap.Namespace(group))
+	}
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, attr)
+	}
+	return &SlogHandler{
+		core:   h.core,
+		fields: fields,
+	}
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]string, 0, len(h.groups)+1)
+	groups = append(groups, h.groups...)
+	return &SlogHandler{
+		core:   h.core,
+		fields: h.fields,
+		groups: append(groups, name),
+	}
+}
+
+func slogToZapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+// appendSlogAttr converts attr into zap fields and appends them to fields,
+// following the rules of slog.Handler:
+//
+// - Attrs with empty key and value are ignored.
+//
+// - LogValuers are resolved.
+//
+// - Groups with empty key are inlined, and empty groups are ignored.
+func appendSlogAttr(fields []zapcore.Field, attr slog.Attr) []zapcore.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	value := attr.Value
+	switch value.Kind() {
+	case slog.KindGroup:
+		attrs := value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			for _, a := range attrs {
+				fields = appendSlogAttr(fields, a)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(attr.Key, slogGroup(attrs)))
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, value.Time()))
+	}
+
+	if err, ok := value.Any().(error); ok {
+		return append(fields, zap.NamedError(attr.Key, err))
+	}
+	return append(fields, zap.Any(attr.Key, value.Any()))
+}
+
+// slogGroup is a group of slog attrs to be logged as a zap object.
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, attr := range g {
+		for _, f := range appendSlogAttr(nil, attr) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
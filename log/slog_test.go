@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type slogTestContextKeyType struct{}
+
+var slogTestContextKey slogTestContextKeyType
+
+func init() {
+	RegisterContextAttrs(func(ctx context.Context) []slog.Attr {
+		if v, ok := ctx.Value(slogTestContextKey).(string); ok {
+			return []slog.Attr{slog.String("edge", v)}
+		}
+		return nil
+	})
+}
+
+type slogTestValuer struct{}
+
+func (slogTestValuer) LogValue() slog.Value {
+	return slog.StringValue("resolved")
+}
+
+func TestSlogHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	ctx := context.WithValue(
+		context.Background(),
+		contextKey,
+		zap.New(wrappedCore{Core: initCore(buf)}).Sugar().With(zap.String(traceIDKey, "12345")),
+	)
+	ctx = context.WithValue(ctx, slogTestContextKey, "value")
+	logger := slog.New(NewSlogHandler(nil))
+
+	for _, c := range []struct {
+		label string
+		log   func()
+		want  string
+	}{
+		{
+			label: "context",
+			log: func() {
+				logger.InfoContext(ctx, "msg", "int64", int64(1234), "valuer", slogTestValuer{})
+			},
+			want: `{"level":"info","msg":"msg","traceID":"12345","edge":"value","int64":"1234","valuer":"resolved"}`,
+		},
+		{
+			label: "global-logger",
+			log: func() {
+				logger.Info("msg")
+			},
+			want: "",
+		},
+		{
+			label: "groups",
+			log: func() {
+				logger.With("a", 1).WithGroup("g").With("b", true).WithGroup("h").ErrorContext(
+					ctx,
+					"msg",
+					"c", time.Second,
+					slog.Group("d", "e", "f"),
+					slog.Group("", "inline", "yes"),
+					slog.Group("empty"),
+				)
+			},
+			want: `{"level":"error","msg":"msg","traceID":"12345","edge":"value","a":"1","g":{"b":true,"h":{"c":"1s","d":{"e":"f"},"inline":"yes"}}}`,
+		},
+		{
+			label: "empty-group",
+			log: func() {
+				logger.WithGroup("g").WarnContext(ctx, "msg")
+			},
+			want: `{"level":"warn","msg":"msg","traceID":"12345","edge":"value"}`,
+		},
+		{
+			label: "error",
+			log: func() {
+				logger.DebugContext(ctx, "msg", "err", errors.New("foo"))
+			},
+			want: `{"level":"debug","msg":"msg","traceID":"12345","edge":"value","err":"foo"}`,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			buf.Reset()
+			c.log()
+			if got := strings.TrimSpace(buf.String()); got != c.want {
+				t.Errorf("Expected log line %s, got %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSlogHandlerCore(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewSlogHandler(initCore(buf)))
+
+	logger.Info("msg", "foo", "bar")
+	want := `{"level":"info","msg":"msg","foo":"bar"}`
+	if got := strings.TrimSpace(buf.String()); got != want {
+		t.Errorf("Expected log line %s, got %s", want, got)
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	core := initCore(new(bytes.Buffer))
+	handler := NewSlogHandler(zap.New(core).WithOptions(zap.IncreaseLevel(zap.WarnLevel)).Core())
+	for level, want := range map[slog.Level]bool{
+		slog.LevelDebug:     false,
+		slog.LevelInfo:      false,
+		slog.LevelWarn:      true,
+		slog.LevelError:     true,
+		slog.LevelError + 4: true,
+	} {
+		if got := handler.Enabled(context.Background(), level); got != want {
+			t.Errorf("Enabled(%v) got %v, want %v", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package ratelimitbp provides token bucket rate limiting keyed by the
+// identity of the caller, to be used by servers.
+//
+// There are 2 Limiter implementations:
+//
+// - LocalLimiter keeps the token buckets in memory, so the limit applies to
+// each instance of your service separately.
+//
+// - RedisLimiter keeps the token buckets in redis (through redisx.Sync),
+// so the limit is shared by all the instances of your service.
+//
+// The key of the token bucket is extracted from the request by a KeyFunc,
+// for example the client name set by thriftbp.SetClientName (ClientNameKey),
+// a baseplate header propagated by headerbp (HeaderbpKey), or any protocol
+// header (HeaderKey).
+//
+// Please use httpbp.RateLimit, thriftbp.RateLimit and
+// grpcbp.RateLimitInterceptorUnary/RateLimitInterceptorStreaming to apply a
+// Limiter to your servers.
+package ratelimitbp
@@ -0,0 +1,70 @@
+package ratelimitbp
+
+import (
+	"context"
+
+	"github.com/reddit/baseplate.go/headerbp"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+// Headers is the protocol specific header lookup provided by the middlewares
+// to KeyFunc.
+//
+// http.Header implements this interface.
+type Headers interface {
+	// Get returns the value of the header, or empty string if not found.
+	Get(key string) string
+}
+
+// HeadersFunc is a function implementing Headers.
+type HeadersFunc func(key string) string
+
+// Get implements Headers.
+func (f HeadersFunc) Get(key string) string {
+	return f(key)
+}
+
+// KeyFunc extracts the key of the token bucket, usually the identity of the
+// caller, from the request.
+type KeyFunc func(ctx context.Context, headers Headers) string
+
+// HeaderKey returns a KeyFunc that uses the value of the protocol header with
+// the given name (HTTP header, thrift THeader or gRPC metadata) as the key.
+func HeaderKey(name string) KeyFunc {
+	return func(_ context.Context, headers Headers) string {
+		return headers.Get(name)
+	}
+}
+
+// ClientNameKey returns a KeyFunc that uses the client name as the key.
+//
+// The client name is the "User-Agent" (transport.HeaderUserAgent) header,
+// which is set by thriftbp.SetClientName on thrift clients.
+func ClientNameKey() KeyFunc {
+	return HeaderKey(transport.HeaderUserAgent)
+}
+
+// HeaderbpKey returns a KeyFunc that uses the value of the baseplate header
+// with the given name propagated by headerbp as the key.
+//
+// The headerbp server middleware must be applied before the rate limit
+// middleware for it to work.
+func HeaderbpKey(name string) KeyFunc {
+	return func(ctx context.Context, _ Headers) string {
+		v, _ := headerbp.GetHeader(ctx, name)
+		return v
+	}
+}
+
+// FirstKey returns a KeyFunc that returns the first non-empty key returned by
+// the given KeyFuncs.
+func FirstKey(funcs ...KeyFunc) KeyFunc {
+	return func(ctx context.Context, headers Headers) string {
+		for _, f := range funcs {
+			if key := f(ctx, headers); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,101 @@
+package ratelimitbp
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type localBucket struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// LocalLimiter is a Limiter keeping the token buckets in memory.
+//
+// At most MaxBuckets buckets are kept, when a new bucket is needed at the limit
+// the least recently used one is discarded.
+//
+// It's safe for concurrent use.
+type LocalLimiter struct {
+	cfg Config
+
+	// For tests.
+	now func() time.Time
+
+	lock    sync.Mutex
+	buckets map[string]*list.Element
+	// The buckets ordered by lastSeen, the most recently used in the front.
+	lru *list.List
+}
+
+// NewLocal creates a new LocalLimiter with the given config.
+func NewLocal(cfg Config) (*LocalLimiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	return &LocalLimiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// Allow implements Limiter.
+//
+// It never returns an error.
+func (l *LocalLimiter) Allow(_ context.Context, key string) (Result, error) {
+	now := l.now()
+
+	l.lock.Lock()
+	l.evictLocked(now)
+	var b *localBucket
+	if e, ok := l.buckets[key]; ok {
+		b = e.Value.(*localBucket)
+		l.lru.MoveToFront(e)
+	} else {
+		if l.lru.Len() >= l.cfg.MaxBuckets {
+			l.removeLocked(l.lru.Back())
+		}
+		b = &localBucket{
+			key:     key,
+			limiter: rate.NewLimiter(rate.Limit(l.cfg.Rate), l.cfg.Burst),
+		}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+	b.lastSeen = now
+	l.lock.Unlock()
+
+	var result Result
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		result.RetryAfter = delay
+	} else {
+		result.Allowed = true
+	}
+	recordResult(l.cfg.Name, result, nil)
+	return result, nil
+}
+
+// evictLocked discards the buckets idle for at least IdleTimeout.
+func (l *LocalLimiter) evictLocked(now time.Time) {
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		if now.Sub(e.Value.(*localBucket).lastSeen) < l.cfg.IdleTimeout {
+			return
+		}
+		l.removeLocked(e)
+	}
+}
+
+func (l *LocalLimiter) removeLocked(e *list.Element) {
+	delete(l.buckets, l.lru.Remove(e).(*localBucket).key)
+}
+
+var _ Limiter = (*LocalLimiter)(nil)
@@ -0,0 +1,139 @@
+package ratelimitbp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+const (
+	nameLabel    = "limiter"
+	allowedLabel = "allowed"
+)
+
+var (
+	requestsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimitbp_requests_total",
+		Help: "The number of requests checked by the rate limiter",
+	}, []string{
+		nameLabel,
+		allowedLabel,
+	})
+
+	errorsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimitbp_errors_total",
+		Help: "The number of errors from the rate limiter, requests are allowed on errors",
+	}, []string{
+		nameLabel,
+	})
+)
+
+// Default values used by Config.
+const (
+	DefaultIdleTimeout = 10 * time.Minute
+	DefaultKeyPrefix   = "ratelimitbp:"
+	DefaultMaxBuckets  = 10000
+)
+
+// Config errors are returned if the configuration validation fails.
+var (
+	ErrConfigInvalidRate  = errors.New("ratelimitbp: `rate` must be positive")
+	ErrConfigInvalidBurst = errors.New("ratelimitbp: `burst` must be positive")
+)
+
+// Config is the configuration of a Limiter.
+//
+// It can be parsed directly from YAML.
+type Config struct {
+	// Name of the limiter, used as the label value in the Prometheus metrics.
+	Name string `yaml:"name"`
+
+	// Rate is the number of tokens added to each bucket per second.
+	Rate float64 `yaml:"rate"`
+
+	// Burst is the size of each bucket.
+	Burst int `yaml:"burst"`
+
+	// IdleTimeout is the duration after which an unused bucket is discarded.
+	// A discarded bucket is equivalent to a full bucket.
+	//
+	// Optional, default to DefaultIdleTimeout.
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+
+	// MaxBuckets is only used by LocalLimiter, it's the max number of buckets
+	// kept in memory. When it's reached, the least recently used bucket is
+	// discarded to make room for a new one.
+	//
+	// Optional, default to DefaultMaxBuckets.
+	MaxBuckets int `yaml:"maxBuckets"`
+
+	// KeyPrefix is only used by RedisLimiter, it's prepended to the bucket keys
+	// to form the redis keys.
+	//
+	// Optional, default to DefaultKeyPrefix.
+	KeyPrefix string `yaml:"keyPrefix"`
+}
+
+// Validate checks the Config for errors.
+func (cfg Config) Validate() error {
+	var errs []error
+	if cfg.Rate <= 0 {
+		errs = append(errs, ErrConfigInvalidRate)
+	}
+	if cfg.Burst <= 0 {
+		errs = append(errs, ErrConfigInvalidBurst)
+	}
+	return errors.Join(errs...)
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultKeyPrefix
+	}
+	if cfg.MaxBuckets <= 0 {
+		cfg.MaxBuckets = DefaultMaxBuckets
+	}
+	return cfg
+}
+
+// Result is the result of Limiter.Allow.
+type Result struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+
+	// RetryAfter is the duration after which the request would be allowed.
+	// It's only set when Allowed is false.
+	RetryAfter time.Duration
+}
+
+// Limiter is the interface of a token bucket rate limiter.
+type Limiter interface {
+	// Allow takes a token from the bucket of the key and reports whether the
+	// request is allowed.
+	//
+	// Requests with an empty key share the same bucket.
+	//
+	// When an error is returned, the middlewares log it and allow the request.
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// ErrRateLimited is the error used by the middlewares when a request is
+// rejected.
+var ErrRateLimited = errors.New("ratelimitbp: rate limited")
+
+func recordResult(name string, result Result, err error) {
+	if err != nil {
+		errorsCounter.WithLabelValues(name).Inc()
+		return
+	}
+	requestsCounter.WithLabelValues(name, strconv.FormatBool(result.Allowed)).Inc()
+}
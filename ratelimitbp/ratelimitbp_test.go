@@ -0,0 +1,232 @@
+package ratelimitbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/joomcode/redispipe/redis"
+	"github.com/joomcode/redispipe/redisconn"
+
+	"github.com/reddit/baseplate.go/headerbp"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+	"github.com/reddit/baseplate.go/redis/cache/redisx"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		cfg   ratelimitbp.Config
+		want  []error
+	}{
+		{
+			label: "valid",
+			cfg: ratelimitbp.Config{
+				Rate:  1,
+				Burst: 1,
+			},
+		},
+		{
+			label: "empty",
+			want: []error{
+				ratelimitbp.ErrConfigInvalidRate,
+				ratelimitbp.ErrConfigInvalidBurst,
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := c.cfg.Validate()
+			if len(c.want) == 0 && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			for _, want := range c.want {
+				if !errors.Is(err, want) {
+					t.Errorf("Expected error %v, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func testLimiter(t *testing.T, limiter ratelimitbp.Limiter) {
+	t.Helper()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, "foo")
+		if err != nil {
+			t.Fatalf("Allow returned error: %v", err)
+		}
+		if !result.Allowed {
+			t.Errorf("Expected request #%d to be allowed", i)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Allow returned error: %v", err)
+	}
+	if result.Allowed {
+		t.Error("Expected request to be rejected after the burst")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Second {
+		t.Errorf("Expected RetryAfter in (0, 1s], got %v", result.RetryAfter)
+	}
+
+	// Different keys have their own buckets.
+	result, err = limiter.Allow(ctx, "bar")
+	if err != nil {
+		t.Fatalf("Allow returned error: %v", err)
+	}
+	if !result.Allowed {
+		t.Error("Expected request with a different key to be allowed")
+	}
+}
+
+func TestLocalLimiter(t *testing.T) {
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:  "local",
+		Rate:  1,
+		Burst: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLimiter(t, limiter)
+}
+
+func TestLocalLimiterMaxBuckets(t *testing.T) {
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:       "local-max-buckets",
+		Rate:       0.1,
+		Burst:      1,
+		MaxBuckets: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		key  string
+		want bool
+	}{
+		{key: "foo", want: true},
+		{key: "foo", want: false},
+		// Evicts the bucket of foo.
+		{key: "bar", want: true},
+		{key: "foo", want: true},
+	} {
+		result, err := limiter.Allow(ctx, c.key)
+		if err != nil {
+			t.Fatalf("Allow returned error: %v", err)
+		}
+		if result.Allowed != c.want {
+			t.Errorf("Allow(%q) got allowed %v, want %v", c.key, result.Allowed, c.want)
+		}
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+
+	sender, err := redisconn.Connect(context.Background(), s.Addr(), redisconn.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sender.Close)
+
+	sync := redisx.BaseSync{
+		SyncCtx: redis.SyncCtx{S: sender},
+	}
+	limiter, err := ratelimitbp.NewRedis(
+		ratelimitbp.Config{
+			Name:  "redis",
+			Rate:  1,
+			Burst: 2,
+		},
+		sync,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLimiter(t, limiter)
+
+	// The script is loaded again after redis flushed its script cache.
+	if err := redis.AsError(sync.Do(context.Background(), "SCRIPT", "FLUSH")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Allow(context.Background(), "baz"); err != nil {
+		t.Errorf("Allow returned error after SCRIPT FLUSH: %v", err)
+	}
+
+	if !s.Exists(ratelimitbp.DefaultKeyPrefix + "foo") {
+		t.Errorf("Expected key %q to exist in redis", ratelimitbp.DefaultKeyPrefix+"foo")
+	}
+	if ttl := s.TTL(ratelimitbp.DefaultKeyPrefix + "foo"); ttl != ratelimitbp.DefaultIdleTimeout {
+		t.Errorf("Expected ttl %v, got %v", ratelimitbp.DefaultIdleTimeout, ttl)
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("User-Agent", "foo-client")
+	headers.Set("X-Custom", "custom")
+
+	incoming := headerbp.NewIncomingHeaders()
+	incoming.RecordHeader("X-Bp-Caller", "bp-caller")
+	ctx := incoming.SetOnContext(context.Background())
+
+	for _, c := range []struct {
+		label string
+		f     ratelimitbp.KeyFunc
+		want  string
+	}{
+		{
+			label: "client-name",
+			f:     ratelimitbp.ClientNameKey(),
+			want:  "foo-client",
+		},
+		{
+			label: "header",
+			f:     ratelimitbp.HeaderKey("X-Custom"),
+			want:  "custom",
+		},
+		{
+			label: "headerbp",
+			f:     ratelimitbp.HeaderbpKey("X-Bp-Caller"),
+			want:  "bp-caller",
+		},
+		{
+			label: "first",
+			f: ratelimitbp.FirstKey(
+				ratelimitbp.HeaderKey("X-Missing"),
+				ratelimitbp.HeaderbpKey("x-bp-caller"),
+				ratelimitbp.ClientNameKey(),
+			),
+			want: "bp-caller",
+		},
+		{
+			label: "headers-func",
+			f: func(ctx context.Context, _ ratelimitbp.Headers) string {
+				return ratelimitbp.HeaderKey("key")(ctx, ratelimitbp.HeadersFunc(func(key string) string {
+					return key + "-value"
+				}))
+			},
+			want: "key-value",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if got := c.f(ctx, headers); got != c.want {
+				t.Errorf("Got key %q, want %q", got, c.want)
+			}
+		})
+	}
+}
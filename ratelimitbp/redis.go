@@ -0,0 +1,128 @@
+package ratelimitbp
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joomcode/redispipe/redis"
+
+	"github.com/reddit/baseplate.go/redis/cache/redisx"
+)
+
+// tokenBucketScript implements the token bucket atomically in redis.
+//
+// KEYS[1]: the key of the bucket
+// ARGV[1]: rate, tokens per second
+// ARGV[2]: burst
+// ARGV[3]: current time in milliseconds
+// ARGV[4]: ttl of the key in milliseconds
+//
+// It returns {allowed, retry after in milliseconds}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, wait}
+`
+
+// tokenBucketScriptSHA is the SHA1 digest of tokenBucketScript, used to run
+// the script cached by redis with EVALSHA.
+var tokenBucketScriptSHA = func() string {
+	sum := sha1.Sum([]byte(tokenBucketScript))
+	return hex.EncodeToString(sum[:])
+}()
+
+// RedisLimiter is a Limiter keeping the token buckets in redis, so that the
+// limit is shared by all the instances using the same redis.
+//
+// The time used to refill the buckets comes from the instances,
+// so the clocks of the instances should be reasonably in sync.
+type RedisLimiter struct {
+	cfg  Config
+	sync redisx.Sync
+
+	// For tests.
+	now func() time.Time
+}
+
+// NewRedis creates a new RedisLimiter with the given config and redis client.
+func NewRedis(cfg Config, sync redisx.Sync) (*RedisLimiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &RedisLimiter{
+		cfg:  cfg.withDefaults(),
+		sync: sync,
+		now:  time.Now,
+	}, nil
+}
+
+// Allow implements Limiter.
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (result Result, err error) {
+	defer func() {
+		recordResult(l.cfg.Name, result, err)
+	}()
+
+	args := []interface{}{
+		1,
+		l.cfg.KeyPrefix + key,
+		l.cfg.Rate,
+		l.cfg.Burst,
+		l.now().UnixMilli(),
+		l.cfg.IdleTimeout.Milliseconds(),
+	}
+	res := l.sync.Do(ctx, "EVALSHA", append([]interface{}{tokenBucketScriptSHA}, args...)...)
+	if isNoScript(res) {
+		// The script is not cached by redis yet (or the cache was flushed),
+		// EVAL runs and caches it for the following EVALSHA calls.
+		res = l.sync.Do(ctx, "EVAL", append([]interface{}{tokenBucketScript}, args...)...)
+	}
+	if err := redis.AsError(res); err != nil {
+		return Result{}, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("ratelimitbp: unexpected response from redis: %#v", res)
+	}
+	allowed, ok1 := values[0].(int64)
+	wait, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return Result{}, fmt.Errorf("ratelimitbp: unexpected response from redis: %#v", res)
+	}
+	return Result{
+		Allowed:    allowed == 1,
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
+
+// isNoScript reports whether res is the NOSCRIPT error returned by EVALSHA
+// when the script is not cached by redis.
+func isNoScript(res interface{}) bool {
+	err := redis.AsError(res)
+	return err != nil && strings.Contains(err.Error(), "NOSCRIPT")
+}
+
+var _ Limiter = (*RedisLimiter)(nil)
@@ -29,6 +29,26 @@ func (s Secret) IsEmpty() bool {
 	return len(s) == 0
 }
 
+// values returns all the secret values, to be masked by log redaction.
+func (s *Secrets) values() []string {
+	var values []string
+	for _, secret := range s.simpleSecrets {
+		values = append(values, string(secret.Value))
+	}
+	for _, secret := range s.versionedSecrets {
+		for _, v := range secret.GetAll() {
+			values = append(values, string(v))
+		}
+	}
+	for _, secret := range s.credentialSecrets {
+		values = append(values, secret.Password)
+	}
+	if s.vault.Token != "" {
+		values = append(values, s.vault.Token)
+	}
+	return values
+}
+
 // CSIFile represents the raw parsed object of a file made by the Vault CSI provider
 type CSIFile struct {
 	Secret GenericSecret `json:"data"`
@@ -24,6 +24,10 @@ func nopSecretHandlerFunc(sec *Secrets) {}
 
 // Store gives access to secret tokens with automatic refresh on change.
 //
+// All the secret values loaded by the Store are registered via
+// log.SetSecretValues, so they are masked in logs and Sentry events when log
+// redaction is enabled.
+//
 // This local vault allows access to the secrets cached on disk by the fetcher
 // daemon. It will automatically reload the cache when it is changed. Do not
 // cache or store the values returned by this class's methods but rather get
@@ -38,6 +42,9 @@ type Store struct {
 	// calling unsafeSecretHandlerFunc directly
 	mu                      sync.Mutex
 	unsafeSecretHandlerFunc SecretHandlerFunc
+
+	// The source name used in log.SetSecretValues.
+	logSource string
 }
 
 // NewStore returns a new instance of Store by configuring it
@@ -54,6 +61,7 @@ func NewStore(ctx context.Context, path string, logger log.Wrapper, middlewares
 func newStore(ctx context.Context, fsEventsDelay time.Duration, path string, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
 	store := &Store{
 		unsafeSecretHandlerFunc: nopSecretHandlerFunc,
+		logSource:               "secrets.Store:" + path,
 	}
 	store.secretHandler(middlewares...)
 	fileInfo, err := os.Stat(path)
@@ -90,6 +98,7 @@ func (s *Store) parser(r io.Reader) (any, error) {
 		return nil, err
 	}
 
+	log.SetSecretValues(s.logSource, secrets.values())
 	s.secretHandlerFunc(secrets)
 
 	return secrets, nil
@@ -101,6 +110,7 @@ func (s *Store) dirParser(dir fs.FS) (any, error) {
 		return nil, err
 	}
 
+	log.SetSecretValues(s.logSource, secrets.values())
 	s.secretHandlerFunc(secrets)
 
 	return secrets, nil
@@ -140,12 +150,14 @@ func (s *Store) getSecrets() *Secrets {
 //
 // After Close is called, you won't get any updates to the secret file,
 // but can still access the secrets as they were before Close is called.
+// The secrets are also no longer masked by log redaction.
 //
 // It's OK to call Close multiple times. Calls after the first one are no-ops.
 //
 // Close doesn't return non-nil errors, but implements io.Closer.
 func (s *Store) Close() error {
 	s.watcher.Stop()
+	log.SetSecretValues(s.logSource, nil)
 	return nil
 }
 
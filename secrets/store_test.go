@@ -532,3 +532,28 @@ func TestNewStoreWaitBeforeAvailable(t *testing.T) {
 	}
 	store.Close()
 }
+
+func TestStoreMasksSecretValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	if err := os.WriteFile(path, []byte(specificationExample), 0600); err != nil {
+		t.Fatal(err)
+	}
+	redactor, err := log.NewRedactor(log.RedactionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const msg = "key: cdoUxM1WlMrfkpChtFgGObEFJ, token: 17213328-36d4-11e7-8459-525400f56d04"
+	store, err := secrets.NewStore(context.Background(), path, log.TestWrapper(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := redactor.RedactString(msg), "key: [REDACTED], token: [REDACTED]"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	store.Close()
+	if got := redactor.RedactString(msg); got != msg {
+		t.Errorf("Expected secrets to be no longer masked after Close, got %q", got)
+	}
+}
@@ -77,6 +77,15 @@ type ClientPoolConfig struct {
 	// "var/run/thrift.socket" (a relative path).
 	Addr string `yaml:"addr"`
 
+	// MultiAddress is the optional configuration to balance the connections
+	// across multiple addresses of the thrift service.
+	//
+	// When it's set, Addr is not required, and both Addr and the
+	// AddressGenerator passed into NewCustomClientPool are ignored when opening
+	// new connections.
+	// See MultiAddressConfig for more details.
+	MultiAddress *MultiAddressConfig `yaml:"multiAddress"`
+
 	// InitialConnections is the desired inital number of thrift connections
 	// created by the client pool.
 	//
@@ -101,6 +110,18 @@ type ClientPoolConfig struct {
 	// pool can maintain.
 	MaxConnections int `yaml:"maxConnections"`
 
+	// MaxPoolWaitTime is the maximum duration a client call will wait for a
+	// connection to be released back to the pool when the pool is exhausted.
+	//
+	// Waiting calls are roughly served in the order they started waiting (see
+	// clientpool.ContextPool.GetContext for details). The wait also ends when
+	// the context object passed into the client call is done, whichever comes
+	// first.
+	//
+	// When it's <=0 (default), client calls fail immediately with a PoolError
+	// wrapping clientpool.ErrExhausted when the pool is exhausted.
+	MaxPoolWaitTime time.Duration `yaml:"maxPoolWaitTime"`
+
 	// MaxConnectionAge is the maximum duration that a pooled connection will be
 	// kept before closing in favor of a new one.
 	//
@@ -244,6 +265,9 @@ func (c ClientPoolConfig) Validate() error {
 	if c.InitialConnections > c.MaxConnections {
 		return ErrConfigInvalidConnections
 	}
+	if c.MultiAddress != nil {
+		return c.MultiAddress.Validate()
+	}
 	return nil
 }
 
@@ -280,12 +304,15 @@ func (c BaseplateClientPoolConfig) Validate() error {
 	if c.ServiceSlug == "" {
 		errs = append(errs, ErrConfigMissingServiceSlug)
 	}
-	if c.Addr == "" {
+	if c.Addr == "" && c.MultiAddress == nil {
 		errs = append(errs, ErrConfigMissingAddr)
 	}
 	if c.InitialConnections > c.MaxConnections {
 		errs = append(errs, ErrConfigInvalidConnections)
 	}
+	if c.MultiAddress != nil {
+		errs = append(errs, c.MultiAddress.Validate())
+	}
 	return errors.Join(errs...)
 }
 
@@ -388,7 +415,8 @@ func NewBaseplateClientPool(cfg ClientPoolConfig, middlewares ...thrift.ClientMi
 // passed into this function.
 //
 // It always uses SingleAddressGenerator with the server address configured in
-// cfg, and THeader+TCompact as the protocol factory.
+// cfg (or the addresses configured in cfg.MultiAddress),
+// and THeader+TCompact as the protocol factory.
 //
 // If you have RequiredInitialConnections > 0, ctx passed in controls the
 // timeout of retries to hit required initial connections. Having a ctx without
@@ -491,36 +519,62 @@ func newClientPool(
 	if cfg.MaxConnectionAgeJitter != nil {
 		jitter = *cfg.MaxConnectionAgeJitter
 	}
-	opener := func() (clientpool.Client, error) {
-		// opener is only called in 2 scenarios:
-		//
-		// 1. fill in the initial clients when initialize a client pool
-		// 2. we failed to get a open client from the pool when trying to use it,
-		//    so we have to fallback to call opener to open a new one
-		//
-		// so this counter gives us _good enough_ data (when igoring scenario 1),
-		// in combination with clientPoolGetsCounter, to understand how many client
-		// calls had to do dns on hot path.
-		clientPoolOpenerCounter.With(prometheus.Labels{
-			"thrift_pool": cfg.ServiceSlug,
-		}).Inc()
+	newOpener := func(genAddr AddressGenerator) clientpool.ClientOpener {
+		return func() (clientpool.Client, error) {
+			// opener is only called in 2 scenarios:
+			//
+			// 1. fill in the initial clients when initialize a client pool
+			// 2. we failed to get a open client from the pool when trying to use it,
+			//    so we have to fallback to call opener to open a new one
+			//
+			// so this counter gives us _good enough_ data (when igoring scenario 1),
+			// in combination with clientPoolGetsCounter, to understand how many
+			// client calls had to do dns on hot path.
+			clientPoolOpenerCounter.With(prometheus.Labels{
+				"thrift_pool": cfg.ServiceSlug,
+			}).Inc()
 
-		return newClient(
-			tConfig,
+			return newClient(
+				tConfig,
+				cfg.ServiceSlug,
+				cfg.MaxConnectionAge,
+				jitter,
+				genAddr,
+				proto,
+			)
+		}
+	}
+	var pool clientpool.Pool
+	var err error
+	if cfg.MultiAddress != nil {
+		pool, err = newMultiAddressPool(
+			ctx,
 			cfg.ServiceSlug,
-			cfg.MaxConnectionAge,
-			jitter,
-			genAddr,
-			proto,
+			*cfg.MultiAddress,
+			func(ctx context.Context, opener clientpool.ClientOpener, isInitial bool) (clientpool.Pool, error) {
+				if !isInitial {
+					// Open the connections lazily for the endpoints added later.
+					return clientpool.NewChannelPool(ctx, 0, 0, cfg.MaxConnections, opener)
+				}
+				return clientpool.NewChannelPool(
+					ctx,
+					cfg.RequiredInitialConnections,
+					cfg.InitialConnections,
+					cfg.MaxConnections,
+					opener,
+				)
+			},
+			newOpener,
+		)
+	} else {
+		pool, err = clientpool.NewChannelPool(
+			ctx,
+			cfg.RequiredInitialConnections,
+			cfg.InitialConnections,
+			cfg.MaxConnections,
+			newOpener(genAddr),
 		)
 	}
-	pool, err := clientpool.NewChannelPool(
-		ctx,
-		cfg.RequiredInitialConnections,
-		cfg.InitialConnections,
-		cfg.MaxConnections,
-		opener,
-	)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"thriftbp: error initializing the required number of connections in the thrift clientpool for %q: %w",
@@ -550,7 +604,8 @@ func newClientPool(
 	pooledClient := &clientPool{
 		Pool: pool,
 
-		slug: cfg.ServiceSlug,
+		slug:        cfg.ServiceSlug,
+		maxWaitTime: cfg.MaxPoolWaitTime,
 	}
 	middlewares = append(middlewares, thriftHostnameHeaderMiddleware(cfg.ThriftHostnameHeader))
 
@@ -611,7 +666,8 @@ func newClient(
 type clientPool struct {
 	clientpool.Pool
 
-	slug string
+	slug        string
+	maxWaitTime time.Duration
 
 	wrappedClient thrift.TClient
 }
@@ -644,7 +700,7 @@ func (p *clientPool) wrapCalls(middlewares ...thrift.ClientMiddleware) {
 // wrapCalls, so it runs after all of the middleware.
 func (p *clientPool) pooledCall(ctx context.Context, method string, args, result thrift.TStruct) (_ thrift.ResponseMeta, err error) {
 	var client Client
-	client, err = p.getClient()
+	client, err = p.getClient(ctx)
 	if err != nil {
 		return thrift.ResponseMeta{}, PoolError{Cause: err}
 	}
@@ -668,14 +724,19 @@ func (p *clientPool) pooledCall(ctx context.Context, method string, args, result
 	return client.Call(ctx, method, args, result)
 }
 
-func (p *clientPool) getClient() (_ Client, err error) {
+func (p *clientPool) getClient(ctx context.Context) (_ Client, err error) {
 	defer func() {
 		clientPoolGetsCounter.With(prometheus.Labels{
 			"thrift_pool":    p.slug,
 			"thrift_success": strconv.FormatBool(err == nil),
 		}).Inc()
 	}()
-	c, err := p.Pool.Get()
+	var c clientpool.Client
+	if p.maxWaitTime > 0 {
+		c, err = p.getClientWithWait(ctx)
+	} else {
+		c, err = p.Pool.Get()
+	}
 	if err != nil {
 		if errors.Is(err, clientpool.ErrExhausted) {
 			clientPoolExhaustedCounter.With(prometheus.Labels{
@@ -692,6 +753,47 @@ func (p *clientPool) getClient() (_ Client, err error) {
 	return c.(Client), nil
 }
 
+// getClientWithWait gets a client from the pool, waiting up to p.maxWaitTime
+// when the pool is exhausted.
+func (p *clientPool) getClientWithWait(ctx context.Context) (clientpool.Client, error) {
+	if !p.Pool.IsExhausted() {
+		// Fast path, no need to create a new context object.
+		c, err := p.Pool.Get()
+		if !errors.Is(err, clientpool.ErrExhausted) {
+			return c, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.maxWaitTime)
+	defer cancel()
+
+	start := time.Now()
+	c, err := poolGetContext(ctx, p.Pool)
+	clientPoolWaitDuration.With(prometheus.Labels{
+		"thrift_pool":    p.slug,
+		"thrift_success": strconv.FormatBool(err == nil),
+	}).Observe(time.Since(start).Seconds())
+	return c, err
+}
+
+// poolGetContext calls pool.GetContext when pool implements
+// clientpool.ContextPool, otherwise it falls back to pool.Get.
+func poolGetContext(ctx context.Context, pool clientpool.Pool) (clientpool.Client, error) {
+	if cp, ok := pool.(clientpool.ContextPool); ok {
+		return cp.GetContext(ctx)
+	}
+	return pool.Get()
+}
+
+// poolNumWaiters returns pool.NumWaiters when pool implements
+// clientpool.ContextPool, otherwise it returns 0.
+func poolNumWaiters(pool clientpool.Pool) int32 {
+	if cp, ok := pool.(clientpool.ContextPool); ok {
+		return cp.NumWaiters()
+	}
+	return 0
+}
+
 func (p *clientPool) releaseClient(c Client) {
 	if err := p.Pool.Release(c); err != nil {
 		log.Errorw(
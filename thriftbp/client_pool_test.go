@@ -13,6 +13,7 @@ import (
 	"github.com/apache/thrift/lib/go/thrift"
 
 	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/clientpool"
 	"github.com/reddit/baseplate.go/ecinterface"
 	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/thriftbp"
@@ -355,3 +356,106 @@ func TestUDS(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type slowHealthHandler struct {
+	delay time.Duration
+}
+
+func (h slowHealthHandler) IsHealthy(ctx context.Context, _ *baseplatethrift.IsHealthyRequest) (r bool, err error) {
+	time.Sleep(h.delay)
+	return true, nil
+}
+
+func TestMaxPoolWaitTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "socket")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	server, err := thriftbp.NewServer(thriftbp.ServerConfig{
+		Processor: baseplatethrift.NewBaseplateServiceV2Processor(slowHealthHandler{
+			delay: 50 * time.Millisecond,
+		}),
+		Socket: thrift.NewTServerSocketFromAddrTimeout(&net.UnixAddr{
+			Net:  "unix",
+			Name: path,
+		}, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	// give the server a little time to start serving
+	time.Sleep(10 * time.Millisecond)
+	t.Cleanup(func() {
+		server.Stop()
+	})
+
+	for _, c := range []struct {
+		label       string
+		waitTime    time.Duration
+		expectError bool
+	}{
+		{
+			label:       "no-wait",
+			waitTime:    0,
+			expectError: true,
+		},
+		{
+			label:       "wait-too-short",
+			waitTime:    time.Millisecond,
+			expectError: true,
+		},
+		{
+			label:       "wait",
+			waitTime:    time.Second,
+			expectError: false,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			pool, err := thriftbp.NewBaseplateClientPool(thriftbp.ClientPoolConfig{
+				ServiceSlug:     "test",
+				Addr:            "unix://" + path,
+				EdgeContextImpl: ecinterface.Mock(),
+				MaxConnections:  1,
+				MaxPoolWaitTime: c.waitTime,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create client pool: %v", err)
+			}
+			t.Cleanup(func() {
+				pool.Close()
+			})
+
+			const n = 2
+			errs := make(chan error, n)
+			for i := 0; i < n; i++ {
+				go func() {
+					client := baseplatethrift.NewBaseplateServiceV2Client(pool.TClient())
+					_, err := client.IsHealthy(ctx, &baseplatethrift.IsHealthyRequest{})
+					errs <- err
+				}()
+			}
+			var poolErrs int
+			for i := 0; i < n; i++ {
+				err := <-errs
+				var poolErr thriftbp.PoolError
+				if errors.As(err, &poolErr) {
+					poolErrs++
+					if !errors.Is(err, clientpool.ErrExhausted) {
+						t.Errorf("Expected PoolError to wrap clientpool.ErrExhausted, got %v", err)
+					}
+				} else if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+			if c.expectError && poolErrs != 1 {
+				t.Errorf("Expected 1 PoolError, got %d", poolErrs)
+			}
+			if !c.expectError && poolErrs != 0 {
+				t.Errorf("Expected no PoolError, got %d", poolErrs)
+			}
+		})
+	}
+}
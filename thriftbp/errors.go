@@ -32,6 +32,17 @@ var (
 	ErrConfigInvalidConnections = errors.New("`InitialConnections` cannot be bigger than `MaxConnections`")
 )
 
+// TApplicationException type IDs replied by the server middlewares rejecting
+// requests without calling the handler, see ConcurrencyLimit and RateLimit.
+//
+// They are the same as the corresponding baseplate.Error codes, which are
+// outside of the range of the type IDs defined by thrift, so the clients can
+// tell the rejected requests apart from other TApplicationExceptions.
+const (
+	ApplicationExceptionTooManyRequests    = int32(baseplatethrift.ErrorCode_TOO_MANY_REQUESTS)
+	ApplicationExceptionServiceUnavailable = int32(baseplatethrift.ErrorCode_SERVICE_UNAVAILABLE)
+)
+
 // WithDefaultRetryableCodes returns a list including the given error codes and
 // the default retryable error codes:
 //
@@ -52,6 +63,10 @@ func WithDefaultRetryableCodes(codes ...int32) []int32 {
 // and returns one of the given codes and false if it is a baseplate.Error
 // but does not return one of the given codes otherwise it calls the next filter
 // in the chain.
+//
+// TApplicationExceptions with one of the given codes as the type ID,
+// e.g. ApplicationExceptionServiceUnavailable replied by ConcurrencyLimit,
+// are also treated as retryable.
 func BaseplateErrorFilter(codes ...int32) retrybp.Filter {
 	codeMap := make(map[int32]bool, len(codes))
 	for _, code := range codes {
@@ -62,6 +77,10 @@ func BaseplateErrorFilter(codes ...int32) retrybp.Filter {
 		if errors.As(err, &bpErr) {
 			return codeMap[bpErr.GetCode()]
 		}
+		var appErr thrift.TApplicationException
+		if errors.As(err, &appErr) && codeMap[appErr.TypeId()] {
+			return true
+		}
 		return next(err)
 	}
 }
@@ -101,6 +101,18 @@ func TestBaseplateErrorFilter(t *testing.T) {
 			expected:       false,
 			fallbackCalled: false,
 		},
+		{
+			name:           "application-exception-retryable",
+			err:            thrift.NewTApplicationException(retryableCode, "test"),
+			expected:       true,
+			fallbackCalled: false,
+		},
+		{
+			name:           "application-exception-other",
+			err:            thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "test"),
+			expected:       false,
+			fallbackCalled: true,
+		},
 		{
 			name:           "other-error",
 			err:            errors.New("test"),
@@ -0,0 +1,518 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/clientpool"
+	"github.com/reddit/baseplate.go/filewatcher/v2"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// LoadBalancingPolicy defines how a multi-address client pool picks the
+// endpoint to use for each client call.
+type LoadBalancingPolicy string
+
+// Supported LoadBalancingPolicy values.
+const (
+	// RoundRobin picks the available endpoints in turn.
+	//
+	// This is the default policy when none is configured.
+	RoundRobin LoadBalancingPolicy = "round_robin"
+
+	// LeastOutstandingRequests picks the available endpoint with the least
+	// number of in-flight client calls.
+	LeastOutstandingRequests LoadBalancingPolicy = "least_outstanding_requests"
+)
+
+// DefaultEndpointEjectionDuration is the default value used when
+// MultiAddressConfig.EjectionDuration is <= 0.
+const DefaultEndpointEjectionDuration = 10 * time.Second
+
+// Errors returned by MultiAddressConfig.Validate.
+var (
+	ErrConfigMissingAddrs  = errors.New("exactly one of `Addrs` and `AddrsFile` must be set")
+	ErrConfigInvalidPolicy = errors.New("unknown `Policy`")
+)
+
+// MultiAddressConfig is the configuration to create a client pool that
+// balances connections across multiple addresses of a thrift service.
+//
+// A multi-address client pool keeps a sub-pool for each address, and each of
+// the sub-pools follows InitialConnections, RequiredInitialConnections and
+// MaxConnections configured in ClientPoolConfig, with the exception that
+// sub-pools for addresses added after the pool is created start empty.
+type MultiAddressConfig struct {
+	// Addrs is the static list of the addresses of the thrift service.
+	//
+	// The format of each address is the same as ClientPoolConfig.Addr.
+	Addrs []string `yaml:"addrs"`
+
+	// AddrsFile is the path to a file containing the list of the addresses of
+	// the thrift service, as a YAML (or JSON) list of strings.
+	//
+	// The file is watched via filewatcher and changes to the list are applied
+	// to the pool automatically. Sub-pools for removed addresses are closed
+	// once all their in-use connections are released.
+	//
+	// Exactly one of Addrs and AddrsFile must be set.
+	AddrsFile string `yaml:"addrsFile"`
+
+	// Policy is the load balancing policy used to pick an endpoint for each
+	// client call.
+	//
+	// Optional. Default is RoundRobin.
+	Policy LoadBalancingPolicy `yaml:"policy"`
+
+	// EjectionDuration is how long an endpoint is taken out of rotation after
+	// it failed a connection attempt.
+	//
+	// When all the endpoints are ejected, the pool uses all of them anyway.
+	//
+	// Optional. Default is DefaultEndpointEjectionDuration.
+	EjectionDuration time.Duration `yaml:"ejectionDuration"`
+}
+
+// Validate checks MultiAddressConfig for any missing or erroneous values.
+func (c MultiAddressConfig) Validate() error {
+	var errs []error
+	if (len(c.Addrs) == 0) == (c.AddrsFile == "") {
+		errs = append(errs, ErrConfigMissingAddrs)
+	}
+	switch c.Policy {
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrConfigInvalidPolicy, c.Policy))
+	case "", RoundRobin, LeastOutstandingRequests:
+	}
+	return errors.Join(errs...)
+}
+
+// addressList is the parsed result of MultiAddressConfig.
+//
+// A new *addressList is created every time the list changes, so pointer
+// comparison can be used to detect changes.
+type addressList struct {
+	addrs []string
+}
+
+func parseAddressList(r io.Reader) (*addressList, error) {
+	var addrs []string
+	if err := yaml.NewDecoder(r).Decode(&addrs); err != nil {
+		return nil, fmt.Errorf("thriftbp: error parsing addresses file: %w", err)
+	}
+	return &addressList{addrs: addrs}, nil
+}
+
+// endpoint is the sub-pool of a single address in a multiAddressPool.
+type endpoint struct {
+	addr string
+	pool clientpool.Pool
+
+	// ejectedUntil is the unix nano timestamp before which this endpoint shall
+	// not be used.
+	ejectedUntil atomic.Int64
+
+	// removed is set when the address is no longer in the list,
+	// the pool is closed once all in-use clients are released.
+	removed atomic.Bool
+
+	// lock guards pool.Release calls (read lock) against pool.Close (write lock).
+	lock   sync.RWMutex
+	closed bool
+}
+
+func (ep *endpoint) isClosed() bool {
+	ep.lock.RLock()
+	defer ep.lock.RUnlock()
+	return ep.closed
+}
+
+func (ep *endpoint) isEjected(now time.Time) bool {
+	return now.UnixNano() < ep.ejectedUntil.Load()
+}
+
+func (ep *endpoint) release(c clientpool.Client) error {
+	ep.lock.RLock()
+	var err error
+	if ep.closed {
+		err = c.Close()
+	} else {
+		err = ep.pool.Release(c)
+	}
+	ep.lock.RUnlock()
+
+	ep.closeIfDrained()
+	return err
+}
+
+func (ep *endpoint) closeIfDrained() {
+	if ep.removed.Load() && ep.pool.NumActiveClients() <= 0 {
+		if err := ep.close(); err != nil {
+			log.Errorw(
+				"Failed to close client pool for removed endpoint",
+				"addr", ep.addr,
+				"err", err,
+			)
+		}
+	}
+}
+
+func (ep *endpoint) close() error {
+	ep.lock.Lock()
+	defer ep.lock.Unlock()
+
+	if ep.closed {
+		return nil
+	}
+	ep.closed = true
+	return ep.pool.Close()
+}
+
+// endpointClient is the Client returned by multiAddressPool, which remembers
+// the endpoint it belongs to.
+type endpointClient struct {
+	Client
+
+	endpoint *endpoint
+}
+
+// multiAddressPool implements clientpool.Pool with a sub-pool for each
+// address, and balances the calls across them.
+type multiAddressPool struct {
+	slug             string
+	policy           LoadBalancingPolicy
+	ejectionDuration time.Duration
+	newPool          func(ctx context.Context, opener clientpool.ClientOpener, isInitial bool) (clientpool.Pool, error)
+	newOpener        func(genAddr AddressGenerator) clientpool.ClientOpener
+
+	source  func() *addressList
+	watcher io.Closer
+
+	// lock guards current, endpoints and draining.
+	lock      sync.RWMutex
+	current   *addressList
+	endpoints []*endpoint
+	// draining are the endpoints removed but not yet closed,
+	// they are closed in Close if they are still not drained by then.
+	draining []*endpoint
+
+	next atomic.Uint64
+}
+
+var _ clientpool.ContextPool = (*multiAddressPool)(nil)
+
+func newMultiAddressPool(
+	ctx context.Context,
+	slug string,
+	cfg MultiAddressConfig,
+	newPool func(ctx context.Context, opener clientpool.ClientOpener, isInitial bool) (clientpool.Pool, error),
+	newOpener func(genAddr AddressGenerator) clientpool.ClientOpener,
+) (*multiAddressPool, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	p := &multiAddressPool{
+		slug:             slug,
+		policy:           cfg.Policy,
+		ejectionDuration: cfg.EjectionDuration,
+		newPool:          newPool,
+		newOpener:        newOpener,
+	}
+	if p.policy == "" {
+		p.policy = RoundRobin
+	}
+	if p.ejectionDuration <= 0 {
+		p.ejectionDuration = DefaultEndpointEjectionDuration
+	}
+
+	if cfg.AddrsFile != "" {
+		fw, err := filewatcher.New(ctx, cfg.AddrsFile, parseAddressList)
+		if err != nil {
+			return nil, err
+		}
+		p.source = fw.Get
+		p.watcher = fw
+	} else {
+		static := &addressList{addrs: slices.Clone(cfg.Addrs)}
+		p.source = func() *addressList {
+			return static
+		}
+	}
+
+	if err := p.update(ctx, p.source(), true); err != nil {
+		return nil, errors.Join(err, p.Close())
+	}
+	return p, nil
+}
+
+// refresh checks the source of the addresses and updates the endpoints if
+// there are changes.
+func (p *multiAddressPool) refresh() {
+	list := p.source()
+	p.lock.RLock()
+	changed := list != p.current
+	p.lock.RUnlock()
+	if !changed {
+		return
+	}
+	if err := p.update(context.Background(), list, false); err != nil {
+		log.Errorw(
+			"Failed to update endpoints of multi-address client pool",
+			"pool", p.slug,
+			"err", err,
+		)
+	}
+}
+
+func (p *multiAddressPool) update(ctx context.Context, list *addressList, isInitial bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if list == p.current {
+		// Someone else already updated it.
+		return nil
+	}
+
+	existing := make(map[string]*endpoint, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		existing[ep.addr] = ep
+	}
+
+	var errs []error
+	endpoints := make([]*endpoint, 0, len(list.addrs))
+	for _, addr := range list.addrs {
+		if ep, ok := existing[addr]; ok {
+			endpoints = append(endpoints, ep)
+			delete(existing, addr)
+			continue
+		}
+		if slices.ContainsFunc(endpoints, func(ep *endpoint) bool { return ep.addr == addr }) {
+			// Duplicated address.
+			continue
+		}
+		ep := &endpoint{addr: addr}
+		pool, err := p.newPool(ctx, p.endpointOpener(ep), isInitial)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("thriftbp: error creating client pool for endpoint %q: %w", addr, err))
+			continue
+		}
+		ep.pool = pool
+		endpoints = append(endpoints, ep)
+	}
+	if err := errors.Join(errs...); err != nil {
+		if isInitial {
+			for _, ep := range endpoints {
+				ep.close()
+			}
+			return err
+		}
+		log.Errorw(
+			"Failed to create client pools for some endpoints",
+			"pool", p.slug,
+			"err", err,
+		)
+	}
+	p.current = list
+
+	p.draining = slices.DeleteFunc(p.draining, (*endpoint).isClosed)
+	for _, ep := range existing {
+		ep.removed.Store(true)
+		ep.closeIfDrained()
+		if !ep.isClosed() {
+			p.draining = append(p.draining, ep)
+		}
+	}
+	p.endpoints = endpoints
+	return nil
+}
+
+// endpointOpener wraps the opener to eject ep on connection failures.
+func (p *multiAddressPool) endpointOpener(ep *endpoint) clientpool.ClientOpener {
+	opener := p.newOpener(SingleAddressGenerator(ep.addr))
+	return func() (clientpool.Client, error) {
+		c, err := opener()
+		if err != nil {
+			ep.ejectedUntil.Store(time.Now().Add(p.ejectionDuration).UnixNano())
+			endpointEjectionsCounter.WithLabelValues(p.slug, ep.addr).Inc()
+		}
+		return c, err
+	}
+}
+
+// pick picks an endpoint to use following the policy.
+func (p *multiAddressPool) pick() (*endpoint, error) {
+	p.refresh()
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if len(p.endpoints) == 0 {
+		return nil, errors.New("thriftbp: no endpoints available in multi-address client pool")
+	}
+
+	now := time.Now()
+	candidates := make([]*endpoint, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		if !ep.isEjected(now) {
+			candidates = append(candidates, ep)
+		}
+	}
+	if len(candidates) == 0 {
+		// All endpoints are ejected, try all of them anyway.
+		candidates = p.endpoints
+	}
+
+	start := int(p.next.Add(1) % uint64(len(candidates)))
+	switch p.policy {
+	default:
+		return candidates[start], nil
+	case LeastOutstandingRequests:
+		// Start from a rotating offset so that ties are broken in round-robin
+		// order.
+		picked := candidates[start]
+		for i := 1; i < len(candidates); i++ {
+			ep := candidates[(start+i)%len(candidates)]
+			if ep.pool.NumActiveClients() < picked.pool.NumActiveClients() {
+				picked = ep
+			}
+		}
+		return picked, nil
+	}
+}
+
+// maxPickAttempts is the max number of endpoints get tries when the picked
+// ones are closed concurrently.
+const maxPickAttempts = 3
+
+// get gets a client from a picked endpoint via getClient.
+//
+// An address update can remove and close the picked endpoint after it's
+// picked but before getClient is called on it, in which case another endpoint
+// is picked from the updated list.
+func (p *multiAddressPool) get(getClient func(clientpool.Pool) (clientpool.Client, error)) (clientpool.Client, error) {
+	for attempt := 1; ; attempt++ {
+		ep, err := p.pick()
+		if err != nil {
+			return nil, err
+		}
+		c, err := getClient(ep.pool)
+		if err != nil {
+			if attempt < maxPickAttempts && ep.isClosed() {
+				continue
+			}
+			return nil, err
+		}
+		return &endpointClient{
+			Client:   c.(Client),
+			endpoint: ep,
+		}, nil
+	}
+}
+
+// Get implements clientpool.Pool.
+func (p *multiAddressPool) Get() (clientpool.Client, error) {
+	return p.get(clientpool.Pool.Get)
+}
+
+// GetContext implements clientpool.ContextPool.
+func (p *multiAddressPool) GetContext(ctx context.Context) (clientpool.Client, error) {
+	return p.get(func(pool clientpool.Pool) (clientpool.Client, error) {
+		return poolGetContext(ctx, pool)
+	})
+}
+
+// Release implements clientpool.Pool.
+func (p *multiAddressPool) Release(c clientpool.Client) error {
+	if c == nil {
+		return nil
+	}
+	ec, ok := c.(*endpointClient)
+	if !ok {
+		return fmt.Errorf("thriftbp: releasing client of type %T not from the multi-address client pool", c)
+	}
+	return ec.endpoint.release(ec.Client)
+}
+
+// Close implements clientpool.Pool.
+//
+// It also closes the endpoints removed by address updates that are still
+// draining, the clients from them in use are closed when released.
+func (p *multiAddressPool) Close() error {
+	var errs []error
+	if p.watcher != nil {
+		errs = append(errs, p.watcher.Close())
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	for _, ep := range p.endpoints {
+		errs = append(errs, ep.close())
+	}
+	for _, ep := range p.draining {
+		errs = append(errs, ep.close())
+	}
+	return errors.Join(errs...)
+}
+
+func (p *multiAddressPool) sum(f func(clientpool.Pool) int32) int32 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var n int32
+	for _, ep := range p.endpoints {
+		n += f(ep.pool)
+	}
+	return n
+}
+
+// NumActiveClients implements clientpool.Pool.
+//
+// It returns the sum of all the endpoints.
+func (p *multiAddressPool) NumActiveClients() int32 {
+	return p.sum(clientpool.Pool.NumActiveClients)
+}
+
+// NumAllocated implements clientpool.Pool.
+//
+// It returns the sum of all the endpoints.
+func (p *multiAddressPool) NumAllocated() int32 {
+	return p.sum(clientpool.Pool.NumAllocated)
+}
+
+// NumWaiters implements clientpool.ContextPool.
+//
+// It returns the sum of all the endpoints.
+func (p *multiAddressPool) NumWaiters() int32 {
+	return p.sum(poolNumWaiters)
+}
+
+// IsExhausted implements clientpool.Pool.
+//
+// It returns true when all the endpoints are exhausted.
+func (p *multiAddressPool) IsExhausted() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, ep := range p.endpoints {
+		if !ep.pool.IsExhausted() {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns the current endpoints.
+func (p *multiAddressPool) snapshot() []*endpoint {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return slices.Clone(p.endpoints)
+}
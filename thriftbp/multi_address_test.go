@@ -0,0 +1,401 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/clientpool"
+)
+
+type fakeEndpointClient struct {
+	addr string
+
+	lock   sync.Mutex
+	closed bool
+}
+
+func (c *fakeEndpointClient) Call(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+	return thrift.ResponseMeta{}, nil
+}
+
+func (c *fakeEndpointClient) IsOpen() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return !c.closed
+}
+
+func (c *fakeEndpointClient) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	return nil
+}
+
+// fakeOpeners returns the newOpener arg for newMultiAddressPool,
+// with the addresses in failing set to always fail.
+func fakeOpeners(failing ...string) func(AddressGenerator) clientpool.ClientOpener {
+	return func(genAddr AddressGenerator) clientpool.ClientOpener {
+		return func() (clientpool.Client, error) {
+			addr, err := genAddr()
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range failing {
+				if f == addr {
+					return nil, errors.New("connection refused")
+				}
+			}
+			return &fakeEndpointClient{addr: addr}, nil
+		}
+	}
+}
+
+func fakeNewPool(ctx context.Context, opener clientpool.ClientOpener, isInitial bool) (clientpool.Pool, error) {
+	return clientpool.NewChannelPool(ctx, 0, 0, 10, opener)
+}
+
+func addrOf(t *testing.T, c clientpool.Client) string {
+	t.Helper()
+	return c.(*endpointClient).Client.(*fakeEndpointClient).addr
+}
+
+func TestMultiAddressConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		cfg   MultiAddressConfig
+		want  error
+	}{
+		{
+			label: "addrs",
+			cfg: MultiAddressConfig{
+				Addrs: []string{"foo:9090"},
+			},
+		},
+		{
+			label: "file",
+			cfg: MultiAddressConfig{
+				AddrsFile: "/path/to/file",
+				Policy:    LeastOutstandingRequests,
+			},
+		},
+		{
+			label: "missing",
+			cfg:   MultiAddressConfig{},
+			want:  ErrConfigMissingAddrs,
+		},
+		{
+			label: "both",
+			cfg: MultiAddressConfig{
+				Addrs:     []string{"foo:9090"},
+				AddrsFile: "/path/to/file",
+			},
+			want: ErrConfigMissingAddrs,
+		},
+		{
+			label: "policy",
+			cfg: MultiAddressConfig{
+				Addrs:  []string{"foo:9090"},
+				Policy: "random",
+			},
+			want: ErrConfigInvalidPolicy,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := c.cfg.Validate()
+			if c.want == nil && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if c.want != nil && !errors.Is(err, c.want) {
+				t.Errorf("Expected error %v, got %v", c.want, err)
+			}
+		})
+	}
+}
+
+func TestMultiAddressPoolRoundRobin(t *testing.T) {
+	pool, err := newMultiAddressPool(
+		context.Background(),
+		"test",
+		MultiAddressConfig{
+			Addrs: []string{"a:9090", "b:9090", "c:9090"},
+		},
+		fakeNewPool,
+		fakeOpeners(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		c, err := pool.Get()
+		if err != nil {
+			t.Fatalf("pool.Get returned error: %v", err)
+		}
+		counts[addrOf(t, c)]++
+		if err := pool.Release(c); err != nil {
+			t.Fatalf("pool.Release returned error: %v", err)
+		}
+	}
+	for _, addr := range []string{"a:9090", "b:9090", "c:9090"} {
+		if counts[addr] != 2 {
+			t.Errorf("Expected %q to be picked 2 times, got %d", addr, counts[addr])
+		}
+	}
+}
+
+func TestMultiAddressPoolLeastOutstandingRequests(t *testing.T) {
+	pool, err := newMultiAddressPool(
+		context.Background(),
+		"test",
+		MultiAddressConfig{
+			Addrs:  []string{"a:9090", "b:9090"},
+			Policy: LeastOutstandingRequests,
+		},
+		fakeNewPool,
+		fakeOpeners(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	// Hold on to the clients without releasing them,
+	// the picks should alternate between the 2 endpoints.
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		c, err := pool.Get()
+		if err != nil {
+			t.Fatalf("pool.Get returned error: %v", err)
+		}
+		counts[addrOf(t, c)]++
+	}
+	if counts["a:9090"] != 3 || counts["b:9090"] != 3 {
+		t.Errorf("Expected both endpoints to be picked 3 times, got %v", counts)
+	}
+	if n := pool.NumActiveClients(); n != 6 {
+		t.Errorf("pool.NumActiveClients() expected 6, got %d", n)
+	}
+}
+
+func TestMultiAddressPoolEjection(t *testing.T) {
+	pool, err := newMultiAddressPool(
+		context.Background(),
+		"test",
+		MultiAddressConfig{
+			Addrs:            []string{"a:9090", "b:9090"},
+			EjectionDuration: time.Minute,
+		},
+		fakeNewPool,
+		fakeOpeners("b:9090"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	var failures int
+	for i := 0; i < 10; i++ {
+		c, err := pool.Get()
+		if err != nil {
+			failures++
+			continue
+		}
+		if addr := addrOf(t, c); addr != "a:9090" {
+			t.Errorf("Expected only a:9090 to be picked, got %q", addr)
+		}
+		pool.Release(c)
+	}
+	if failures > 1 {
+		t.Errorf("Expected at most 1 failure before b:9090 is ejected, got %d", failures)
+	}
+}
+
+func TestMultiAddressPoolFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrs.yaml")
+	if err := os.WriteFile(path, []byte(`["a:9090", "b:9090"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	pool, err := newMultiAddressPool(
+		ctx,
+		"test",
+		MultiAddressConfig{
+			AddrsFile: path,
+		},
+		fakeNewPool,
+		fakeOpeners(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	if n := len(pool.snapshot()); n != 2 {
+		t.Fatalf("Expected 2 endpoints, got %d", n)
+	}
+
+	// Hold on to a client from each endpoint.
+	var clients []clientpool.Client
+	for i := 0; i < 2; i++ {
+		c, err := pool.Get()
+		if err != nil {
+			t.Fatalf("pool.Get returned error: %v", err)
+		}
+		clients = append(clients, c)
+	}
+
+	// Simulate the file change.
+	removed := pool.snapshot()[1]
+	pool.source = func() *addressList {
+		return &addressList{addrs: []string{"a:9090", "c:9090"}}
+	}
+	pool.refresh()
+
+	endpoints := pool.snapshot()
+	if len(endpoints) != 2 || endpoints[0].addr != "a:9090" || endpoints[1].addr != "c:9090" {
+		t.Fatalf("Unexpected endpoints after refresh: %+v", endpoints)
+	}
+	if !removed.removed.Load() {
+		t.Error("Expected b:9090 to be marked as removed")
+	}
+	if removed.closed {
+		t.Error("Expected b:9090 not to be closed before all clients are released")
+	}
+
+	for _, c := range clients {
+		if err := pool.Release(c); err != nil {
+			t.Errorf("pool.Release returned error: %v", err)
+		}
+	}
+	if !removed.isClosed() {
+		t.Error("Expected b:9090 to be closed after all clients are released")
+	}
+}
+
+// beforeGetPool is a clientpool.Pool calling beforeGet before each Get.
+type beforeGetPool struct {
+	clientpool.Pool
+
+	beforeGet func()
+}
+
+func (p *beforeGetPool) Get() (clientpool.Client, error) {
+	p.beforeGet()
+	return p.Pool.Get()
+}
+
+func TestMultiAddressPoolGetDuringUpdate(t *testing.T) {
+	var pool *multiAddressPool
+	var once sync.Once
+	newPool := func(ctx context.Context, opener clientpool.ClientOpener, isInitial bool) (clientpool.Pool, error) {
+		p, err := fakeNewPool(ctx, opener, isInitial)
+		if err != nil || !isInitial {
+			return p, err
+		}
+		return &beforeGetPool{
+			Pool: p,
+			// Simulate an address update removing and closing the endpoint
+			// after it's picked.
+			beforeGet: func() {
+				once.Do(func() {
+					pool.source = func() *addressList {
+						return &addressList{addrs: []string{"b:9090"}}
+					}
+					pool.refresh()
+				})
+			},
+		}, nil
+	}
+
+	var err error
+	pool, err = newMultiAddressPool(
+		context.Background(),
+		"test",
+		MultiAddressConfig{
+			Addrs: []string{"a:9090"},
+		},
+		newPool,
+		fakeOpeners(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+	removed := pool.snapshot()[0]
+
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatalf("pool.Get returned error: %v", err)
+	}
+	if !removed.isClosed() {
+		t.Error("Expected a:9090 to be closed")
+	}
+	if got, want := addrOf(t, c), "b:9090"; got != want {
+		t.Errorf("Expected client from %q, got %q", want, got)
+	}
+	if err := pool.Release(c); err != nil {
+		t.Errorf("pool.Release returned error: %v", err)
+	}
+}
+
+func TestMultiAddressPoolCloseDraining(t *testing.T) {
+	pool, err := newMultiAddressPool(
+		context.Background(),
+		"test",
+		MultiAddressConfig{
+			Addrs: []string{"a:9090"},
+		},
+		fakeNewPool,
+		fakeOpeners(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatalf("pool.Get returned error: %v", err)
+	}
+	removed := pool.snapshot()[0]
+	pool.source = func() *addressList {
+		return &addressList{addrs: []string{"b:9090"}}
+	}
+	pool.refresh()
+	if removed.isClosed() {
+		t.Fatal("Expected a:9090 not to be closed before all clients are released")
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("pool.Close returned error: %v", err)
+	}
+	if !removed.isClosed() {
+		t.Error("Expected draining a:9090 to be closed by Close")
+	}
+	if err := pool.Release(c); err != nil {
+		t.Errorf("pool.Release returned error: %v", err)
+	}
+	if c.IsOpen() {
+		t.Error("Expected the client released after Close to be closed")
+	}
+}
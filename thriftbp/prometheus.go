@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/prometheus/client_golang/prometheus"
@@ -223,6 +224,15 @@ var (
 		"thrift_success",
 	})
 
+	clientPoolWaitDuration = promauto.With(prometheusbpint.GlobalRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thriftbp_client_pool_wait_duration_seconds",
+		Help:    "The time spent waiting for a connection to be released back to an exhausted thrift client pool",
+		Buckets: prometheusbp.DefaultLatencyBuckets,
+	}, []string{
+		"thrift_pool",
+		"thrift_success",
+	})
+
 	clientPoolMaxSizeGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
 		Name: "thrift_client_pool_max_size",
 		Help: "The configured max size of a thrift client pool",
@@ -248,6 +258,44 @@ var (
 		[]string{"thrift_pool"},
 		nil, // const labels
 	)
+
+	clientPoolEndpointLabels = []string{
+		"thrift_pool",
+		"thrift_endpoint",
+	}
+
+	clientPoolEndpointActiveConnectionsDesc = prometheus.NewDesc(
+		"thriftbp_client_pool_endpoint_active_connections",
+		"The number of active (in-use) connections to an endpoint of a multi-address thrift client pool",
+		clientPoolEndpointLabels,
+		nil, // const labels
+	)
+
+	clientPoolEndpointIdleConnectionsDesc = prometheus.NewDesc(
+		"thriftbp_client_pool_endpoint_idle_connections",
+		"The number of idle (in-pool) connections to an endpoint of a multi-address thrift client pool",
+		clientPoolEndpointLabels,
+		nil, // const labels
+	)
+
+	clientPoolEndpointEjectedDesc = prometheus.NewDesc(
+		"thriftbp_client_pool_endpoint_ejected",
+		"Whether an endpoint of a multi-address thrift client pool is currently ejected (1) or not (0)",
+		clientPoolEndpointLabels,
+		nil, // const labels
+	)
+
+	endpointEjectionsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "thriftbp_client_pool_endpoint_ejections_total",
+		Help: "The number of times an endpoint of a multi-address thrift client pool is ejected due to connection failures",
+	}, clientPoolEndpointLabels)
+
+	clientPoolWaitersDesc = prometheus.NewDesc(
+		"thriftbp_client_pool_waiters",
+		"The number of client calls waiting for a connection from an exhausted thrift client pool",
+		[]string{"thrift_pool"},
+		nil, // const labels
+	)
 )
 
 const (
@@ -284,6 +332,7 @@ func (e *clientPoolGaugeExporter) Collect(ch chan<- prometheus.Metric) {
 	e.activeConnections.Set(int64(e.pool.NumActiveClients()))
 	active, max := e.activeConnections.GetBoth()
 	idle := float64(e.pool.NumAllocated())
+	waiters := float64(poolNumWaiters(e.pool))
 
 	// MustNewConstMetric would only panic if there's a label mismatch, which we
 	// have a unit test to cover.
@@ -305,6 +354,43 @@ func (e *clientPoolGaugeExporter) Collect(ch chan<- prometheus.Metric) {
 		idle,
 		e.slug,
 	)
+	ch <- prometheus.MustNewConstMetric(
+		clientPoolWaitersDesc,
+		prometheus.GaugeValue,
+		waiters,
+		e.slug,
+	)
+
+	if mp, ok := e.pool.(*multiAddressPool); ok {
+		now := time.Now()
+		for _, ep := range mp.snapshot() {
+			var ejected float64
+			if ep.isEjected(now) {
+				ejected = 1
+			}
+			ch <- prometheus.MustNewConstMetric(
+				clientPoolEndpointActiveConnectionsDesc,
+				prometheus.GaugeValue,
+				float64(ep.pool.NumActiveClients()),
+				e.slug,
+				ep.addr,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				clientPoolEndpointIdleConnectionsDesc,
+				prometheus.GaugeValue,
+				float64(ep.pool.NumAllocated()),
+				e.slug,
+				ep.addr,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				clientPoolEndpointEjectedDesc,
+				prometheus.GaugeValue,
+				ejected,
+				e.slug,
+				ep.addr,
+			)
+		}
+	}
 }
 
 func stringifyErrorType(err error) string {
@@ -144,7 +144,7 @@ func (p PromClientMetricsTest) CheckMetrics() {
 }
 
 type fakePool struct {
-	clientpool.Pool
+	clientpool.ContextPool
 }
 
 func (fakePool) NumActiveClients() int32 {
@@ -155,6 +155,10 @@ func (fakePool) NumAllocated() int32 {
 	return 2
 }
 
+func (fakePool) NumWaiters() int32 {
+	return 3
+}
+
 func TestClientPoolGaugeExporterRegister(t *testing.T) {
 	// This test is to make sure that a service creating more than one thrift
 	// client pool will not cause issues in prometheus metrics.
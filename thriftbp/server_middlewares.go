@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/reddit/baseplate.go/concurrencybp"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/errorsbp"
 	"github.com/reddit/baseplate.go/headerbp"
@@ -21,6 +23,7 @@ import (
 	"github.com/reddit/baseplate.go/iobp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/prometheusbp"
+	"github.com/reddit/baseplate.go/ratelimitbp"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -127,6 +130,9 @@ func StartSpanFromThriftContext(ctx context.Context, name string) (context.Conte
 		sampled = str == transport.HeaderTracingSampledTrue
 		headers.Sampled = &sampled
 	}
+	if str, ok := header(ctx, transport.HeaderTracingBaggage); ok {
+		headers.Baggage = str
+	}
 
 	return tracing.StartSpanFromHeaders(ctx, name, headers)
 }
@@ -521,3 +527,132 @@ func ServerMethodDescriptorMiddleware(serverName string) thrift.ProcessorMiddlew
 		return next
 	}
 }
+
+// ConcurrencyLimit returns a ProcessorMiddleware that sheds load using the
+// given adaptive concurrency limiter.
+//
+// When the limit is reached, the request is rejected without calling the
+// handler, and the client gets a TApplicationException with type
+// ApplicationExceptionServiceUnavailable and the retry-after duration in its
+// message, which works with any endpoint regardless of the exceptions it
+// declares, and is retried by BaseplateErrorFilter with
+// WithDefaultRetryableCodes.
+// The middlewares wrapping this one see a retryable baseplate.Error with code
+// SERVICE_UNAVAILABLE instead.
+// Otherwise the latency and the outcome of the request (see
+// concurrencybp.OutcomeFromError) are reported back to the limiter to adjust
+// the limit.
+//
+// onewayMethods are the names of the oneway methods of the service,
+// the rejected requests to them get no reply, as their clients never read one.
+func ConcurrencyLimit(limiter *concurrencybp.Limiter, onewayMethods ...string) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		oneway := slices.Contains(onewayMethods, name)
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (_ bool, err thrift.TException) {
+				release, ok := limiter.Acquire()
+				if !ok {
+					return writeRejectedReply(ctx, name, seqID, in, out, oneway, &baseplate.Error{
+						Code:      thrift.Int32Ptr(int32(baseplate.ErrorCode_SERVICE_UNAVAILABLE)),
+						Message:   thrift.StringPtr(concurrencybp.ErrLimitExceeded.Error()),
+						Retryable: thrift.BoolPtr(true),
+						Details: map[string]string{
+							"retry_after_ms": strconv.FormatInt(limiter.RetryAfter().Milliseconds(), 10),
+						},
+					})
+				}
+				defer func() {
+					release(concurrencybp.OutcomeFromError(err))
+				}()
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
+// RateLimit returns a ProcessorMiddleware that rate limits the requests using
+// the given limiter, with the token bucket keys extracted by key from the
+// request THeaders.
+//
+// When the request is rate limited, it's rejected without calling the
+// handler, the same way as ConcurrencyLimit, with a TApplicationException with
+// type ApplicationExceptionTooManyRequests replied to the client, and a
+// retryable baseplate.Error with code TOO_MANY_REQUESTS seen by the
+// middlewares wrapping this one.
+//
+// When the limiter returns an error, the error is logged and the request is
+// allowed.
+//
+// onewayMethods are the same as in ConcurrencyLimit.
+func RateLimit(limiter ratelimitbp.Limiter, key ratelimitbp.KeyFunc, onewayMethods ...string) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		oneway := slices.Contains(onewayMethods, name)
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				headers := ratelimitbp.HeadersFunc(func(key string) string {
+					v, _ := thrift.GetHeader(ctx, key)
+					return v
+				})
+				result, err := limiter.Allow(ctx, key(ctx, headers))
+				if err != nil {
+					log.C(ctx).Warnw(
+						"thriftbp.RateLimit: limiter failed, allowing the request",
+						"err", err,
+						"endpoint", name,
+					)
+				} else if !result.Allowed {
+					return writeRejectedReply(ctx, name, seqID, in, out, oneway, &baseplate.Error{
+						Code:      thrift.Int32Ptr(int32(baseplate.ErrorCode_TOO_MANY_REQUESTS)),
+						Message:   thrift.StringPtr(ratelimitbp.ErrRateLimited.Error()),
+						Retryable: thrift.BoolPtr(true),
+						Details: map[string]string{
+							"retry_after_ms": strconv.FormatInt(result.RetryAfter.Milliseconds(), 10),
+						},
+					})
+				}
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
+// writeRejectedReply discards the args of the request, and replies a
+// TApplicationException with the code and the message of the given
+// baseplate.Error without calling the handler.
+// Nothing is replied to oneway methods.
+//
+// TApplicationException is used instead of bpErr itself so that the clients
+// can always decode the reply, regardless of the exceptions (if any) declared
+// by the endpoint.
+//
+// It returns the same values as the compiler generated processor functions
+// after the handler returned bpErr, so the server middlewares still see bpErr.
+func writeRejectedReply(ctx context.Context, name string, seqID int32, in, out thrift.TProtocol, oneway bool, bpErr *baseplate.Error) (bool, thrift.TException) {
+	if err := in.Skip(ctx, thrift.STRUCT); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := in.ReadMessageEnd(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if oneway {
+		return true, bpErr
+	}
+	msg := bpErr.GetMessage()
+	if retryAfter, ok := bpErr.GetDetails()["retry_after_ms"]; ok {
+		msg = fmt.Sprintf("%s, retry after %sms", msg, retryAfter)
+	}
+	appErr := thrift.NewTApplicationException(bpErr.GetCode(), msg)
+	if err := out.WriteMessageBegin(ctx, name, thrift.EXCEPTION, seqID); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := appErr.Write(ctx, out); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := out.WriteMessageEnd(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := out.Flush(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	return true, bpErr
+}
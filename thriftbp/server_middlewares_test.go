@@ -3,12 +3,19 @@ package thriftbp_test
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/avast/retry-go"
 
+	"github.com/reddit/baseplate.go/concurrencybp"
 	"github.com/reddit/baseplate.go/ecinterface"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+	"github.com/reddit/baseplate.go/retrybp"
 	"github.com/reddit/baseplate.go/thriftbp"
 	"github.com/reddit/baseplate.go/thriftbp/thrifttest"
 	"github.com/reddit/baseplate.go/tracing"
@@ -248,3 +255,300 @@ func TestPanicMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestConcurrencyLimit(t *testing.T) {
+	const name = "test"
+	limiter, err := concurrencybp.New(concurrencybp.Config{
+		Name:         "thriftbp-test",
+		InitialLimit: 1,
+		MaxLimit:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var called int
+	next := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			called++
+			return true, nil
+		},
+	}
+	wrapped := thriftbp.ConcurrencyLimit(limiter)(name, next)
+
+	t.Run("accepted", func(t *testing.T) {
+		called = 0
+		if _, err := wrapped.Process(context.Background(), 1, nil, nil); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if called != 1 {
+			t.Errorf("Expected next to be called once, got %d", called)
+		}
+		if got := limiter.Inflight(); got != 0 {
+			t.Errorf("Expected the request to be released, got %d inflight", got)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		called = 0
+		release, ok := limiter.Acquire()
+		if !ok {
+			t.Fatal("Failed to acquire from limiter")
+		}
+		defer release(concurrencybp.Ignored)
+
+		ctx := context.Background()
+		inBuf := thrift.NewTMemoryBuffer()
+		in := thrift.NewTBinaryProtocolConf(inBuf, nil)
+		// The args struct of the request.
+		args := baseplatethrift.NewBaseplateServiceV2IsHealthyArgs()
+		args.Request = &baseplatethrift.IsHealthyRequest{}
+		if err := args.Write(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+		outBuf := thrift.NewTMemoryBuffer()
+		out := thrift.NewTBinaryProtocolConf(outBuf, nil)
+
+		ok, err := wrapped.Process(ctx, 1, in, out)
+		if !ok {
+			t.Error("Expected ok to be true, got false")
+		}
+		var bpErr *baseplatethrift.Error
+		if !errors.As(err, &bpErr) {
+			t.Fatalf("Expected baseplate.Error, got %v", err)
+		}
+		if bpErr.GetCode() != int32(baseplatethrift.ErrorCode_SERVICE_UNAVAILABLE) || !bpErr.GetRetryable() {
+			t.Errorf("Expected retryable SERVICE_UNAVAILABLE error, got %v", bpErr)
+		}
+		if called != 0 {
+			t.Errorf("Expected next to not be called, got %d", called)
+		}
+		if inBuf.Len() != 0 {
+			t.Errorf("Expected the args to be consumed, %d bytes left", inBuf.Len())
+		}
+
+		// Decode the reply the same way as the generated client of a void endpoint
+		// without any exception declared.
+		client := thrift.NewTStandardClient(out, out)
+		recvErr := client.Recv(ctx, out, 1, name, voidResult{})
+		var appErr thrift.TApplicationException
+		if !errors.As(recvErr, &appErr) {
+			t.Fatalf("Expected TApplicationException from the reply, got %v", recvErr)
+		}
+		if appErr.TypeId() != thriftbp.ApplicationExceptionServiceUnavailable {
+			t.Errorf("Expected TApplicationException type %d, got %d", thriftbp.ApplicationExceptionServiceUnavailable, appErr.TypeId())
+		}
+		if !strings.Contains(appErr.Error(), concurrencybp.ErrLimitExceeded.Error()) {
+			t.Errorf("Expected the exception message to contain %q, got %q", concurrencybp.ErrLimitExceeded, appErr.Error())
+		}
+	})
+
+	t.Run("rejected-oneway", func(t *testing.T) {
+		called = 0
+		release, ok := limiter.Acquire()
+		if !ok {
+			t.Fatal("Failed to acquire from limiter")
+		}
+		defer release(concurrencybp.Ignored)
+
+		ctx := context.Background()
+		in := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+		args := baseplatethrift.NewBaseplateServiceV2IsHealthyArgs()
+		if err := args.Write(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+		outBuf := thrift.NewTMemoryBuffer()
+		out := thrift.NewTBinaryProtocolConf(outBuf, nil)
+
+		oneway := thriftbp.ConcurrencyLimit(limiter, name)(name, next)
+		_, err := oneway.Process(ctx, 1, in, out)
+		var bpErr *baseplatethrift.Error
+		if !errors.As(err, &bpErr) {
+			t.Fatalf("Expected baseplate.Error, got %v", err)
+		}
+		if called != 0 {
+			t.Errorf("Expected next to not be called, got %d", called)
+		}
+		if outBuf.Len() != 0 {
+			t.Errorf("Expected no reply to oneway method, got %d bytes", outBuf.Len())
+		}
+	})
+}
+
+// voidResult is the result struct of a void endpoint without any exception
+// declared.
+type voidResult struct{}
+
+func (voidResult) Write(ctx context.Context, p thrift.TProtocol) error {
+	if err := p.WriteStructBegin(ctx, "result"); err != nil {
+		return err
+	}
+	if err := p.WriteFieldStop(ctx); err != nil {
+		return err
+	}
+	return p.WriteStructEnd(ctx)
+}
+
+func (voidResult) Read(ctx context.Context, p thrift.TProtocol) error {
+	return p.Skip(ctx, thrift.STRUCT)
+}
+
+func TestRateLimit(t *testing.T) {
+	const name = "test"
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:  "thriftbp-test",
+		Rate:  0.1,
+		Burst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var called int
+	next := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			called++
+			return true, nil
+		},
+	}
+	wrapped := thriftbp.RateLimit(limiter, ratelimitbp.ClientNameKey())(name, next)
+	ctx := thrift.SetHeader(context.Background(), transport.HeaderUserAgent, "foo")
+
+	if _, err := wrapped.Process(ctx, 1, nil, nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if called != 1 {
+		t.Errorf("Expected next to be called once, got %d", called)
+	}
+
+	in := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+	args := baseplatethrift.NewBaseplateServiceV2IsHealthyArgs()
+	if err := args.Write(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	out := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+	_, err = wrapped.Process(ctx, 2, in, out)
+	var bpErr *baseplatethrift.Error
+	if !errors.As(err, &bpErr) {
+		t.Fatalf("Expected baseplate.Error, got %v", err)
+	}
+	if bpErr.GetCode() != int32(baseplatethrift.ErrorCode_TOO_MANY_REQUESTS) || !bpErr.GetRetryable() {
+		t.Errorf("Expected retryable TOO_MANY_REQUESTS error, got %v", bpErr)
+	}
+	if called != 1 {
+		t.Errorf("Expected next to not be called again, got %d", called)
+	}
+
+	recvErr := thrift.NewTStandardClient(out, out).Recv(ctx, out, 2, name, voidResult{})
+	var appErr thrift.TApplicationException
+	if !errors.As(recvErr, &appErr) {
+		t.Fatalf("Expected TApplicationException from the reply, got %v", recvErr)
+	}
+	if appErr.TypeId() != thriftbp.ApplicationExceptionTooManyRequests {
+		t.Errorf("Expected TApplicationException type %d, got %d", thriftbp.ApplicationExceptionTooManyRequests, appErr.TypeId())
+	}
+	if !strings.Contains(appErr.Error(), ratelimitbp.ErrRateLimited.Error()) {
+		t.Errorf("Expected the exception message to contain %q, got %q", ratelimitbp.ErrRateLimited, appErr.Error())
+	}
+}
+
+func TestRateLimitOneway(t *testing.T) {
+	const name = "oneway"
+	limiter, err := ratelimitbp.NewLocal(ratelimitbp.Config{
+		Name:  "thriftbp-test-oneway",
+		Rate:  0.1,
+		Burst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := thrift.SetHeader(context.Background(), transport.HeaderUserAgent, "foo")
+	if result, err := limiter.Allow(ctx, "foo"); err != nil || !result.Allowed {
+		t.Fatalf("Failed to take the token from limiter: %v, %v", result, err)
+	}
+
+	next := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			t.Error("Expected next to not be called")
+			return true, nil
+		},
+	}
+	wrapped := thriftbp.RateLimit(limiter, ratelimitbp.ClientNameKey(), name)(name, next)
+
+	in := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+	args := baseplatethrift.NewBaseplateServiceV2IsHealthyArgs()
+	if err := args.Write(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	outBuf := thrift.NewTMemoryBuffer()
+	out := thrift.NewTBinaryProtocolConf(outBuf, nil)
+	_, err = wrapped.Process(ctx, 1, in, out)
+	var bpErr *baseplatethrift.Error
+	if !errors.As(err, &bpErr) {
+		t.Fatalf("Expected baseplate.Error, got %v", err)
+	}
+	if outBuf.Len() != 0 {
+		t.Errorf("Expected no reply to oneway method, got %d bytes", outBuf.Len())
+	}
+}
+
+func TestConcurrencyLimitClientRetry(t *testing.T) {
+	limiter, err := concurrencybp.New(concurrencybp.Config{
+		Name:         "thriftbp-test-client-retry",
+		InitialLimit: 1,
+		MaxLimit:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, ok := limiter.Acquire()
+	if !ok {
+		t.Fatal("Failed to acquire from limiter")
+	}
+	defer release(concurrencybp.Ignored)
+
+	var requests atomic.Int64
+	countRequests := func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				requests.Add(1)
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+	server, err := thrifttest.NewBaseplateServer(thrifttest.ServerConfig{
+		Processor:   baseplatethrift.NewBaseplateServiceV2Processor(mockBaseplateService{}),
+		SecretStore: newSecretsStore(t),
+		ProcessorMiddlewares: []thrift.ProcessorMiddleware{
+			countRequests,
+			thriftbp.ConcurrencyLimit(limiter),
+		},
+		ClientConfig: thriftbp.ClientPoolConfig{
+			DefaultRetryOptions: []retry.Option{
+				retry.Attempts(2),
+				retry.Delay(time.Millisecond),
+				retrybp.Filters(thriftbp.BaseplateErrorFilter(thriftbp.WithDefaultRetryableCodes()...)),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	server.Start(ctx)
+	t.Cleanup(func() { go server.Close() })
+
+	client := baseplatethrift.NewBaseplateServiceV2Client(server.ClientPool.TClient())
+	_, err = client.IsHealthy(ctx, &baseplatethrift.IsHealthyRequest{})
+	var appErr thrift.TApplicationException
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected TApplicationException, got %v", err)
+	}
+	if appErr.TypeId() != thriftbp.ApplicationExceptionServiceUnavailable {
+		t.Errorf("Expected TApplicationException type %d, got %d", thriftbp.ApplicationExceptionServiceUnavailable, appErr.TypeId())
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected the shed request to be retried once, got %d requests", got)
+	}
+}
@@ -77,6 +77,17 @@ func CreateThriftContextFromSpan(ctx context.Context, span *tracing.Span) contex
 		ctx = thrift.UnsetHeader(ctx, transport.HeaderTracingSampled)
 	}
 
+	if baggage := span.Baggage(); baggage != "" {
+		ctx = thrift.SetHeader(
+			ctx,
+			transport.HeaderTracingBaggage,
+			baggage,
+		)
+		headers = append(headers, transport.HeaderTracingBaggage)
+	} else {
+		ctx = thrift.UnsetHeader(ctx, transport.HeaderTracingBaggage)
+	}
+
 	ctx = thrift.SetWriteHeaderList(ctx, headers)
 
 	return ctx
@@ -168,3 +168,28 @@ func TestCreateThriftContextFromSpan(t *testing.T) {
 		},
 	)
 }
+
+func TestBaggagePropagation(t *testing.T) {
+	defer func() {
+		tracing.InitGlobalTracer(tracing.Config{})
+	}()
+	tracing.InitGlobalTracer(tracing.Config{
+		Baggage: tracing.BaggageConfig{AllowList: []string{"foo"}},
+	})
+
+	ctx := context.Background()
+	ctx = thrift.SetHeader(ctx, transport.HeaderTracingTrace, "12345")
+	ctx = thrift.SetHeader(ctx, transport.HeaderTracingBaggage, "foo=bar,other=value")
+	_, span := thriftbp.StartSpanFromThriftContext(ctx, "test")
+	if v := span.BaggageItem("foo"); v != "bar" {
+		t.Errorf("baggage foo expected to be %q, got %q", "bar", v)
+	}
+	if v := span.BaggageItem("other"); v != "" {
+		t.Errorf("baggage other expected to be dropped, got %q", v)
+	}
+
+	ctx = thriftbp.CreateThriftContextFromSpan(context.Background(), span)
+	if v, ok := thrift.GetHeader(ctx, transport.HeaderTracingBaggage); !ok || v != "foo=bar" {
+		t.Errorf("baggage in the context expected to be %q, got %q & %v", "foo=bar", v, ok)
+	}
+}
@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// Default values for BaggageConfig.
+//
+// They are the limits of the W3C baggage header.
+const (
+	DefaultBaggageMaxItems = 64
+	DefaultBaggageMaxBytes = 8192
+)
+
+// BaggageAllowAll can be used in BaggageConfig.AllowList to allow all keys.
+const BaggageAllowAll = "*"
+
+// BaggageConfig is the configuration of the baggage propagated across service
+// boundaries.
+//
+// Baggage is always available in process via Span.BaggageItem,
+// BaggageConfig only controls what is read from the upstream headers and
+// written to the downstream headers.
+//
+// Can be deserialized from YAML.
+type BaggageConfig struct {
+	// The baggage keys allowed to be read from and written to headers.
+	//
+	// Keys are matched against it case-insensitively,
+	// but they are always propagated unchanged.
+	//
+	// If it's empty, baggage is not propagated at all.
+	// Use BaggageAllowAll ("*") to allow all keys.
+	AllowList []string `yaml:"allowList"`
+
+	// The max number of baggage items propagated.
+	//
+	// If it's <= 0, DefaultBaggageMaxItems will be used.
+	MaxItems int `yaml:"maxItems"`
+
+	// The max size of the encoded baggage header in bytes.
+	//
+	// If it's <= 0, DefaultBaggageMaxBytes will be used.
+	MaxBytes int `yaml:"maxBytes"`
+}
+
+// baggagePolicy is the compiled version of BaggageConfig.
+type baggagePolicy struct {
+	allowAll bool
+	allowed  map[string]bool
+	maxItems int
+	maxBytes int
+}
+
+func newBaggagePolicy(cfg BaggageConfig) baggagePolicy {
+	p := baggagePolicy{
+		allowed:  make(map[string]bool, len(cfg.AllowList)),
+		maxItems: cfg.MaxItems,
+		maxBytes: cfg.MaxBytes,
+	}
+	for _, key := range cfg.AllowList {
+		if key == BaggageAllowAll {
+			p.allowAll = true
+		}
+		p.allowed[strings.ToLower(key)] = true
+	}
+	if p.maxItems <= 0 {
+		p.maxItems = DefaultBaggageMaxItems
+	}
+	if p.maxBytes <= 0 {
+		p.maxBytes = DefaultBaggageMaxBytes
+	}
+	return p
+}
+
+// allow returns true if key matches the allow list case-insensitively.
+func (p baggagePolicy) allow(key string) bool {
+	return p.allowAll || p.allowed[strings.ToLower(key)]
+}
+
+func (p baggagePolicy) enabled() bool {
+	return p.allowAll || len(p.allowed) > 0
+}
+
+// encode encodes the allowed items into a W3C baggage header value.
+//
+// Items are added in the order of their keys,
+// and the ones exceeding the limits are dropped.
+func (p baggagePolicy) encode(items map[string]string) string {
+	if !p.enabled() || len(items) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		if p.allow(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	var count int
+	for _, key := range keys {
+		if count >= p.maxItems {
+			break
+		}
+		member := url.PathEscape(key) + "=" + url.PathEscape(items[key])
+		size := len(member)
+		if sb.Len() > 0 {
+			size++
+		}
+		if sb.Len()+size > p.maxBytes {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(member)
+		count++
+	}
+	return sb.String()
+}
+
+// decode decodes the allowed items out of a W3C baggage header value.
+//
+// Members with properties are supported, but the properties are discarded.
+// Malformed members are skipped.
+func (p baggagePolicy) decode(header string) map[string]string {
+	if !p.enabled() || header == "" {
+		return nil
+	}
+	if len(header) > p.maxBytes {
+		globalTracer.logger.Log(context.Background(), fmt.Sprintf(
+			"Baggage header too large, size %d > %d, ignored",
+			len(header),
+			p.maxBytes,
+		))
+		return nil
+	}
+
+	items := make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		if len(items) >= p.maxItems {
+			break
+		}
+		member, _, _ = strings.Cut(member, ";")
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSpace(key))
+		if err != nil || key == "" {
+			continue
+		}
+		if !p.allow(key) {
+			continue
+		}
+		value, err = url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		items[key] = value
+	}
+	return items
+}
+
+// Baggage returns the W3C baggage header value to be sent to downstream
+// services for requests made within this span.
+//
+// Only the keys allowed by Config.Baggage are included,
+// and it's empty when there's nothing to propagate.
+func (s Span) Baggage() string {
+	return globalTracer.baggage.encode(s.trace.baggage)
+}
+
+// ForeachBaggageItem implements opentracing.SpanContext.
+//
+// handler is called for every baggage item until it returns false.
+func (s *Span) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range s.trace.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+// SetBaggageItem implements opentracing.Span.
+//
+// Baggage items are propagated to all the child spans created after it's set,
+// and to downstream services if the key is allowed by Config.Baggage.
+// Keys are case-sensitive, as in the W3C baggage header.
+func (s *Span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	if s.trace.baggage == nil {
+		s.trace.baggage = make(map[string]string)
+	}
+	s.trace.baggage[restrictedKey] = value
+	return s
+}
+
+// BaggageItem implements opentracing.Span.
+//
+// It returns empty string if the baggage item is not set.
+func (s *Span) BaggageItem(restrictedKey string) string {
+	return s.trace.baggage[restrictedKey]
+}
@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+func TestBaggagePolicy(t *testing.T) {
+	items := map[string]string{
+		"foo":   "bar",
+		"fizz":  "buzz",
+		"other": "a value,with;special=chars",
+	}
+
+	for _, c := range []struct {
+		label  string
+		cfg    BaggageConfig
+		header string
+	}{
+		{
+			label: "disabled",
+		},
+		{
+			label:  "allow-list",
+			cfg:    BaggageConfig{AllowList: []string{"FOO", "fizz"}},
+			header: "fizz=buzz,foo=bar",
+		},
+		{
+			label:  "allow-all",
+			cfg:    BaggageConfig{AllowList: []string{BaggageAllowAll}},
+			header: "fizz=buzz,foo=bar,other=a%20value%2Cwith%3Bspecial=chars",
+		},
+		{
+			label: "max-items",
+			cfg: BaggageConfig{
+				AllowList: []string{BaggageAllowAll},
+				MaxItems:  1,
+			},
+			header: "fizz=buzz",
+		},
+		{
+			label: "max-bytes",
+			cfg: BaggageConfig{
+				AllowList: []string{BaggageAllowAll},
+				MaxBytes:  len("fizz=buzz,foo=bar"),
+			},
+			header: "fizz=buzz,foo=bar",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			p := newBaggagePolicy(c.cfg)
+			header := p.encode(items)
+			if header != c.header {
+				t.Errorf("encode got %q, want %q", header, c.header)
+			}
+			if header == "" {
+				return
+			}
+			decoded := p.decode(header)
+			for k, v := range decoded {
+				if items[k] != v {
+					t.Errorf("decode got %q=%q, want %q", k, v, items[k])
+				}
+			}
+			if got, want := len(decoded), strings.Count(header, ",")+1; got != want {
+				t.Errorf("decode got %d items, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestBaggagePolicyDecode(t *testing.T) {
+	p := newBaggagePolicy(BaggageConfig{
+		AllowList: []string{"foo", "fizz"},
+		MaxBytes:  64,
+	})
+
+	for _, c := range []struct {
+		label  string
+		header string
+		want   map[string]string
+	}{
+		{
+			label:  "properties",
+			header: "foo=bar;prop=1, fizz = buzz",
+			want:   map[string]string{"foo": "bar", "fizz": "buzz"},
+		},
+		{
+			label:  "not-allowed",
+			header: "foo=bar,other=value",
+			want:   map[string]string{"foo": "bar"},
+		},
+		{
+			label:  "case-insensitive-allow-list",
+			header: "FOO=bar,Fizz=buzz",
+			want:   map[string]string{"FOO": "bar", "Fizz": "buzz"},
+		},
+		{
+			label:  "malformed",
+			header: "foo,fizz=%zz,=bar",
+			want:   map[string]string{},
+		},
+		{
+			label:  "too-large",
+			header: "foo=" + strings.Repeat("a", 64),
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			got := p.decode(c.header)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("decode(%q) got %v, want %v", c.header, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSpanBaggage(t *testing.T) {
+	defer func(policy baggagePolicy) {
+		globalTracer.baggage = policy
+	}(globalTracer.baggage)
+	globalTracer.baggage = newBaggagePolicy(BaggageConfig{
+		AllowList: []string{"foo"},
+	})
+
+	parent := AsSpan(opentracing.StartSpan("parent"))
+	parent.SetBaggageItem("Foo", "bar")
+	parent.SetBaggageItem("foo", "lower")
+	parent.SetBaggageItem("local", "value")
+
+	child := AsSpan(opentracing.StartSpan("child", opentracing.ChildOf(parent)))
+	if got := child.BaggageItem("Foo"); got != "bar" {
+		t.Errorf("Expected child to inherit baggage Foo=bar, got %q", got)
+	}
+	if got := child.BaggageItem("foo"); got != "lower" {
+		t.Errorf("Expected child to inherit baggage foo=lower, got %q", got)
+	}
+	if got := child.BaggageItem("local"); got != "value" {
+		t.Errorf("Expected child to inherit baggage local=value, got %q", got)
+	}
+
+	child.SetBaggageItem("child", "only")
+	if got := parent.BaggageItem("child"); got != "" {
+		t.Errorf("Expected baggage set on child to not leak to parent, got %q", got)
+	}
+
+	items := make(map[string]string)
+	child.Context().ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+	want := map[string]string{"Foo": "bar", "foo": "lower", "local": "value", "child": "only"}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("ForeachBaggageItem got %v, want %v", items, want)
+	}
+
+	if got, want := child.Baggage(), "Foo=bar,foo=lower"; got != want {
+		t.Errorf("Baggage got %q, want %q", got, want)
+	}
+}
+
+func TestStartSpanFromHeadersBaggage(t *testing.T) {
+	defer func(policy baggagePolicy) {
+		globalTracer.baggage = policy
+	}(globalTracer.baggage)
+
+	headers := Headers{
+		TraceID: "12345",
+		SpanID:  "67890",
+		Baggage: "foo=bar,other=value",
+	}
+
+	_, span := StartSpanFromHeaders(context.Background(), "test", headers)
+	if got := span.BaggageItem("foo"); got != "" {
+		t.Errorf("Expected baggage to be ignored by default, got %q", got)
+	}
+
+	globalTracer.baggage = newBaggagePolicy(BaggageConfig{
+		AllowList: []string{"foo"},
+	})
+	_, span = StartSpanFromHeaders(context.Background(), "test", headers)
+	if got := span.BaggageItem("foo"); got != "bar" {
+		t.Errorf("Expected baggage foo=bar, got %q", got)
+	}
+	if got := span.BaggageItem("other"); got != "" {
+		t.Errorf("Expected baggage other to be dropped, got %q", got)
+	}
+}
+
+func TestStartSpanFromHeadersBaggageOnly(t *testing.T) {
+	defer func(policy baggagePolicy) {
+		globalTracer.baggage = policy
+	}(globalTracer.baggage)
+	globalTracer.baggage = newBaggagePolicy(BaggageConfig{
+		AllowList: []string{"foo"},
+	})
+
+	_, span := StartSpanFromHeaders(context.Background(), "test", Headers{
+		Baggage: "foo=bar",
+	})
+	if span.TraceID() == "" {
+		t.Error("Expected a new trace to be started")
+	}
+	if got := span.BaggageItem("foo"); got != "bar" {
+		t.Errorf("Expected baggage foo=bar, got %q", got)
+	}
+}
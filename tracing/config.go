@@ -26,6 +26,14 @@ type Config struct {
 	// headers from the client.
 	SampleRate float64 `yaml:"sampleRate"`
 
+	// Sampling configures additional sampling rules on top of SampleRate,
+	// see SamplingConfig for details.
+	Sampling SamplingConfig `yaml:"sampling"`
+
+	// Sampler, if non-nil, is used to decide whether new traces should be
+	// sampled, and SampleRate and Sampling will be ignored.
+	Sampler Sampler `yaml:"-"`
+
 	// Logger, if non-nil, will be used to log additional informations Record
 	// returned certain errors.
 	Logger log.Wrapper `yaml:"logger"`
@@ -40,6 +48,12 @@ type Config struct {
 	// that it fails immediately if the queue is full.
 	MaxRecordTimeout time.Duration `yaml:"recordTimeout"`
 
+	// Exporter selects how sampled spans are sent to the backend service,
+	// either ExporterSidecar or ExporterOTLP.
+	//
+	// If it's empty, ExporterSidecar will be used.
+	Exporter string `yaml:"exporter"`
+
 	// The name of the message queue to be used to actually send sampled spans to
 	// backend service (requires Baseplate.py tracing publishing sidecar with the
 	// same name configured).
@@ -48,6 +62,8 @@ type Config struct {
 	//
 	// If QueueName is empty, no spans will be sampled,
 	// including the ones with debug flag set.
+	//
+	// This is only used when Exporter is ExporterSidecar.
 	QueueName string `yaml:"queueName"`
 
 	// The max size of the message queue (number of messages).
@@ -65,6 +81,23 @@ type Config struct {
 	// can handle hex trace ids (Baseplate.go v0.8.0+ or Baseplate.py v2.0.0+).
 	UseHex bool `yaml:"useHex"`
 
+	// HeaderPrecedence decides which trace headers are used to continue an
+	// upstream trace when a request carries both the Baseplate and the W3C
+	// traceparent headers, either PrecedenceBaseplate or PrecedenceW3C.
+	//
+	// If it's empty, PrecedenceBaseplate will be used.
+	HeaderPrecedence string `yaml:"headerPrecedence"`
+
+	// Baggage controls the baggage items propagated across service boundaries.
+	//
+	// By default no baggage is propagated.
+	Baggage BaggageConfig `yaml:"baggage"`
+
+	// The configuration of the OTLP/HTTP exporter.
+	//
+	// This is only used when Exporter is ExporterOTLP.
+	OTLP OTLPConfig `yaml:"otlp"`
+
 	// In test code,
 	// this field can be used to set the message queue the tracer publishes to,
 	// usually an *mqsend.MockMessageQueue.
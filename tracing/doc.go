@@ -11,4 +11,9 @@
 // importing this package will call opentracing.SetGlobalTracer automatically
 // with a Tracer implementation that does not send spans anywhere.
 // Call InitGlobalTracer early in your main function to setup spans sampling.
+//
+// By default sampled spans are sent to the Baseplate.py tracing publishing
+// sidecar through a message queue.
+// Set Config.Exporter to ExporterOTLP to send them in batches directly to an
+// OpenTelemetry collector over OTLP/HTTP instead.
 package tracing
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"github.com/opentracing/opentracing-go"
+)
+
+// SpanLink is a reference from a span to another span that is causally
+// related to it but is not its parent, usually in another trace.
+//
+// For example, a consumer span processing a batch of messages can link to the
+// producer spans of all the messages in the batch.
+type SpanLink struct {
+	TraceID string
+	SpanID  string
+}
+
+// String returns the "<trace id>:<span id>" representation of the link.
+func (l SpanLink) String() string {
+	return l.TraceID + ":" + l.SpanID
+}
+
+// Link returns a SpanLink pointing to this span.
+func (s Span) Link() SpanLink {
+	return SpanLink{
+		TraceID: s.trace.traceID,
+		SpanID:  s.trace.spanID,
+	}
+}
+
+// AddLinks adds links to this span.
+//
+// Links with empty trace id or span id are ignored.
+func (s *Span) AddLinks(links ...SpanLink) {
+	for _, link := range links {
+		if link.TraceID == "" || link.SpanID == "" {
+			continue
+		}
+		s.trace.links = append(s.trace.links, link)
+	}
+}
+
+// Links returns the links added to this span.
+func (s Span) Links() []SpanLink {
+	return s.trace.links
+}
+
+// LinkFromHeaders creates a SpanLink to the span that sent the headers,
+// for example from the tracing headers of a message read from a message queue.
+//
+// The W3C traceparent and the Baseplate headers are used in the same
+// precedence as StartSpanFromHeaders.
+// ok is false when the headers don't have both the trace id and span id.
+func LinkFromHeaders(headers Headers) (link SpanLink, ok bool) {
+	if headers.useW3C() {
+		if tp, ok := headers.ParseTraceParent(); ok {
+			return SpanLink{TraceID: tp.TraceID, SpanID: tp.SpanID}, true
+		}
+	}
+	traceID, ok := headers.ParseTraceID()
+	if !ok {
+		return SpanLink{}, false
+	}
+	spanID, ok := headers.ParseSpanID()
+	if !ok {
+		return SpanLink{}, false
+	}
+	return SpanLink{TraceID: traceID, SpanID: spanID}, true
+}
+
+// LinksOption implements StartSpanOption to add links to the span.
+//
+// opentracing.FollowsFrom references to *Span are also added as links.
+type LinksOption struct {
+	nopOption
+
+	Links []SpanLink
+}
+
+// ApplyBP implements StartSpanOption.
+func (l LinksOption) ApplyBP(sso *StartSpanOptions) {
+	sso.Links = append(sso.Links, l.Links...)
+}
+
+var (
+	_ StartSpanOption = LinksOption{}
+)
+
+// followsFromLinks returns the links of all the FollowsFrom references in
+// refs.
+func followsFromLinks(refs []opentracing.SpanReference) []SpanLink {
+	var links []SpanLink
+	for _, ref := range refs {
+		if ref.Type != opentracing.FollowsFromRef {
+			continue
+		}
+		// Note that we only support using our type as the reference right now.
+		if span, ok := ref.ReferencedContext.(*Span); ok && span != nil {
+			links = append(links, span.Link())
+		}
+	}
+	return links
+}
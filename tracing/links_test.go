@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+func TestSpanLinks(t *testing.T) {
+	producer1 := AsSpan(opentracing.StartSpan("producer1"))
+	producer2 := AsSpan(opentracing.StartSpan("producer2"))
+	remote := SpanLink{TraceID: "12345", SpanID: "67890"}
+
+	span := AsSpan(opentracing.StartSpan(
+		"consumer",
+		LinksOption{Links: []SpanLink{remote}},
+		opentracing.FollowsFrom(producer1),
+	))
+	span.AddLinks(producer2.Link(), SpanLink{TraceID: "12345"})
+
+	want := []SpanLink{remote, producer1.Link(), producer2.Link()}
+	if got := span.Links(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Links expected %+v, got %+v", want, got)
+	}
+	if span.TraceID() == producer1.TraceID() {
+		t.Error("Expected FollowsFrom reference to not continue the trace")
+	}
+
+	zs := span.trace.toZipkinSpan()
+	if !reflect.DeepEqual(zs.Links, want) {
+		t.Errorf("ZipkinSpan.Links expected %+v, got %+v", want, zs.Links)
+	}
+	annotations := make(map[string]interface{})
+	for _, ba := range zs.BinaryAnnotations {
+		annotations[ba.Key] = ba.Value
+	}
+	for i, key := range []string{"link.0", "link.1", "link.2"} {
+		if got := annotations[key]; got != want[i].String() {
+			t.Errorf("Binary annotation %q expected %q, got %v", key, want[i].String(), got)
+		}
+	}
+}
+
+func TestLinkFromHeaders(t *testing.T) {
+	for _, c := range []struct {
+		label   string
+		headers Headers
+		want    SpanLink
+		ok      bool
+	}{
+		{
+			label: "baseplate",
+			headers: Headers{
+				TraceID: "12345",
+				SpanID:  "67890",
+			},
+			want: SpanLink{TraceID: "12345", SpanID: "67890"},
+			ok:   true,
+		},
+		{
+			label: "w3c",
+			headers: Headers{
+				TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			},
+			want: SpanLink{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"},
+			ok:   true,
+		},
+		{
+			label: "missing-span-id",
+			headers: Headers{
+				TraceID: "12345",
+			},
+		},
+		{
+			label: "empty",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			got, ok := LinkFromHeaders(c.headers)
+			if ok != c.ok || got != c.want {
+				t.Errorf("Expected %+v, %v, got %+v, %v", c.want, c.ok, got, ok)
+			}
+		})
+	}
+}
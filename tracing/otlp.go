@@ -0,0 +1,490 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Supported values of Config.Exporter.
+const (
+	// ExporterSidecar sends spans to the Baseplate.py tracing publishing sidecar
+	// through the message queue configured by Config.QueueName.
+	//
+	// This is the default.
+	ExporterSidecar = "sidecar"
+
+	// ExporterOTLP sends batched spans directly to an OpenTelemetry collector
+	// over OTLP/HTTP, configured by Config.OTLP.
+	ExporterOTLP = "otlp"
+)
+
+// Default values for OTLPConfig.
+const (
+	DefaultOTLPPath          = "/v1/traces"
+	DefaultOTLPTimeout       = 10 * time.Second
+	DefaultOTLPBatchSize     = 512
+	DefaultOTLPFlushInterval = 5 * time.Second
+	DefaultOTLPMaxQueueSize  = 2048
+)
+
+// OTLPScopeName is the instrumentation scope name attached to all the spans
+// exported via OTLP.
+const OTLPScopeName = "github.com/reddit/baseplate.go/tracing"
+
+// ErrOTLPMissingEndpoint is returned by InitGlobalTracer when the OTLP
+// exporter is selected without OTLPConfig.Endpoint.
+var ErrOTLPMissingEndpoint = errors.New("tracing: otlp.endpoint is required when exporter is otlp")
+
+// ErrOTLPQueueFull is returned by Tracer.Record when the OTLP exporter's queue
+// is still full after the record timeout.
+var ErrOTLPQueueFull = errors.New("tracing: otlp exporter queue is full")
+
+// ErrOTLPExporterClosed is returned by Tracer.Record when the OTLP exporter is
+// already closed.
+var ErrOTLPExporterClosed = errors.New("tracing: otlp exporter is closed")
+
+// OTLPConfig is the configuration for the OTLP/HTTP span exporter.
+//
+// Can be deserialized from YAML.
+type OTLPConfig struct {
+	// The URL of the collector, for example "http://localhost:4318".
+	//
+	// If the URL has no path, DefaultOTLPPath will be used.
+	Endpoint string `yaml:"endpoint"`
+
+	// Additional headers to be sent with every export request,
+	// for example authentication headers required by the collector.
+	Headers map[string]string `yaml:"headers"`
+
+	// The timeout of every export request.
+	//
+	// If it's <= 0, DefaultOTLPTimeout will be used.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// The max number of spans sent in a single export request.
+	//
+	// If it's <= 0, DefaultOTLPBatchSize will be used.
+	BatchSize int `yaml:"batchSize"`
+
+	// The max amount of time a span can stay in the queue before being
+	// exported, when the batch is not full.
+	//
+	// If it's <= 0, DefaultOTLPFlushInterval will be used.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+
+	// The max number of spans waiting to be exported.
+	//
+	// When the queue is full, Record blocks up to Config.MaxRecordTimeout before
+	// dropping the span.
+	//
+	// If it's <= 0, DefaultOTLPMaxQueueSize will be used.
+	MaxQueueSize int `yaml:"maxQueueSize"`
+}
+
+func (cfg OTLPConfig) withDefaults() OTLPConfig {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultOTLPTimeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOTLPBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultOTLPFlushInterval
+	}
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = DefaultOTLPMaxQueueSize
+	}
+	return cfg
+}
+
+func (cfg OTLPConfig) url() (string, error) {
+	if cfg.Endpoint == "" {
+		return "", ErrOTLPMissingEndpoint
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("tracing: invalid otlp.endpoint %q: %w", cfg.Endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = DefaultOTLPPath
+	}
+	return u.String(), nil
+}
+
+// otlpExporter converts ZipkinSpans into OTLP protobuf and sends them in
+// batches to an OTLP/HTTP collector from a background goroutine.
+type otlpExporter struct {
+	cfg      OTLPConfig
+	url      string
+	client   *http.Client
+	logger   log.Wrapper
+	resource []byte
+
+	spans     chan ZipkinSpan
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func newOTLPExporter(cfg OTLPConfig, endpoint ZipkinEndpointInfo, logger log.Wrapper) (*otlpExporter, error) {
+	u, err := cfg.url()
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	e := &otlpExporter{
+		cfg:      cfg,
+		url:      u,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		logger:   logger,
+		resource: otlpResource(endpoint),
+
+		spans:   make(chan ZipkinSpan, cfg.MaxQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// export queues zs to be exported.
+//
+// If the queue is full, it waits up to timeout for it to free up.
+// If timeout <= 0 it fails immediately instead.
+func (e *otlpExporter) export(ctx context.Context, zs ZipkinSpan, timeout time.Duration) error {
+	select {
+	case <-e.done:
+		return ErrOTLPExporterClosed
+	default:
+	}
+
+	if timeout <= 0 {
+		select {
+		case e.spans <- zs:
+			return nil
+		default:
+			return ErrOTLPQueueFull
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	select {
+	case e.spans <- zs:
+		return nil
+	case <-e.done:
+		return ErrOTLPExporterClosed
+	case <-ctx.Done():
+		return ErrOTLPQueueFull
+	}
+}
+
+// Close stops accepting new spans, and flushes the queued spans before
+// returning.
+func (e *otlpExporter) Close() error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+	<-e.stopped
+	return nil
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]ZipkinSpan, 0, e.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			e.logger.Log(context.Background(), fmt.Sprintf(
+				"Failed to export %d spans to otlp collector %q: %v",
+				len(batch),
+				e.url,
+				err,
+			))
+		}
+		batch = batch[:0]
+	}
+	add := func(zs ZipkinSpan) {
+		batch = append(batch, zs)
+		if len(batch) >= e.cfg.BatchSize {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case zs := <-e.spans:
+			add(zs)
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case zs := <-e.spans:
+					add(zs)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *otlpExporter) send(batch []ZipkinSpan) error {
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(e.encode(batch)))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("tracing: otlp collector responded with %s: %s", resp.Status, body)
+	}
+	return nil
+}
+
+// OTLP protobuf field numbers, from opentelemetry/proto/trace/v1/trace.proto
+// and opentelemetry/proto/common/v1/common.proto.
+const (
+	otlpRequestResourceSpans protowire.Number = 1
+
+	otlpResourceSpansResource   protowire.Number = 1
+	otlpResourceSpansScopeSpans protowire.Number = 2
+
+	otlpResourceAttributes protowire.Number = 1
+
+	otlpScopeSpansScope protowire.Number = 1
+	otlpScopeSpansSpans protowire.Number = 2
+
+	otlpScopeName protowire.Number = 1
+
+	otlpSpanTraceID      protowire.Number = 1
+	otlpSpanSpanID       protowire.Number = 2
+	otlpSpanParentSpanID protowire.Number = 4
+	otlpSpanName         protowire.Number = 5
+	otlpSpanKind         protowire.Number = 6
+	otlpSpanStartTime    protowire.Number = 7
+	otlpSpanEndTime      protowire.Number = 8
+	otlpSpanAttributes   protowire.Number = 9
+	otlpSpanEvents       protowire.Number = 11
+	otlpSpanLinks        protowire.Number = 13
+	otlpSpanStatus       protowire.Number = 15
+
+	otlpEventTime protowire.Number = 1
+	otlpEventName protowire.Number = 2
+
+	otlpLinkTraceID protowire.Number = 1
+	otlpLinkSpanID  protowire.Number = 2
+
+	otlpStatusCode protowire.Number = 3
+
+	otlpKeyValueKey   protowire.Number = 1
+	otlpKeyValueValue protowire.Number = 2
+
+	otlpAnyValueString protowire.Number = 1
+	otlpAnyValueBool   protowire.Number = 2
+	otlpAnyValueInt    protowire.Number = 3
+	otlpAnyValueDouble protowire.Number = 4
+)
+
+// OTLP enum values.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+
+	otlpStatusCodeError = 2
+)
+
+// encode encodes batch into an ExportTraceServiceRequest.
+func (e *otlpExporter) encode(batch []ZipkinSpan) []byte {
+	scope := appendString(nil, otlpScopeName, OTLPScopeName)
+	scopeSpans := appendMessage(nil, otlpScopeSpansScope, scope)
+	for _, zs := range batch {
+		scopeSpans = appendMessage(scopeSpans, otlpScopeSpansSpans, otlpSpan(zs))
+	}
+
+	resourceSpans := appendMessage(nil, otlpResourceSpansResource, e.resource)
+	resourceSpans = appendMessage(resourceSpans, otlpResourceSpansScopeSpans, scopeSpans)
+	return appendMessage(nil, otlpRequestResourceSpans, resourceSpans)
+}
+
+func otlpResource(endpoint ZipkinEndpointInfo) []byte {
+	b := appendKeyValue(nil, otlpResourceAttributes, "service.name", endpoint.ServiceName)
+	if endpoint.IPv4 != "" {
+		b = appendKeyValue(b, otlpResourceAttributes, "host.ip", endpoint.IPv4)
+	}
+	return b
+}
+
+func otlpSpan(zs ZipkinSpan) []byte {
+	start := time.Time(zs.Start)
+	end := start.Add(time.Duration(zs.Duration))
+
+	var b []byte
+	b = appendBytes(b, otlpSpanTraceID, otlpID(zs.TraceID, 16))
+	b = appendBytes(b, otlpSpanSpanID, otlpID(zs.SpanID, 8))
+	if zs.ParentID != "" {
+		b = appendBytes(b, otlpSpanParentSpanID, otlpID(zs.ParentID, 8))
+	}
+	b = appendString(b, otlpSpanName, zs.Name)
+	b = appendVarint(b, otlpSpanKind, otlpKind(zs))
+	b = appendFixed64(b, otlpSpanStartTime, uint64(start.UnixNano()))
+	b = appendFixed64(b, otlpSpanEndTime, uint64(end.UnixNano()))
+
+	var failed bool
+	for _, ba := range zs.BinaryAnnotations {
+		if strings.HasPrefix(ba.Key, linkKeyPrefix) {
+			// Exported as links below instead.
+			continue
+		}
+		b = appendKeyValue(b, otlpSpanAttributes, ba.Key, ba.Value)
+		if ba.Key == ZipkinBinaryAnnotationKeyError && fmt.Sprint(ba.Value) == "true" {
+			failed = true
+		}
+	}
+	for _, ta := range zs.TimeAnnotations {
+		switch ta.Key {
+		case ZipkinTimeAnnotationKeyServerReceive,
+			ZipkinTimeAnnotationKeyServerSend,
+			ZipkinTimeAnnotationKeyClientSend,
+			ZipkinTimeAnnotationKeyClientReceive:
+			// Already covered by span kind, start and end time.
+			continue
+		}
+		event := appendFixed64(nil, otlpEventTime, uint64(time.Time(ta.Timestamp).UnixNano()))
+		event = appendString(event, otlpEventName, ta.Key)
+		b = appendMessage(b, otlpSpanEvents, event)
+	}
+	for _, link := range zs.Links {
+		l := appendBytes(nil, otlpLinkTraceID, otlpID(link.TraceID, 16))
+		l = appendBytes(l, otlpLinkSpanID, otlpID(link.SpanID, 8))
+		b = appendMessage(b, otlpSpanLinks, l)
+	}
+	if failed {
+		status := appendVarint(nil, otlpStatusCode, otlpStatusCodeError)
+		b = appendMessage(b, otlpSpanStatus, status)
+	}
+	return b
+}
+
+func otlpKind(zs ZipkinSpan) uint64 {
+	for _, ta := range zs.TimeAnnotations {
+		switch ta.Key {
+		case ZipkinTimeAnnotationKeyServerReceive, ZipkinTimeAnnotationKeyServerSend:
+			return otlpSpanKindServer
+		case ZipkinTimeAnnotationKeyClientSend, ZipkinTimeAnnotationKeyClientReceive:
+			return otlpSpanKindClient
+		}
+	}
+	return otlpSpanKindInternal
+}
+
+// otlpID converts a trace or span id into its OTLP binary form of size bytes.
+//
+// Ids that are exactly 2*size hex digits are decoded as hex.
+// Otherwise decimal uint64 ids are used as the lower 8 bytes,
+// and shorter hex ids are left padded with zeros.
+// Ids that can't be parsed at all are hashed with FNV-1a,
+// so the same id always maps to the same value.
+func otlpID(id string, size int) []byte {
+	b := make([]byte, size)
+	if len(id) == 2*size {
+		if _, err := hex.Decode(b, []byte(id)); err == nil {
+			return b
+		}
+	}
+	if v, err := strconv.ParseUint(id, 10, 64); err == nil {
+		binary.BigEndian.PutUint64(b[size-8:], v)
+		return b
+	}
+	if len(id) <= 2*size && len(id)%2 == 0 {
+		if decoded, err := hex.DecodeString(id); err == nil {
+			copy(b[size-len(decoded):], decoded)
+			return b
+		}
+	}
+	h := fnv.New64a()
+	io.WriteString(h, id)
+	binary.BigEndian.PutUint64(b[size-8:], h.Sum64())
+	return b
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendKeyValue(b []byte, num protowire.Number, key string, value interface{}) []byte {
+	var anyValue []byte
+	switch v := value.(type) {
+	case string:
+		anyValue = appendString(nil, otlpAnyValueString, v)
+	case bool:
+		anyValue = appendVarint(nil, otlpAnyValueBool, protowire.EncodeBool(v))
+	case int:
+		anyValue = appendVarint(nil, otlpAnyValueInt, uint64(v))
+	case int64:
+		anyValue = appendVarint(nil, otlpAnyValueInt, uint64(v))
+	case float64:
+		anyValue = appendFixed64(nil, otlpAnyValueDouble, math.Float64bits(v))
+	default:
+		anyValue = appendString(nil, otlpAnyValueString, fmt.Sprint(v))
+	}
+	kv := appendString(nil, otlpKeyValueKey, key)
+	kv = appendMessage(kv, otlpKeyValueValue, anyValue)
+	return appendMessage(b, num, kv)
+}
@@ -0,0 +1,348 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type protoField struct {
+	num    protowire.Number
+	bytes  []byte
+	number uint64
+}
+
+// decodeProto does a shallow decode of a protobuf message.
+func decodeProto(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Failed to decode tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		f := protoField{num: num}
+		switch typ {
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			f.number, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.number, n = protowire.ConsumeFixed64(b)
+		default:
+			t.Fatalf("Unexpected wire type %v", typ)
+		}
+		if n < 0 {
+			t.Fatalf("Failed to decode field %d: %v", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func protoMessages(t *testing.T, b []byte, num protowire.Number) [][]byte {
+	t.Helper()
+	var msgs [][]byte
+	for _, f := range decodeProto(t, b) {
+		if f.num == num {
+			msgs = append(msgs, f.bytes)
+		}
+	}
+	return msgs
+}
+
+type otlpTestSpan struct {
+	traceID string
+	spanID  string
+	name    string
+	kind    uint64
+	failed  bool
+}
+
+// decodeOTLPSpans decodes the spans out of an ExportTraceServiceRequest.
+func decodeOTLPSpans(t *testing.T, req []byte) []otlpTestSpan {
+	t.Helper()
+	var spans []otlpTestSpan
+	for _, rs := range protoMessages(t, req, otlpRequestResourceSpans) {
+		for _, ss := range protoMessages(t, rs, otlpResourceSpansScopeSpans) {
+			for _, s := range protoMessages(t, ss, otlpScopeSpansSpans) {
+				var span otlpTestSpan
+				for _, f := range decodeProto(t, s) {
+					switch f.num {
+					case otlpSpanTraceID:
+						span.traceID = hex.EncodeToString(f.bytes)
+					case otlpSpanSpanID:
+						span.spanID = hex.EncodeToString(f.bytes)
+					case otlpSpanName:
+						span.name = string(f.bytes)
+					case otlpSpanKind:
+						span.kind = f.number
+					case otlpSpanStatus:
+						span.failed = bytes.Equal(f.bytes, appendVarint(nil, otlpStatusCode, otlpStatusCodeError))
+					}
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans
+}
+
+func TestOTLPID(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		id    string
+		size  int
+		want  string
+	}{
+		{
+			label: "hex-128",
+			id:    "0123456789abcdef0123456789abcdef",
+			size:  16,
+			want:  "0123456789abcdef0123456789abcdef",
+		},
+		{
+			label: "hex-64",
+			id:    "0123456789abcdef",
+			size:  8,
+			want:  "0123456789abcdef",
+		},
+		{
+			label: "hex-64-trace",
+			id:    "0123456789abcdef",
+			size:  16,
+			want:  "00000000000000000123456789abcdef",
+		},
+		{
+			label: "dec",
+			id:    "12345",
+			size:  8,
+			want:  "0000000000003039",
+		},
+		{
+			label: "dec-trace",
+			id:    "12345",
+			size:  16,
+			want:  "00000000000000000000000000003039",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			got := hex.EncodeToString(otlpID(c.id, c.size))
+			if got != c.want {
+				t.Errorf("otlpID(%q, %d) got %q, want %q", c.id, c.size, got, c.want)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		id := otlpID("not an id", 8)
+		if len(id) != 8 {
+			t.Errorf("Expected 8 bytes, got %d", len(id))
+		}
+		if !bytes.Equal(id, otlpID("not an id", 8)) {
+			t.Error("Expected invalid ids to be converted consistently")
+		}
+	})
+}
+
+func TestOTLPConfigURL(t *testing.T) {
+	for _, c := range []struct {
+		endpoint string
+		want     string
+		err      error
+	}{
+		{
+			endpoint: "http://localhost:4318",
+			want:     "http://localhost:4318/v1/traces",
+		},
+		{
+			endpoint: "http://localhost:4318/",
+			want:     "http://localhost:4318/v1/traces",
+		},
+		{
+			endpoint: "https://collector/custom/path",
+			want:     "https://collector/custom/path",
+		},
+		{
+			err: ErrOTLPMissingEndpoint,
+		},
+	} {
+		t.Run(c.endpoint, func(t *testing.T) {
+			got, err := OTLPConfig{Endpoint: c.endpoint}.url()
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+			if got != c.want {
+				t.Errorf("Expected url %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestInitGlobalTracerExporter(t *testing.T) {
+	t.Run("unsupported", func(t *testing.T) {
+		err := InitGlobalTracer(Config{Exporter: "foo"})
+		if err == nil {
+			t.Error("Expected error for unsupported exporter, got nil")
+		}
+	})
+
+	t.Run("otlp-missing-endpoint", func(t *testing.T) {
+		err := InitGlobalTracer(Config{Exporter: ExporterOTLP})
+		if !errors.Is(err, ErrOTLPMissingEndpoint) {
+			t.Errorf("Expected error %v, got %v", ErrOTLPMissingEndpoint, err)
+		}
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		requests int
+		spans    []otlpTestSpan
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DefaultOTLPPath {
+			t.Errorf("Expected path %q, got %q", DefaultOTLPPath, r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/x-protobuf" {
+			t.Errorf("Expected protobuf content type, got %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "token" {
+			t.Errorf("Expected Authorization header %q, got %q", "token", got)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read body: %v", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		spans = append(spans, decodeOTLPSpans(t, body)...)
+	}))
+	t.Cleanup(server.Close)
+
+	if err := InitGlobalTracer(Config{
+		Namespace:        "test-service",
+		SampleRate:       1,
+		MaxRecordTimeout: testTimeout,
+		UseHex:           true,
+		Exporter:         ExporterOTLP,
+		OTLP: OTLPConfig{
+			Endpoint:      server.URL,
+			Headers:       map[string]string{"Authorization": "token"},
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		InitGlobalTracer(Config{})
+	})
+
+	server1 := opentracing.StartSpan("server", SpanTypeOption{Type: SpanTypeServer}).(*Span)
+	client := opentracing.StartSpan(
+		"client",
+		opentracing.ChildOf(server1),
+		SpanTypeOption{Type: SpanTypeClient},
+	).(*Span)
+	client.FinishWithOptions(FinishOptions{
+		Ctx: context.Background(),
+		Err: errors.New("failed"),
+	}.Convert())
+	server1.Finish()
+	local := opentracing.StartSpan("local").(*Span)
+	local.Finish()
+
+	if err := CloseTracer(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if requests != 2 {
+		t.Errorf("Expected 2 export requests, got %d", requests)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %+v", spans)
+	}
+	want := []otlpTestSpan{
+		{
+			traceID: server1.trace.traceID,
+			spanID:  client.trace.spanID,
+			name:    "client",
+			kind:    otlpSpanKindClient,
+			failed:  true,
+		},
+		{
+			traceID: server1.trace.traceID,
+			spanID:  server1.trace.spanID,
+			name:    "server",
+			kind:    otlpSpanKindServer,
+		},
+		{
+			traceID: local.trace.traceID,
+			spanID:  local.trace.spanID,
+			name:    "local",
+			kind:    otlpSpanKindInternal,
+		},
+	}
+	for i := range want {
+		if spans[i] != want[i] {
+			t.Errorf("Span #%d expected %+v, got %+v", i, want[i], spans[i])
+		}
+	}
+}
+
+func TestOTLPSpanEventsAndLinks(t *testing.T) {
+	span := AsSpan(opentracing.StartSpan(
+		"test",
+		SpanTypeOption{Type: SpanTypeServer},
+		LinksOption{Links: []SpanLink{{TraceID: "12345", SpanID: "67890"}}},
+	))
+	span.LogEvent("foo")
+	span.Stop(context.Background(), nil)
+
+	var events, links [][]byte
+	for _, f := range decodeProto(t, otlpSpan(span.trace.toZipkinSpan())) {
+		switch f.num {
+		case otlpSpanEvents:
+			events = append(events, f.bytes)
+		case otlpSpanLinks:
+			links = append(links, f.bytes)
+		case otlpSpanAttributes:
+			key := protoMessages(t, f.bytes, otlpKeyValueKey)
+			if len(key) > 0 && string(key[0]) == "link.0" {
+				t.Error("Expected link binary annotation to not be exported as attribute")
+			}
+		}
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if name := protoMessages(t, events[0], otlpEventName); len(name) != 1 || string(name[0]) != "foo" {
+		t.Errorf("Expected event name %q, got %q", "foo", name)
+	}
+
+	if len(links) != 1 {
+		t.Fatalf("Expected 1 link, got %d", len(links))
+	}
+	traceID := protoMessages(t, links[0], otlpLinkTraceID)
+	if len(traceID) != 1 || !bytes.Equal(traceID[0], otlpID("12345", 16)) {
+		t.Errorf("Unexpected link trace id %x", traceID)
+	}
+}
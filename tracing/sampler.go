@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"golang.org/x/time/rate"
+
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// SamplingParams are the information about a new trace available to a
+// Sampler.
+type SamplingParams struct {
+	// Name is the name of the root span of the trace,
+	// usually the endpoint name for server spans.
+	Name string
+
+	// SpanType is the type of the root span of the trace.
+	SpanType SpanType
+}
+
+// Sampler decides whether a new trace started in this service should be
+// sampled.
+//
+// Traces continued from upstream headers always use the upstream sampling
+// decision instead.
+type Sampler interface {
+	ShouldSample(params SamplingParams) bool
+}
+
+// TailSampler is an optional interface a Sampler can implement to also sample
+// spans that were not sampled when they were started,
+// when they are stopped.
+//
+// Note that only the stopped span (and its ancestors, if they are sampled by
+// the TailSampler as well) will be recorded,
+// its already stopped child spans were not.
+type TailSampler interface {
+	Sampler
+
+	ShouldSampleOnStop(span *Span, err error) bool
+}
+
+// SamplerFunc is a Sampler implemented as a function.
+type SamplerFunc func(params SamplingParams) bool
+
+// ShouldSample implements Sampler.
+func (f SamplerFunc) ShouldSample(params SamplingParams) bool {
+	return f(params)
+}
+
+// ProbabilitySampler samples new traces with a fixed probability in [0, 1].
+//
+// This is the Sampler used with Config.SampleRate.
+type ProbabilitySampler float64
+
+// ShouldSample implements Sampler.
+func (s ProbabilitySampler) ShouldSample(SamplingParams) bool {
+	return randbp.ShouldSampleWithRate(float64(s))
+}
+
+// EndpointSampler samples new traces with per endpoint (root span name)
+// Samplers, and Default for the ones not in Endpoints.
+type EndpointSampler struct {
+	Endpoints map[string]Sampler
+
+	// If Default is nil, traces of endpoints not in Endpoints will not be
+	// sampled.
+	Default Sampler
+}
+
+// NewEndpointSampler creates an EndpointSampler with ProbabilitySamplers of
+// the given rates.
+func NewEndpointSampler(defaultRate float64, rates map[string]float64) EndpointSampler {
+	endpoints := make(map[string]Sampler, len(rates))
+	for name, r := range rates {
+		endpoints[name] = ProbabilitySampler(r)
+	}
+	return EndpointSampler{
+		Endpoints: endpoints,
+		Default:   ProbabilitySampler(defaultRate),
+	}
+}
+
+// ShouldSample implements Sampler.
+func (s EndpointSampler) ShouldSample(params SamplingParams) bool {
+	if sampler, ok := s.Endpoints[params.Name]; ok {
+		return sampler.ShouldSample(params)
+	}
+	if s.Default == nil {
+		return false
+	}
+	return s.Default.ShouldSample(params)
+}
+
+// RateLimitedSampler caps the number of new traces sampled by Sampler to at
+// most a fixed number per second.
+type RateLimitedSampler struct {
+	sampler Sampler
+	limiter *rate.Limiter
+}
+
+// NewRateLimitedSampler creates a RateLimitedSampler that allows at most
+// perSecond traces sampled by sampler per second.
+//
+// If sampler is nil, all new traces are candidates for sampling,
+// so it samples exactly up to perSecond traces per second.
+func NewRateLimitedSampler(sampler Sampler, perSecond float64) *RateLimitedSampler {
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimitedSampler{
+		sampler: sampler,
+		limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+	}
+}
+
+// ShouldSample implements Sampler.
+func (s *RateLimitedSampler) ShouldSample(params SamplingParams) bool {
+	if s.sampler != nil && !s.sampler.ShouldSample(params) {
+		return false
+	}
+	return s.limiter.Allow()
+}
+
+// ShouldSampleOnStop implements TailSampler by delegating to the wrapped
+// Sampler if it's a TailSampler.
+func (s *RateLimitedSampler) ShouldSampleOnStop(span *Span, err error) bool {
+	return shouldSampleOnStop(s.sampler, span, err)
+}
+
+// AlwaysSampleErrors wraps sampler into a TailSampler that also samples all
+// the spans stopped with a non-nil error.
+//
+// If sampler is nil, new traces are never sampled at start time.
+func AlwaysSampleErrors(sampler Sampler) TailSampler {
+	return errorSampler{sampler: sampler}
+}
+
+type errorSampler struct {
+	sampler Sampler
+}
+
+func (s errorSampler) ShouldSample(params SamplingParams) bool {
+	if s.sampler == nil {
+		return false
+	}
+	return s.sampler.ShouldSample(params)
+}
+
+func (s errorSampler) ShouldSampleOnStop(span *Span, err error) bool {
+	return err != nil || shouldSampleOnStop(s.sampler, span, err)
+}
+
+func shouldSampleOnStop(sampler Sampler, span *Span, err error) bool {
+	if tail, ok := sampler.(TailSampler); ok {
+		return tail.ShouldSampleOnStop(span, err)
+	}
+	return false
+}
+
+// SamplingConfig is the configuration for the Sampler used by
+// InitGlobalTracer, on top of Config.SampleRate.
+//
+// Can be deserialized from YAML, for example:
+//
+//	sampleRate: 0.01
+//	sampling:
+//	  endpointRates:
+//	    "/health": 0
+//	    "/v1/checkout": 0.5
+//	  maxTracesPerSecond: 20
+//	  alwaysSampleErrors: true
+type SamplingConfig struct {
+	// Sample rates of the endpoints (root span names) overriding
+	// Config.SampleRate.
+	EndpointRates map[string]float64 `yaml:"endpointRates"`
+
+	// If > 0, at most this many new traces will be sampled per second.
+	MaxTracesPerSecond float64 `yaml:"maxTracesPerSecond"`
+
+	// If true, spans stopped with an error are always sampled,
+	// regardless of the decision made when they were started.
+	AlwaysSampleErrors bool `yaml:"alwaysSampleErrors"`
+}
+
+// NewSampler creates the Sampler described by cfg,
+// using defaultRate for endpoints without a sample rate in cfg.
+func NewSampler(defaultRate float64, cfg SamplingConfig) Sampler {
+	var sampler Sampler = ProbabilitySampler(defaultRate)
+	if len(cfg.EndpointRates) > 0 {
+		sampler = NewEndpointSampler(defaultRate, cfg.EndpointRates)
+	}
+	if cfg.MaxTracesPerSecond > 0 {
+		sampler = NewRateLimitedSampler(sampler, cfg.MaxTracesPerSecond)
+	}
+	if cfg.AlwaysSampleErrors {
+		sampler = AlwaysSampleErrors(sampler)
+	}
+	return sampler
+}
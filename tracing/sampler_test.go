@@ -0,0 +1,165 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/mqsend"
+)
+
+func TestEndpointSampler(t *testing.T) {
+	sampler := NewEndpointSampler(0, map[string]float64{
+		"always": 1,
+		"never":  0,
+	})
+	for _, c := range []struct {
+		name string
+		want bool
+	}{
+		{name: "always", want: true},
+		{name: "never", want: false},
+		{name: "default", want: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				if got := sampler.ShouldSample(SamplingParams{Name: c.name}); got != c.want {
+					t.Fatalf("ShouldSample(%q) got %v, want %v", c.name, got, c.want)
+				}
+			}
+		})
+	}
+
+	t.Run("nil-default", func(t *testing.T) {
+		sampler := EndpointSampler{}
+		if sampler.ShouldSample(SamplingParams{Name: "foo"}) {
+			t.Error("Expected no sampling without Default")
+		}
+	})
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	t.Run("limited", func(t *testing.T) {
+		sampler := NewRateLimitedSampler(ProbabilitySampler(1), 3)
+		var sampled int
+		for i := 0; i < 10; i++ {
+			if sampler.ShouldSample(SamplingParams{}) {
+				sampled++
+			}
+		}
+		if sampled != 3 {
+			t.Errorf("Expected 3 sampled traces, got %d", sampled)
+		}
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		sampler := NewRateLimitedSampler(ProbabilitySampler(0), 3)
+		for i := 0; i < 10; i++ {
+			if sampler.ShouldSample(SamplingParams{}) {
+				t.Fatal("Expected traces not sampled by the wrapped sampler to be not sampled")
+			}
+		}
+	})
+}
+
+func TestNewSampler(t *testing.T) {
+	sampler := NewSampler(0, SamplingConfig{
+		EndpointRates:      map[string]float64{"foo": 1},
+		MaxTracesPerSecond: 1,
+		AlwaysSampleErrors: true,
+	})
+	if !sampler.ShouldSample(SamplingParams{Name: "foo"}) {
+		t.Error("Expected the first foo trace to be sampled")
+	}
+	if sampler.ShouldSample(SamplingParams{Name: "foo"}) {
+		t.Error("Expected the second foo trace to be rate limited")
+	}
+	if sampler.ShouldSample(SamplingParams{Name: "bar"}) {
+		t.Error("Expected bar trace to not be sampled")
+	}
+
+	tail, ok := sampler.(TailSampler)
+	if !ok {
+		t.Fatalf("Expected TailSampler, got %T", sampler)
+	}
+	if !tail.ShouldSampleOnStop(nil, errors.New("foo")) {
+		t.Error("Expected span with error to be sampled on stop")
+	}
+	if tail.ShouldSampleOnStop(nil, nil) {
+		t.Error("Expected span without error to not be sampled on stop")
+	}
+
+	if _, ok := NewSampler(0.5, SamplingConfig{}).(ProbabilitySampler); !ok {
+		t.Error("Expected ProbabilitySampler with empty SamplingConfig")
+	}
+}
+
+func TestTracerSampler(t *testing.T) {
+	recorder := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   10,
+		MaxMessageSize: MaxSpanSize,
+	})
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(Config{})
+	}()
+
+	var params []SamplingParams
+	if err := InitGlobalTracer(Config{
+		MaxRecordTimeout: testTimeout,
+		Sampler: AlwaysSampleErrors(SamplerFunc(func(p SamplingParams) bool {
+			params = append(params, p)
+			return p.Name == "sampled"
+		})),
+		TestOnlyMockMessageQueue: recorder,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(t *testing.T) (ZipkinSpan, bool) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+		msg, err := recorder.Receive(ctx)
+		if err != nil {
+			return ZipkinSpan{}, false
+		}
+		var zs ZipkinSpan
+		if err := json.Unmarshal(msg, &zs); err != nil {
+			t.Fatal(err)
+		}
+		return zs, true
+	}
+
+	t.Run("head", func(t *testing.T) {
+		span := AsSpan(opentracing.StartSpan("sampled", SpanTypeOption{Type: SpanTypeServer}))
+		span.Stop(context.Background(), nil)
+		if zs, ok := receive(t); !ok || zs.Name != "sampled" {
+			t.Errorf("Expected span to be recorded, got %+v, %v", zs, ok)
+		}
+
+		want := SamplingParams{Name: "sampled", SpanType: SpanTypeServer}
+		if len(params) == 0 || params[len(params)-1] != want {
+			t.Errorf("Expected sampler to be called with %+v, got %+v", want, params)
+		}
+	})
+
+	t.Run("not-sampled", func(t *testing.T) {
+		span := AsSpan(opentracing.StartSpan("other"))
+		span.Stop(context.Background(), nil)
+		if zs, ok := receive(t); ok {
+			t.Errorf("Expected span to not be recorded, got %+v", zs)
+		}
+	})
+
+	t.Run("tail-error", func(t *testing.T) {
+		span := AsSpan(opentracing.StartSpan("other"))
+		span.Stop(context.Background(), errors.New("foo"))
+		if zs, ok := receive(t); !ok || zs.Name != "other" {
+			t.Errorf("Expected span with error to be recorded, got %+v, %v", zs, ok)
+		}
+	})
+}
@@ -241,6 +241,13 @@ func (s Span) initChildSpan(child *Span) {
 	child.trace.traceID = s.trace.traceID
 	child.trace.sampled = s.trace.sampled
 	child.trace.flags = s.trace.flags
+	child.trace.traceState = s.trace.traceState
+	if len(s.trace.baggage) > 0 {
+		child.trace.baggage = make(map[string]string, len(s.trace.baggage))
+		for k, v := range s.trace.baggage {
+			child.trace.baggage[k] = v
+		}
+	}
 	child.hub = s.hub
 
 	if child.spanType != SpanTypeServer {
@@ -265,7 +272,14 @@ func (s Span) initChildSpan(child *Span) {
 // In most cases FinishWithOptions should be used instead,
 // which calls Stop and auto logs the error returned by Stop.
 // Stop is still provided in case there's need to handle the error differently.
+//
+// If the Span was not sampled, but the configured Sampler is a TailSampler
+// that decides to sample it on stop (for example AlwaysSampleErrors),
+// it will be sampled.
 func (s *Span) Stop(ctx context.Context, err error) error {
+	if !s.trace.shouldSample() && s.trace.tracer != nil && s.trace.tracer.shouldSampleOnStop(s, err) {
+		s.trace.sampled = true
+	}
 	s.preStop(err)
 	for _, h := range s.hooks {
 		if hook, ok := h.(StartStopSpanHook); ok {
@@ -305,26 +319,6 @@ func (s Span) getHub() *sentry.Hub {
 	return getNopHub()
 }
 
-// ForeachBaggageItem implements opentracing.SpanContext.
-//
-// We don't support any extra baggage items, so it's a noop.
-func (s *Span) ForeachBaggageItem(handler func(k, v string) bool) {}
-
-// SetBaggageItem implements opentracing.Span.
-//
-// As we don't support any extra baggage items,
-// it's a noop and just returns self.
-func (s *Span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
-	return s
-}
-
-// BaggageItem implements opentracing.Span.
-//
-// As we don't support any extra baggage items, it always returns empty string.
-func (s *Span) BaggageItem(restrictedKey string) string {
-	return ""
-}
-
 // Finish implements opentracing.Span.
 //
 // It calls Stop with background context and nil error.
@@ -338,9 +332,9 @@ func (s *Span) Finish() {
 
 // FinishWithOptions implements opentracing.Span.
 //
-// In this implementation we ignore all timestamps in opts,
-// only extract context and error out of all the log fields,
-// and ignore all other log fields.
+// In this implementation we extract context and error out of all the log
+// fields, and record all other log fields as annotations of the span,
+// the same way as LogFields.
 //
 // Please use FinishOptions.Convert() to prepare the opts arg.
 //
@@ -351,6 +345,7 @@ func (s *Span) FinishWithOptions(opts opentracing.FinishOptions) {
 		s.trace.stop = opts.FinishTime
 	}
 	var err error
+	var logs []otlog.Field
 	ctx := context.Background()
 	for _, records := range opts.LogRecords {
 		for _, field := range records.Fields {
@@ -363,8 +358,12 @@ func (s *Span) FinishWithOptions(opts opentracing.FinishOptions) {
 				if e, ok := field.Value().(error); ok {
 					err = e
 				}
+			default:
+				logs = append(logs, field)
 			}
 		}
+		s.trace.addLog(records.Timestamp, logs)
+		logs = logs[:0]
 	}
 	if stopErr := s.Stop(ctx, err); stopErr != nil {
 		s.logError(ctx, "Span.Stop returned error: ", stopErr)
@@ -391,28 +390,51 @@ func (s *Span) Tracer() opentracing.Tracer {
 
 // LogFields implements opentracing.Span.
 //
-// In this implementation it's a no-op.
-func (s *Span) LogFields(fields ...otlog.Field) {}
+// The fields are recorded as a timestamped annotation of the span,
+// see LogKV for the format.
+func (s *Span) LogFields(fields ...otlog.Field) {
+	s.trace.addLog(time.Now(), fields)
+}
 
 // LogKV implements opentracing.Span.
 //
-// In this implementation it's a no-op.
-func (s *Span) LogKV(alternatingKeyValues ...interface{}) {}
+// The key-value pairs are recorded as a timestamped annotation of the span.
+// If the only key is "event", the value of the annotation is the event,
+// otherwise it's the space separated "key=value" pairs.
+//
+// At most MaxSpanLogs annotations are recorded for each span,
+// the rest are dropped.
+// Values longer than MaxSpanLogValueLength bytes are truncated.
+func (s *Span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := otlog.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.logError(context.Background(), "LogKV error: ", err)
+		return
+	}
+	s.LogFields(fields...)
+}
 
 // LogEvent implements opentracing.Span.
 //
-// it's deprecated in the interface and is a no-op here.
-func (s *Span) LogEvent(event string) {}
+// It's deprecated in the interface, use LogKV("event", event) instead.
+func (s *Span) LogEvent(event string) {
+	s.LogFields(otlog.String(logEventKey, event))
+}
 
 // LogEventWithPayload implements opentracing.Span.
 //
-// it's deprecated in the interface and is a noop here.
-func (s *Span) LogEventWithPayload(event string, payload interface{}) {}
+// It's deprecated in the interface, use LogKV instead.
+func (s *Span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(otlog.String(logEventKey, event), otlog.Object("payload", payload))
+}
 
 // Log implements opentracing.Span.
 //
-// it's deprecated in the interface and is a no-op here.
-func (s *Span) Log(data opentracing.LogData) {}
+// It's deprecated in the interface, use LogKV instead.
+func (s *Span) Log(data opentracing.LogData) {
+	record := data.ToLogRecord()
+	s.trace.addLog(record.Timestamp, record.Fields)
+}
 
 // StartTopLevelServerSpan initializes a new, top level server span.
 //
@@ -444,14 +466,35 @@ type Headers struct {
 	// Sampled is whether this span was sampled by the upstream caller.  Uses
 	// a pointer to a bool so it can distinguish between set/not-set.
 	Sampled *bool
+
+	// TraceParent is the W3C traceparent header passed via upstream headers.
+	//
+	// When both TraceParent and TraceID are set,
+	// Config.HeaderPrecedence decides which one is used.
+	TraceParent string
+
+	// TraceState is the W3C tracestate header passed via upstream headers.
+	TraceState string
+
+	// Baggage is the W3C baggage header passed via upstream headers.
+	//
+	// Only the keys allowed by Config.Baggage are kept.
+	Baggage string
 }
 
 // AnySet returns true if any of the values in the Headers are set, false otherwise.
 func (h Headers) AnySet() bool {
+	return h.traceSet() || h.Baggage != ""
+}
+
+// traceSet returns true if any of the trace values in the Headers are set,
+// false otherwise.
+func (h Headers) traceSet() bool {
 	return h.TraceID != "" ||
 		h.SpanID != "" ||
 		h.Flags != "" ||
-		h.Sampled != nil
+		h.Sampled != nil ||
+		h.TraceParent != ""
 }
 
 // ParseTraceID attempts to validate h.TraceID, if it succeeds it returns the
@@ -536,6 +579,10 @@ func (h Headers) ParseSampled() (sampled bool, ok bool) {
 // StartSpanFromHeaders creates a server span from the passed in Headers. If no
 // headers are set, then a new top-level server span will be created and returned.
 //
+// Both the Baseplate and the W3C trace headers are supported.
+// When both are set, Config.HeaderPrecedence decides which one is used.
+// A malformed traceparent header falls back to the Baseplate headers.
+//
 // Please note that "Sampled" header is default to false according to baseplate
 // spec, so if the headers are incorrect, this span (and all its child-spans)
 // will never be sampled, unless debug flag was set explicitly later.
@@ -547,6 +594,12 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 	if !headers.AnySet() {
 		return StartTopLevelServerSpan(ctx, name)
 	}
+	if !headers.traceSet() {
+		// Only baggage was passed from upstream, start a new trace with it.
+		ctx, span := StartTopLevelServerSpan(ctx, name)
+		span.trace.baggage = globalTracer.baggage.decode(headers.Baggage)
+		return ctx, span
+	}
 
 	span := newSpan(nil, name, SpanTypeServer)
 	defer func() {
@@ -556,22 +609,38 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 
 	ctx = opentracing.ContextWithSpan(ctx, span)
 
-	if id, ok := headers.ParseTraceID(); ok {
-		span.trace.traceID = id
-	}
+	tp, w3c := TraceParent{}, false
+	if headers.useW3C() {
+		tp, w3c = headers.ParseTraceParent()
+	}
+	if w3c {
+		span.trace.traceID = tp.TraceID
+		span.trace.parentID = tp.SpanID
+		span.trace.sampled = tp.Sampled
+	} else {
+		if id, ok := headers.ParseTraceID(); ok {
+			span.trace.traceID = id
+		}
 
-	if id, ok := headers.ParseSpanID(); ok {
-		span.trace.parentID = id
+		if id, ok := headers.ParseSpanID(); ok {
+			span.trace.parentID = id
+		}
+
+		if sampled, ok := headers.ParseSampled(); ok {
+			span.trace.sampled = sampled
+		}
 	}
 
 	if flags, ok := headers.ParseFlags(); ok {
 		span.trace.flags = flags
 	}
 
-	if sampled, ok := headers.ParseSampled(); ok {
-		span.trace.sampled = sampled
+	if state, ok := headers.ParseTraceState(); ok {
+		span.trace.traceState = state
 	}
 
+	span.trace.baggage = globalTracer.baggage.decode(headers.Baggage)
+
 	ctx = initRootSpan(ctx, span)
 
 	return ctx, span
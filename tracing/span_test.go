@@ -7,8 +7,10 @@ import (
 	"testing"
 	"testing/quick"
 	"time"
+	"unicode/utf8"
 
 	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
 
 	"github.com/reddit/baseplate.go/randbp"
 )
@@ -430,6 +432,13 @@ func TestHeadersAnySet(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "baggage-set",
+			headers: Headers{
+				Baggage: "foo=bar",
+			},
+			expected: true,
+		},
 		{
 			name: "all-set-invalid",
 			headers: Headers{
@@ -774,3 +783,64 @@ func TestSpanMetricsTags(t *testing.T) {
 		t.Errorf("Expected %v, got %v", expected, tags)
 	}
 }
+
+func TestSpanLogs(t *testing.T) {
+	span := AsSpan(opentracing.StartSpan("test", SpanTypeOption{Type: SpanTypeServer}))
+	span.LogKV("event", "cache-miss")
+	span.LogKV("key", "foo", "attempt", 2)
+	span.LogKV("odd")
+	span.LogEvent("retry")
+	span.LogFields()
+	opts := FinishOptions{}.Convert()
+	opts.LogRecords = append(opts.LogRecords, opentracing.LogRecord{
+		Timestamp: time.Unix(1, 0),
+		Fields:    []otlog.Field{otlog.String("event", "finish")},
+	})
+	span.FinishWithOptions(opts)
+
+	var got []string
+	for _, ta := range span.trace.toZipkinSpan().TimeAnnotations {
+		got = append(got, ta.Key)
+	}
+	want := []string{
+		ZipkinTimeAnnotationKeyServerReceive,
+		ZipkinTimeAnnotationKeyServerSend,
+		"cache-miss",
+		"key=foo attempt=2",
+		"retry",
+		"finish",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Time annotations expected %q, got %q", want, got)
+	}
+
+	t.Run("max", func(t *testing.T) {
+		span := AsSpan(opentracing.StartSpan("test"))
+		for i := 0; i < MaxSpanLogs+10; i++ {
+			span.LogEvent("foo")
+		}
+		if got := len(span.trace.toZipkinSpan().TimeAnnotations); got != MaxSpanLogs {
+			t.Errorf("Expected %d time annotations, got %d", MaxSpanLogs, got)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		span := AsSpan(opentracing.StartSpan("test"))
+		// "é" is 2 bytes, make sure the truncation doesn't break it.
+		span.LogEvent(strings.Repeat("é", MaxSpanLogValueLength))
+		annotations := span.trace.toZipkinSpan().TimeAnnotations
+		if len(annotations) != 1 {
+			t.Fatalf("Expected 1 time annotation, got %d", len(annotations))
+		}
+		value := annotations[0].Key
+		if len(value) > MaxSpanLogValueLength {
+			t.Errorf("Expected value length <= %d, got %d", MaxSpanLogValueLength, len(value))
+		}
+		if !strings.HasSuffix(value, truncatedSuffix) {
+			t.Errorf("Expected value to end with %q, got %q", truncatedSuffix, value)
+		}
+		if !utf8.ValidString(value) {
+			t.Errorf("Expected valid utf-8 value, got %q", value)
+		}
+	})
+}
@@ -9,6 +9,8 @@ type StartSpanOptions struct {
 	OpenTracingOptions opentracing.StartSpanOptions
 
 	Type SpanType
+
+	Links []SpanLink
 }
 
 // Apply calls opt.Apply against sso.OpenTracingOptions.
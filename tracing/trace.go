@@ -3,7 +3,12 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	otlog "github.com/opentracing/opentracing-go/log"
 
 	"github.com/reddit/baseplate.go/randbp"
 	"github.com/reddit/baseplate.go/timebp"
@@ -27,9 +32,26 @@ const (
 
 const (
 	counterKeyPrefix   = "counter."
+	linkKeyPrefix      = "link."
+	logEventKey        = "event"
 	baseplateComponent = "baseplate"
 )
 
+// MaxSpanLogs is the max number of logs recorded as time annotations for a
+// single span, the rest are dropped.
+const MaxSpanLogs = 128
+
+// MaxSpanLogValueLength is the max length in bytes of the value of a single
+// time annotation recorded from logs, longer values are truncated.
+//
+// Together with MaxSpanLogs it keeps the logs of a span well below
+// MaxSpanSize, so large payloads don't cause the whole span to be dropped.
+const MaxSpanLogValueLength = 512
+
+// truncatedSuffix is appended to log values truncated to
+// MaxSpanLogValueLength.
+const truncatedSuffix = "...(truncated)"
+
 type trace struct {
 	tracer *Tracer
 
@@ -40,6 +62,9 @@ type trace struct {
 	sampled  bool
 	flags    int64
 
+	traceState string
+	baggage    map[string]string
+
 	timeAnnotationReceiveKey string
 	timeAnnotationSendKey    string
 	start                    time.Time
@@ -47,6 +72,14 @@ type trace struct {
 
 	counters map[string]float64
 	tags     map[string]string
+
+	logs  []spanLog
+	links []SpanLink
+}
+
+type spanLog struct {
+	timestamp time.Time
+	value     string
 }
 
 func newTrace(tracer *Tracer, name string) *trace {
@@ -76,6 +109,49 @@ func (t *trace) setTag(key string, value interface{}) {
 	t.tags[key] = fmt.Sprintf("%v", value)
 }
 
+func (t *trace) addLog(timestamp time.Time, fields []otlog.Field) {
+	if len(fields) == 0 || len(t.logs) >= MaxSpanLogs {
+		return
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	t.logs = append(t.logs, spanLog{
+		timestamp: timestamp,
+		value:     truncateLogValue(formatLogFields(fields)),
+	})
+}
+
+// truncateLogValue truncates v to at most MaxSpanLogValueLength bytes,
+// without breaking multi-byte utf-8 runes.
+func truncateLogValue(v string) string {
+	if len(v) <= MaxSpanLogValueLength {
+		return v
+	}
+	n := MaxSpanLogValueLength - len(truncatedSuffix)
+	for n > 0 && !utf8.RuneStart(v[n]) {
+		n--
+	}
+	return v[:n] + truncatedSuffix
+}
+
+// formatLogFields formats log fields into the value of a time annotation.
+func formatLogFields(fields []otlog.Field) string {
+	if len(fields) == 1 && fields[0].Key() == logEventKey {
+		return fmt.Sprint(fields[0].Value())
+	}
+	var sb strings.Builder
+	for i, field := range fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(field.Key())
+		sb.WriteByte('=')
+		sb.WriteString(fmt.Sprint(field.Value()))
+	}
+	return sb.String()
+}
+
 func (t *trace) toZipkinSpan() ZipkinSpan {
 	zs := ZipkinSpan{
 		TraceID:  t.traceID,
@@ -111,7 +187,15 @@ func (t *trace) toZipkinSpan() ZipkinSpan {
 		})
 	}
 
-	zs.BinaryAnnotations = make([]ZipkinBinaryAnnotation, 0, len(t.counters)+len(t.tags))
+	for _, l := range t.logs {
+		zs.TimeAnnotations = append(zs.TimeAnnotations, ZipkinTimeAnnotation{
+			Endpoint:  endpoint,
+			Key:       l.value,
+			Timestamp: timebp.TimestampMicrosecond(l.timestamp),
+		})
+	}
+
+	zs.BinaryAnnotations = make([]ZipkinBinaryAnnotation, 0, len(t.counters)+len(t.tags)+len(t.links))
 	for key, value := range t.counters {
 		zs.BinaryAnnotations = append(
 			zs.BinaryAnnotations,
@@ -133,6 +217,18 @@ func (t *trace) toZipkinSpan() ZipkinSpan {
 		)
 	}
 
+	for i, link := range t.links {
+		zs.BinaryAnnotations = append(
+			zs.BinaryAnnotations,
+			ZipkinBinaryAnnotation{
+				Endpoint: endpoint,
+				Key:      linkKeyPrefix + strconv.Itoa(i),
+				Value:    link.String(),
+			},
+		)
+	}
+	zs.Links = t.links
+
 	return zs
 }
 
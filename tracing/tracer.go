@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"strconv"
 	"time"
 
@@ -63,18 +64,33 @@ func init() {
 			next(opentracing.ContextWithSpan(dst, child))
 		},
 	})
+	// Add the span ID to the records logged by log.SlogHandler.
+	// The trace ID is already attached to the logger by initRootSpan.
+	log.RegisterContextAttrs(func(ctx context.Context) []slog.Attr {
+		if span, ok := opentracing.SpanFromContext(ctx).(*Span); ok && span != nil {
+			return []slog.Attr{slog.String(SpanIDLogKey, span.ID())}
+		}
+		return nil
+	})
 }
 
+// SpanIDLogKey is the key of the span ID added to the records logged by
+// log.SlogHandler.
+const SpanIDLogKey = "spanID"
+
 var globalTracer = Tracer{logger: log.NopWrapper}
 
 // A Tracer creates and manages spans.
 type Tracer struct {
-	sampleRate       float64
+	sampler          Sampler
 	recorder         mqsend.MessageQueue
+	exporter         *otlpExporter
 	logger           log.Wrapper
 	endpoint         ZipkinEndpointInfo
 	maxRecordTimeout time.Duration
 	useHex           bool
+	headerPrecedence string
+	baggage          baggagePolicy
 }
 
 // InitGlobalTracer initializes opentracing's global tracer.
@@ -89,25 +105,15 @@ func InitGlobalTracer(cfg Config) error {
 		return nil
 	}
 	var tracer Tracer
-	if cfg.QueueName != "" {
-		if cfg.MaxQueueSize <= 0 || cfg.MaxQueueSize > MaxQueueSize {
-			cfg.MaxQueueSize = MaxQueueSize
-		}
-		recorder, err := mqsend.OpenMessageQueue(mqsend.MessageQueueConfig{
-			Name:           QueueNamePrefix + cfg.QueueName,
-			MaxQueueSize:   cfg.MaxQueueSize,
-			MaxMessageSize: MaxSpanSize,
-		})
-		if err != nil {
-			return err
-		}
-		tracer.recorder = recorder
-	} else {
-		tracer.recorder = cfg.TestOnlyMockMessageQueue
-	}
 
-	tracer.sampleRate = cfg.SampleRate
-	tracer.useHex = cfg.UseHex
+	switch cfg.HeaderPrecedence {
+	default:
+		return fmt.Errorf("tracing: unsupported header precedence %q", cfg.HeaderPrecedence)
+	case "", PrecedenceBaseplate:
+		tracer.headerPrecedence = PrecedenceBaseplate
+	case PrecedenceW3C:
+		tracer.headerPrecedence = PrecedenceW3C
+	}
 
 	logger := cfg.Logger
 	if logger == nil {
@@ -115,8 +121,6 @@ func InitGlobalTracer(cfg Config) error {
 	}
 	tracer.logger = logger
 
-	tracer.maxRecordTimeout = cfg.MaxRecordTimeout
-
 	ip, err := runtimebp.GetFirstIPv4()
 	if err != nil {
 		logger(context.Background(), `Unable to get local ip address: `+err.Error())
@@ -126,11 +130,54 @@ func InitGlobalTracer(cfg Config) error {
 		IPv4:        ip,
 	}
 
+	switch cfg.Exporter {
+	default:
+		return fmt.Errorf("tracing: unsupported exporter %q", cfg.Exporter)
+	case ExporterOTLP:
+		exporter, err := newOTLPExporter(cfg.OTLP, tracer.endpoint, logger)
+		if err != nil {
+			return err
+		}
+		tracer.exporter = exporter
+	case "", ExporterSidecar:
+		if err := tracer.initRecorder(cfg); err != nil {
+			return err
+		}
+	}
+
+	tracer.sampler = cfg.Sampler
+	if tracer.sampler == nil {
+		tracer.sampler = NewSampler(cfg.SampleRate, cfg.Sampling)
+	}
+	tracer.useHex = cfg.UseHex
+	tracer.baggage = newBaggagePolicy(cfg.Baggage)
+	tracer.maxRecordTimeout = cfg.MaxRecordTimeout
+
 	globalTracer = tracer
 	opentracing.SetGlobalTracer(&globalTracer)
 	return nil
 }
 
+func (t *Tracer) initRecorder(cfg Config) error {
+	if cfg.QueueName != "" {
+		if cfg.MaxQueueSize <= 0 || cfg.MaxQueueSize > MaxQueueSize {
+			cfg.MaxQueueSize = MaxQueueSize
+		}
+		recorder, err := mqsend.OpenMessageQueue(mqsend.MessageQueueConfig{
+			Name:           QueueNamePrefix + cfg.QueueName,
+			MaxQueueSize:   cfg.MaxQueueSize,
+			MaxMessageSize: MaxSpanSize,
+		})
+		if err != nil {
+			return err
+		}
+		t.recorder = recorder
+	} else {
+		t.recorder = cfg.TestOnlyMockMessageQueue
+	}
+	return nil
+}
+
 type closer struct{}
 
 func (closer) Close() error {
@@ -153,6 +200,11 @@ func InitGlobalTracerWithCloser(cfg Config) (io.Closer, error) {
 //
 // After Close is called, no more spans will be sampled.
 func (t *Tracer) Close() error {
+	if t.exporter != nil {
+		err := t.exporter.Close()
+		t.exporter = nil
+		return err
+	}
 	if t.recorder == nil {
 		return nil
 	}
@@ -163,11 +215,27 @@ func (t *Tracer) Close() error {
 
 // Record records a span with the Recorder.
 //
+// When the OTLP exporter is configured the span is queued to be exported in the
+// next batch instead.
+//
 // Span.Stop(), Span.Finish(), and Span.FinishWithOptions() call this function
 // automatically.
 // In most cases that should be enough and you should not call this function
 // directly.
 func (t *Tracer) Record(ctx context.Context, zs ZipkinSpan) error {
+	if t.exporter != nil {
+		if ctx.Err() != nil {
+			ctx = context.Background()
+		}
+		err := t.exporter.export(ctx, zs, t.maxRecordTimeout)
+		if errors.Is(err, ErrOTLPQueueFull) {
+			t.logger.Log(
+				ctx,
+				"OTLP exporter queue is full. Is the collector healthy? Error: "+err.Error(),
+			)
+		}
+		return err
+	}
 	if t.recorder == nil {
 		return nil
 	}
@@ -213,6 +281,9 @@ func (t *Tracer) Record(ctx context.Context, zs ZipkinSpan) error {
 //
 // - Tags
 //
+// - FollowsFromRef (in which case the referenced span must be of type *Span,
+// and it's added as a link)
+//
 // It supports additional StartSpanOptions defined in this package.
 //
 // If the new span's type is server,
@@ -236,7 +307,10 @@ func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOp
 		parent.initChildSpan(span)
 	} else {
 		span.trace.traceID = t.newTraceID()
-		span.trace.sampled = randbp.ShouldSampleWithRate(t.sampleRate)
+		span.trace.sampled = t.shouldSample(SamplingParams{
+			Name:     operationName,
+			SpanType: sso.Type,
+		})
 		initRootSpan(context.Background(), span)
 	}
 
@@ -250,6 +324,9 @@ func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOp
 		span.SetTag(key, value)
 	}
 
+	span.AddLinks(sso.Links...)
+	span.AddLinks(followsFromLinks(sso.OpenTracingOptions.References)...)
+
 	return span
 }
 
@@ -267,6 +344,17 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.S
 	return nil, opentracing.ErrInvalidCarrier
 }
 
+func (t *Tracer) shouldSample(params SamplingParams) bool {
+	if t.sampler == nil {
+		return false
+	}
+	return t.sampler.ShouldSample(params)
+}
+
+func (t *Tracer) shouldSampleOnStop(span *Span, err error) bool {
+	return shouldSampleOnStop(t.sampler, span, err)
+}
+
 func (t *Tracer) newTraceID() string {
 	if t.useHex {
 		// For traces we just combine two 64-bit hex ids to get a 128-bit hex id.
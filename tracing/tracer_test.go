@@ -3,11 +3,14 @@ package tracing
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"testing"
 	"testing/quick"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/mqsend"
@@ -167,3 +170,24 @@ func BenchmarkHexID64(b *testing.B) {
 		}
 	})
 }
+
+func TestSlogSpanID(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := slog.New(log.NewSlogHandler(core))
+
+	span, ctx := opentracing.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+	logger.InfoContext(ctx, "msg")
+	logger.Info("no span")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(entries))
+	}
+	if got, want := entries[0].ContextMap()[SpanIDLogKey], AsSpan(span).ID(); got != want {
+		t.Errorf("Expected span ID %q, got %v", want, got)
+	}
+	if _, ok := entries[1].ContextMap()[SpanIDLogKey]; ok {
+		t.Errorf("Expected no span ID without span, got %v", entries[1].ContextMap())
+	}
+}
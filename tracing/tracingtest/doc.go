@@ -0,0 +1,17 @@
+// Package tracingtest provides an in-memory span recorder to help testing
+// code creating tracing spans.
+//
+// Call Setup at the beginning of a test to initialize the global tracer with a
+// Recorder, then use the query helpers on the Recorder to assert on the
+// recorded spans:
+//
+//	recorder := tracingtest.Setup(t, tracing.Config{})
+//	// Call the code under test.
+//	span := recorder.MustFindOne(t, "endpoint")
+//	if span.Failed() {
+//	  t.Error("endpoint span failed")
+//	}
+//	if got, want := recorder.Tree().String(), "endpoint\n  service.call\n"; got != want {
+//	  t.Errorf("span tree got %q, want %q", got, want)
+//	}
+package tracingtest
@@ -0,0 +1,234 @@
+package tracingtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Recorder records the spans sent by the tracer in memory.
+//
+// It implements mqsend.MessageQueue so it can be used as
+// tracing.Config.TestOnlyMockMessageQueue,
+// but usually it should be created by Setup instead.
+//
+// It's safe to be used concurrently.
+type Recorder struct {
+	lock    sync.Mutex
+	spans   []Span
+	updated chan struct{}
+}
+
+var _ mqsend.MessageQueue = (*Recorder)(nil)
+
+// NewRecorder creates a new, empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		updated: make(chan struct{}),
+	}
+}
+
+// Setup creates a new Recorder and initializes the global tracer with cfg to
+// record spans into it.
+//
+// If cfg has neither SampleRate nor Sampler set, all traces will be sampled.
+//
+// The global tracer is closed and reset when the test finishes.
+func Setup(tb testing.TB, cfg tracing.Config) *Recorder {
+	tb.Helper()
+
+	recorder := NewRecorder()
+	cfg.Exporter = tracing.ExporterSidecar
+	cfg.QueueName = ""
+	cfg.TestOnlyMockMessageQueue = recorder
+	if cfg.SampleRate == 0 && cfg.Sampler == nil {
+		cfg.SampleRate = 1
+	}
+	if err := tracing.InitGlobalTracer(cfg); err != nil {
+		tb.Fatalf("tracingtest: failed to init global tracer: %v", err)
+	}
+	tb.Cleanup(func() {
+		tracing.CloseTracer()
+		tracing.InitGlobalTracer(tracing.Config{})
+	})
+	return recorder
+}
+
+// Send implements mqsend.MessageQueue.
+//
+// It decodes data as a tracing.ZipkinSpan and records it.
+func (r *Recorder) Send(_ context.Context, data []byte) error {
+	var span Span
+	if err := json.Unmarshal(data, &span.ZipkinSpan); err != nil {
+		return fmt.Errorf("tracingtest: failed to decode span: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = append(r.spans, span)
+	close(r.updated)
+	r.updated = make(chan struct{})
+	return nil
+}
+
+// Close implements mqsend.MessageQueue.
+//
+// It's a no-op, and the recorded spans are still available after Close.
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Reset drops all the recorded spans.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = nil
+}
+
+// Spans returns all the recorded spans, in the order they were stopped.
+func (r *Recorder) Spans() []Span {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	spans := make([]Span, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Wait blocks until at least n spans are recorded, or ctx is done.
+//
+// It's useful when the spans are stopped in another goroutine,
+// for example server spans are stopped after the response is sent to the
+// client.
+func (r *Recorder) Wait(ctx context.Context, n int) error {
+	for {
+		r.lock.Lock()
+		count := len(r.spans)
+		updated := r.updated
+		r.lock.Unlock()
+
+		if count >= n {
+			return nil
+		}
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return fmt.Errorf("tracingtest: got %d spans, want %d: %w", count, n, ctx.Err())
+		}
+	}
+}
+
+// MustWait calls Wait with timeout, and fails the test if it returns an error.
+func (r *Recorder) MustWait(tb testing.TB, n int, timeout time.Duration) {
+	tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := r.Wait(ctx, n); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// Find returns all the recorded spans matching filter.
+func (r *Recorder) Find(filter func(Span) bool) []Span {
+	var spans []Span
+	for _, span := range r.Spans() {
+		if filter(span) {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// FindByName returns all the recorded spans with the given name.
+func (r *Recorder) FindByName(name string) []Span {
+	return r.Find(func(s Span) bool {
+		return s.Name == name
+	})
+}
+
+// MustFindOne returns the only recorded span with the given name,
+// and fails the test if there are none or more than one of them.
+func (r *Recorder) MustFindOne(tb testing.TB, name string) Span {
+	tb.Helper()
+	spans := r.FindByName(name)
+	if len(spans) != 1 {
+		tb.Fatalf("tracingtest: expected exactly 1 span named %q, got %d", name, len(spans))
+	}
+	return spans[0]
+}
+
+// Failed returns all the recorded spans that were stopped with an error.
+func (r *Recorder) Failed() []Span {
+	return r.Find(Span.Failed)
+}
+
+// Children returns the recorded direct children of parent.
+func (r *Recorder) Children(parent Span) []Span {
+	return r.Find(func(s Span) bool {
+		return s.TraceID == parent.TraceID && s.ParentID == parent.SpanID
+	})
+}
+
+// Tree returns the recorded spans as trees.
+//
+// The roots are the spans with parents not recorded,
+// and both the roots and the children are sorted by their start time.
+func (r *Recorder) Tree() Tree {
+	spans := r.Spans()
+	sort.SliceStable(spans, func(i, j int) bool {
+		return time.Time(spans[i].Start).Before(time.Time(spans[j].Start))
+	})
+
+	type key struct {
+		traceID string
+		spanID  string
+	}
+	nodes := make(map[key]*Node, len(spans))
+	for _, span := range spans {
+		nodes[key{span.TraceID, span.SpanID}] = &Node{Span: span}
+	}
+
+	var roots Tree
+	for _, span := range spans {
+		node := nodes[key{span.TraceID, span.SpanID}]
+		if parent, ok := nodes[key{span.TraceID, span.ParentID}]; ok && span.ParentID != "" {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// Node is a span and its children in a Tree.
+type Node struct {
+	Span     Span
+	Children []*Node
+}
+
+// Tree is a list of root Nodes.
+type Tree []*Node
+
+// String returns the names of the spans in the tree,
+// one span per line and indented by 2 spaces per level.
+func (t Tree) String() string {
+	var sb strings.Builder
+	var write func(nodes []*Node, depth int)
+	write = func(nodes []*Node, depth int) {
+		for _, node := range nodes {
+			sb.WriteString(strings.Repeat("  ", depth))
+			sb.WriteString(node.Span.Name)
+			sb.WriteByte('\n')
+			write(node.Children, depth+1)
+		}
+	}
+	write(t, 0)
+	return sb.String()
+}
@@ -0,0 +1,106 @@
+package tracingtest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := tracingtest.Setup(t, tracing.Config{})
+
+	ctx, server := tracing.StartTopLevelServerSpan(context.Background(), "endpoint")
+	server.SetTag("foo", "bar")
+	server.AddCounter("hits", 1)
+
+	client, clientCtx := opentracing.StartSpanFromContext(
+		ctx,
+		"service.call",
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	local, _ := opentracing.StartSpanFromContext(clientCtx, "local")
+	local.LogKV("event", "cache-miss")
+	local.Finish()
+	client.FinishWithOptions(tracing.FinishOptions{
+		Err: errors.New("failed"),
+	}.Convert())
+
+	other, _ := opentracing.StartSpanFromContext(ctx, "other")
+	tracing.AsSpan(other).AddLinks(tracing.SpanLink{TraceID: "1", SpanID: "2"})
+	other.Finish()
+	server.Finish()
+
+	recorder.MustWait(t, 4, time.Second)
+
+	if got := len(recorder.Spans()); got != 4 {
+		t.Fatalf("Expected 4 spans, got %d", got)
+	}
+
+	want := "endpoint\n  service.call\n    local\n  other\n"
+	if got := recorder.Tree().String(); got != want {
+		t.Errorf("Tree expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	endpoint := recorder.MustFindOne(t, "endpoint")
+	if !endpoint.IsServer() {
+		t.Error("Expected endpoint to be a server span")
+	}
+	if value, ok := endpoint.Tag("foo"); !ok || value != "bar" {
+		t.Errorf("Expected tag foo=bar, got %q, %v", value, ok)
+	}
+	if _, ok := endpoint.Tags()["counter.hits"]; ok {
+		t.Error("Expected counters to be excluded from Tags")
+	}
+	if value, ok := endpoint.Counter("hits"); !ok || value != 1 {
+		t.Errorf("Expected counter hits=1, got %v, %v", value, ok)
+	}
+	if endpoint.Failed() {
+		t.Error("Expected endpoint span to not fail")
+	}
+
+	children := recorder.Children(endpoint)
+	if len(children) != 2 {
+		t.Errorf("Expected 2 children of endpoint, got %d", len(children))
+	}
+
+	failed := recorder.Failed()
+	if len(failed) != 1 || failed[0].Name != "service.call" || !failed[0].IsClient() {
+		t.Errorf("Expected only the client span to fail, got %+v", failed)
+	}
+
+	if got, want := recorder.MustFindOne(t, "local").Logs(), []string{"cache-miss"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Logs expected %q, got %q", want, got)
+	}
+
+	if got, want := recorder.MustFindOne(t, "other").Links(), []tracing.SpanLink{{TraceID: "1", SpanID: "2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Links expected %+v, got %+v", want, got)
+	}
+
+	recorder.Reset()
+	if got := len(recorder.Spans()); got != 0 {
+		t.Errorf("Expected no spans after Reset, got %d", got)
+	}
+}
+
+func TestRecorderWait(t *testing.T) {
+	recorder := tracingtest.NewRecorder()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := recorder.Wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	go func() {
+		recorder.Send(context.Background(), []byte(`{"name":"foo"}`))
+	}()
+	recorder.MustWait(t, 1, time.Second)
+	recorder.MustFindOne(t, "foo")
+}
@@ -0,0 +1,112 @@
+package tracingtest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+const (
+	counterKeyPrefix = "counter."
+	linkKeyPrefix    = "link."
+)
+
+// Span is a recorded span.
+type Span struct {
+	tracing.ZipkinSpan
+}
+
+// Tag returns the value of the tag with key, and whether the tag is set.
+//
+// Tags set to non-string values are formatted with fmt.Sprint.
+func (s Span) Tag(key string) (value string, ok bool) {
+	for _, ba := range s.BinaryAnnotations {
+		if ba.Key == key {
+			return fmt.Sprint(ba.Value), true
+		}
+	}
+	return "", false
+}
+
+// Tags returns all the tags of the span, excluding counters and links.
+func (s Span) Tags() map[string]string {
+	tags := make(map[string]string, len(s.BinaryAnnotations))
+	for _, ba := range s.BinaryAnnotations {
+		if strings.HasPrefix(ba.Key, counterKeyPrefix) || strings.HasPrefix(ba.Key, linkKeyPrefix) {
+			continue
+		}
+		tags[ba.Key] = fmt.Sprint(ba.Value)
+	}
+	return tags
+}
+
+// Counter returns the value of the counter with key added by
+// tracing.Span.AddCounter, and whether the counter is set.
+func (s Span) Counter(key string) (value float64, ok bool) {
+	for _, ba := range s.BinaryAnnotations {
+		if ba.Key == counterKeyPrefix+key {
+			value, ok = ba.Value.(float64)
+			return value, ok
+		}
+	}
+	return 0, false
+}
+
+// Failed returns true if the span was stopped with an error.
+func (s Span) Failed() bool {
+	value, _ := s.Tag(tracing.ZipkinBinaryAnnotationKeyError)
+	return value == "true"
+}
+
+// Logs returns the values of the annotations recorded by
+// tracing.Span.LogKV and similar functions, in order.
+func (s Span) Logs() []string {
+	var logs []string
+	for _, ta := range s.TimeAnnotations {
+		switch ta.Key {
+		case tracing.ZipkinTimeAnnotationKeyClientReceive,
+			tracing.ZipkinTimeAnnotationKeyClientSend,
+			tracing.ZipkinTimeAnnotationKeyServerReceive,
+			tracing.ZipkinTimeAnnotationKeyServerSend:
+			continue
+		}
+		logs = append(logs, ta.Key)
+	}
+	return logs
+}
+
+// Links returns the links added to the span.
+func (s Span) Links() []tracing.SpanLink {
+	var links []tracing.SpanLink
+	for _, ba := range s.BinaryAnnotations {
+		if !strings.HasPrefix(ba.Key, linkKeyPrefix) {
+			continue
+		}
+		traceID, spanID, _ := strings.Cut(fmt.Sprint(ba.Value), ":")
+		links = append(links, tracing.SpanLink{
+			TraceID: traceID,
+			SpanID:  spanID,
+		})
+	}
+	return links
+}
+
+// IsServer returns true if the span is a server span.
+func (s Span) IsServer() bool {
+	return s.hasTimeAnnotation(tracing.ZipkinTimeAnnotationKeyServerReceive)
+}
+
+// IsClient returns true if the span is a client span.
+func (s Span) IsClient() bool {
+	return s.hasTimeAnnotation(tracing.ZipkinTimeAnnotationKeyClientSend)
+}
+
+func (s Span) hasTimeAnnotation(key string) bool {
+	for _, ta := range s.TimeAnnotations {
+		if ta.Key == key {
+			return true
+		}
+	}
+	return false
+}
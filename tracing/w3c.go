@@ -0,0 +1,178 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// W3C Trace Context header names.
+//
+// Reference: https://www.w3.org/TR/trace-context/
+const (
+	W3CTraceParentHeader = "traceparent"
+	W3CTraceStateHeader  = "tracestate"
+)
+
+// Supported values of Config.HeaderPrecedence.
+const (
+	// PrecedenceBaseplate uses the Baseplate trace headers when both the
+	// Baseplate and the W3C trace headers are present.
+	//
+	// This is the default.
+	PrecedenceBaseplate = "baseplate"
+
+	// PrecedenceW3C uses the W3C traceparent and tracestate headers when both the
+	// Baseplate and the W3C trace headers are present.
+	PrecedenceW3C = "w3c"
+)
+
+const (
+	w3cVersion        = "00"
+	w3cFlagSampled    = 0x01
+	w3cTraceParentLen = 55
+	// Max length of tracestate header we propagate, as recommended by the spec.
+	w3cMaxTraceStateLen = 512
+)
+
+// ErrInvalidTraceParent is returned by ParseTraceParent when the traceparent
+// header is malformed.
+var ErrInvalidTraceParent = errors.New("tracing: invalid traceparent")
+
+// TraceParent is the parsed W3C traceparent header.
+type TraceParent struct {
+	// TraceID is the 32 lowercase hex digits trace id.
+	TraceID string
+
+	// SpanID is the 16 lowercase hex digits id of the parent span.
+	SpanID string
+
+	// Sampled is the sampled bit of the trace flags.
+	Sampled bool
+}
+
+// String formats tp as a version 00 traceparent header value.
+func (tp TraceParent) String() string {
+	flags := 0
+	if tp.Sampled {
+		flags |= w3cFlagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", w3cVersion, tp.TraceID, tp.SpanID, flags)
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+//
+// Future versions of the header are parsed as version 00 as required by the
+// spec, with any additional fields ignored.
+func ParseTraceParent(value string) (TraceParent, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < w3cTraceParentLen {
+		return TraceParent{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, value)
+	}
+	version := value[:2]
+	if !isLowerHex(version) || version == "ff" {
+		return TraceParent{}, fmt.Errorf("%w: unsupported version in %q", ErrInvalidTraceParent, value)
+	}
+	if version == w3cVersion && len(value) != w3cTraceParentLen {
+		return TraceParent{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, value)
+	}
+	if len(value) > w3cTraceParentLen && value[w3cTraceParentLen] != '-' {
+		return TraceParent{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, value)
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return TraceParent{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, value)
+	}
+
+	traceID := value[3:35]
+	spanID := value[36:52]
+	flags := value[53:55]
+	if !isLowerHex(traceID) || isAllZeros(traceID) {
+		return TraceParent{}, fmt.Errorf("%w: invalid trace id in %q", ErrInvalidTraceParent, value)
+	}
+	if !isLowerHex(spanID) || isAllZeros(spanID) {
+		return TraceParent{}, fmt.Errorf("%w: invalid span id in %q", ErrInvalidTraceParent, value)
+	}
+	if !isLowerHex(flags) {
+		return TraceParent{}, fmt.Errorf("%w: invalid flags in %q", ErrInvalidTraceParent, value)
+	}
+	decoded, _ := hex.DecodeString(flags)
+	return TraceParent{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: decoded[0]&w3cFlagSampled != 0,
+	}, nil
+}
+
+// TraceParent returns the W3C traceparent header value to be sent to
+// downstream services for requests made within this span.
+//
+// Baseplate trace and span ids that are not in the W3C hex format
+// (for example decimal ids) are converted to it.
+// The debug flag is propagated as the sampled bit.
+func (s Span) TraceParent() string {
+	return TraceParent{
+		TraceID: hex.EncodeToString(otlpID(s.trace.traceID, 16)),
+		SpanID:  hex.EncodeToString(otlpID(s.trace.spanID, 8)),
+		Sampled: s.trace.shouldSample(),
+	}.String()
+}
+
+// TraceState returns the W3C tracestate header value received from the
+// upstream caller of this trace, if any.
+func (s Span) TraceState() string {
+	return s.trace.traceState
+}
+
+// useW3C returns true if the W3C trace headers should be used over the
+// Baseplate ones.
+func (h Headers) useW3C() bool {
+	if h.TraceParent == "" {
+		return false
+	}
+	if h.TraceID == "" {
+		return true
+	}
+	return globalTracer.headerPrecedence == PrecedenceW3C
+}
+
+// ParseTraceParent attempts to parse h.TraceParent, if it succeeds it returns
+// the value and 'true'.
+//
+// If h.TraceParent was malformed, an error will be logged using the global
+// tracer's logger but no error will be returned.
+func (h Headers) ParseTraceParent() (tp TraceParent, ok bool) {
+	if h.TraceParent == "" {
+		return
+	}
+	tp, err := ParseTraceParent(h.TraceParent)
+	if err != nil {
+		globalTracer.logger.Log(context.Background(), err.Error())
+		return
+	}
+	return tp, true
+}
+
+// ParseTraceState returns h.TraceState if it's within the size limit.
+func (h Headers) ParseTraceState() (state string, ok bool) {
+	state = strings.TrimSpace(h.TraceState)
+	if state == "" || len(state) > w3cMaxTraceStateLen {
+		return "", false
+	}
+	return state, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAllZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,222 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		value string
+		want  TraceParent
+		err   error
+	}{
+		{
+			label: "sampled",
+			value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			want: TraceParent{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+				Sampled: true,
+			},
+		},
+		{
+			label: "not-sampled",
+			value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00",
+			want: TraceParent{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+			},
+		},
+		{
+			label: "future-version",
+			value: "01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-03-foo",
+			want: TraceParent{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+				Sampled: true,
+			},
+		},
+		{
+			label: "version-ff",
+			value: "ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			err:   ErrInvalidTraceParent,
+		},
+		{
+			label: "version-00-extra",
+			value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-foo",
+			err:   ErrInvalidTraceParent,
+		},
+		{
+			label: "short",
+			value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+			err:   ErrInvalidTraceParent,
+		},
+		{
+			label: "uppercase",
+			value: "00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01",
+			err:   ErrInvalidTraceParent,
+		},
+		{
+			label: "zero-trace-id",
+			value: "00-00000000000000000000000000000000-b7ad6b7169203331-01",
+			err:   ErrInvalidTraceParent,
+		},
+		{
+			label: "zero-span-id",
+			value: "00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+			err:   ErrInvalidTraceParent,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			got, err := ParseTraceParent(c.value)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("Expected error %v, got %v", c.err, err)
+			}
+			if got != c.want {
+				t.Errorf("Expected %+v, got %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSpanTraceParent(t *testing.T) {
+	for _, c := range []struct {
+		label   string
+		traceID string
+		spanID  string
+		sampled bool
+		flags   int64
+		want    string
+	}{
+		{
+			label:   "hex",
+			traceID: "0af7651916cd43dd8448eb211c80319c",
+			spanID:  "b7ad6b7169203331",
+			sampled: true,
+			want:    "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		},
+		{
+			label:   "dec",
+			traceID: "12345",
+			spanID:  "67890",
+			want:    "00-00000000000000000000000000003039-0000000000010932-00",
+		},
+		{
+			label:   "debug",
+			traceID: "12345",
+			spanID:  "67890",
+			flags:   FlagMaskDebug,
+			want:    "00-00000000000000000000000000003039-0000000000010932-01",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			span := newSpan(nil, "test", SpanTypeServer)
+			span.trace.traceID = c.traceID
+			span.trace.spanID = c.spanID
+			span.trace.sampled = c.sampled
+			span.trace.flags = c.flags
+			if got := span.TraceParent(); got != c.want {
+				t.Errorf("Expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestStartSpanFromHeadersW3C(t *testing.T) {
+	const (
+		traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		traceState  = "foo=bar,baz=qux"
+	)
+	sampled := false
+
+	defer func(precedence string) {
+		globalTracer.headerPrecedence = precedence
+	}(globalTracer.headerPrecedence)
+
+	for _, c := range []struct {
+		label      string
+		precedence string
+		headers    Headers
+		traceID    string
+		parentID   string
+		sampled    bool
+	}{
+		{
+			label: "w3c-only",
+			headers: Headers{
+				TraceParent: traceParent,
+				TraceState:  traceState,
+			},
+			traceID:  "0af7651916cd43dd8448eb211c80319c",
+			parentID: "b7ad6b7169203331",
+			sampled:  true,
+		},
+		{
+			label:      "both-baseplate-precedence",
+			precedence: PrecedenceBaseplate,
+			headers: Headers{
+				TraceID:     "12345",
+				SpanID:      "67890",
+				Sampled:     &sampled,
+				TraceParent: traceParent,
+				TraceState:  traceState,
+			},
+			traceID:  "12345",
+			parentID: "67890",
+		},
+		{
+			label:      "both-w3c-precedence",
+			precedence: PrecedenceW3C,
+			headers: Headers{
+				TraceID:     "12345",
+				SpanID:      "67890",
+				Sampled:     &sampled,
+				TraceParent: traceParent,
+				TraceState:  traceState,
+			},
+			traceID:  "0af7651916cd43dd8448eb211c80319c",
+			parentID: "b7ad6b7169203331",
+			sampled:  true,
+		},
+		{
+			label:      "malformed-w3c",
+			precedence: PrecedenceW3C,
+			headers: Headers{
+				TraceID:     "12345",
+				SpanID:      "67890",
+				Sampled:     &sampled,
+				TraceParent: "foo",
+				TraceState:  traceState,
+			},
+			traceID:  "12345",
+			parentID: "67890",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			globalTracer.headerPrecedence = c.precedence
+			_, span := StartSpanFromHeaders(context.Background(), "test", c.headers)
+			if got := span.TraceID(); got != c.traceID {
+				t.Errorf("TraceID expected %q, got %q", c.traceID, got)
+			}
+			if got := span.ParentID(); got != c.parentID {
+				t.Errorf("ParentID expected %q, got %q", c.parentID, got)
+			}
+			if got := span.Sampled(); got != c.sampled {
+				t.Errorf("Sampled expected %v, got %v", c.sampled, got)
+			}
+			if got := span.TraceState(); got != traceState {
+				t.Errorf("TraceState expected %q, got %q", traceState, got)
+			}
+
+			child := AsSpan(opentracing.StartSpan("child", opentracing.ChildOf(span)))
+			if got := child.TraceState(); got != traceState {
+				t.Errorf("Child TraceState expected %q, got %q", traceState, got)
+			}
+		})
+	}
+}
@@ -25,6 +25,11 @@ type ZipkinSpan struct {
 	// Annotations are all optional.
 	TimeAnnotations   []ZipkinTimeAnnotation   `json:"annotations,omitempty"`
 	BinaryAnnotations []ZipkinBinaryAnnotation `json:"binaryAnnotations,omitempty"`
+
+	// Links are not part of zipkin's json format.
+	// They are also added as "link.<n>" binary annotations for the sidecar,
+	// and exported as span links by the OTLP exporter.
+	Links []SpanLink `json:"-"`
 }
 
 // ZipkinEndpointInfo defines Zipkin's endpoint json format.
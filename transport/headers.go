@@ -18,6 +18,15 @@ const (
 	// Trace flags, a 64-bit integer encoded in decimal.
 	// If not present, defaults to null.
 	HeaderTracingFlags = "Flags"
+	// The W3C Trace Context traceparent header.
+	// https://www.w3.org/TR/trace-context/#traceparent-header
+	HeaderTracingTraceParent = "traceparent"
+	// The W3C Trace Context tracestate header.
+	// https://www.w3.org/TR/trace-context/#tracestate-header
+	HeaderTracingTraceState = "tracestate"
+	// The W3C baggage header.
+	// https://www.w3.org/TR/baggage/#baggage-http-header-format
+	HeaderTracingBaggage = "baggage"
 	// UserAgent related headers.
 	HeaderUserAgent = "User-Agent"
 	// HeaderTracingSampledTrue is the header value to indicate that this trace